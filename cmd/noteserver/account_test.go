@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"noteserver/internal/pkg/api"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/responses"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// addPersonalToken creates a personal access token for the user.
+func (s *testServer) addPersonalToken(user *models.User, token string, scopes ...string) {
+	s.t.Helper()
+	sum := sha256.Sum256([]byte(token))
+	err := s.store.CreatePersonalToken(context.Background(), &models.PersonalToken{
+		UserID:    user.ID,
+		Name:      token,
+		TokenHash: hex.EncodeToString(sum[:]),
+		Scopes:    scopes,
+	})
+	if err != nil {
+		s.t.Fatal(err)
+	}
+}
+
+// requestReset asks for a password reset of the address and returns the
+// token from the message sent.
+func (s *testServer) requestReset(email string) string {
+	s.t.Helper()
+	s.expect(s.do("POST", "/v1/password/forgot", "", `{"email":"`+email+`"}`), http.StatusAccepted, nil)
+	return s.resetToken(email)
+}
+
+// resetToken returns the token from the next password reset message, which
+// has to be sent to the address.
+func (s *testServer) resetToken(email string) string {
+	s.t.Helper()
+	select {
+	case message := <-s.messages:
+		if message.To != email {
+			s.t.Fatalf("reset sent to %q, want %q", message.To, email)
+		}
+		for _, line := range strings.Split(message.Body, "\n") {
+			if link, err := url.Parse(line); err == nil && link.Host == "notes.example.com" {
+				return link.Query().Get("token")
+			}
+		}
+		s.t.Fatalf("no reset link in %q", message.Body)
+	case <-time.After(5 * time.Second):
+		s.t.Fatal("no reset message sent")
+	}
+	return ""
+}
+
+func TestPasswordReset(t *testing.T) {
+	s := newTestServer(t)
+	alice, _ := s.addUser("alice")
+	session := s.login("alice", testPassword)
+	s.addPersonalToken(alice, "nsp_alice", api.ScopeNotesRead)
+
+	stale := s.requestReset("alice@example.com")
+	token := s.requestReset("alice@example.com")
+	s.expect(s.do("POST", "/v1/password/reset", "", `{"token":"`+token+`","new_password":"short"}`), http.StatusUnprocessableEntity, nil)
+	s.expect(s.do("POST", "/v1/password/reset", "", `{"token":"`+token+`","new_password":"Another-pass-456"}`), http.StatusOK, nil)
+
+	// The token is used up and the other tokens of the user are discarded.
+	s.expect(s.do("POST", "/v1/password/reset", "", `{"token":"`+token+`","new_password":"Third-pass-789"}`), http.StatusBadRequest, nil)
+	s.expect(s.do("POST", "/v1/password/reset", "", `{"token":"`+stale+`","new_password":"Third-pass-789"}`), http.StatusBadRequest, nil)
+
+	s.expect(s.do("GET", "/v1/account", session, ""), http.StatusUnauthorized, nil)
+	s.expect(s.do("GET", "/v1/notes", "nsp_alice", ""), http.StatusUnauthorized, nil)
+	s.expect(s.do("POST", "/v1/login", "", `{"username":"alice","password":"`+testPassword+`"}`), http.StatusUnauthorized, nil)
+	s.expect(s.do("GET", "/v1/account", s.login("alice", "Another-pass-456"), ""), http.StatusOK, nil)
+}
+
+func TestPasswordResetUnknownAddress(t *testing.T) {
+	s := newTestServer(t)
+	s.addUser("alice")
+	s.expect(s.do("POST", "/v1/password/forgot", "", `{"email":"bob@example.com"}`), http.StatusAccepted, nil)
+	s.expect(s.do("POST", "/v1/password/forgot", "", `{"email":"not an address"}`), http.StatusUnprocessableEntity, nil)
+	select {
+	case message := <-s.messages:
+		t.Errorf("message sent to %q", message.To)
+	case <-time.After(100 * time.Millisecond):
+	}
+	s.expect(s.do("POST", "/v1/password/reset", "", `{"token":"guessed","new_password":"Another-pass-456"}`), http.StatusBadRequest, nil)
+}
+
+func TestPasswordResetExpired(t *testing.T) {
+	s := newTestServer(t)
+	alice, _ := s.addUser("alice")
+	sum := sha256.Sum256([]byte("expired-token"))
+	err := s.store.CreatePasswordReset(context.Background(), &models.PasswordReset{
+		UserID:    alice.ID,
+		TokenHash: hex.EncodeToString(sum[:]),
+		ExpiresAt: time.Now().Add(-time.Second),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.expect(s.do("POST", "/v1/password/reset", "", `{"token":"expired-token","new_password":"Another-pass-456"}`), http.StatusBadRequest, nil)
+	s.login("alice", testPassword)
+}
+
+func TestChangePasswordRevokesTokens(t *testing.T) {
+	s := newTestServer(t)
+	alice, _ := s.addUser("alice")
+	other := s.login("alice", testPassword)
+	current := s.login("alice", testPassword)
+	s.addPersonalToken(alice, "nsp_alice", api.ScopeNotesRead)
+
+	s.expect(s.do("POST", "/v1/account/password", current, `{"current_password":"wrong-password","new_password":"Another-pass-456"}`), http.StatusForbidden, nil)
+	s.expect(s.do("GET", "/v1/account", other, ""), http.StatusOK, nil)
+	s.expect(s.do("GET", "/v1/notes", "nsp_alice", ""), http.StatusOK, nil)
+
+	var renewed responses.Token
+	s.expect(s.do("POST", "/v1/account/password", current, `{"current_password":"`+testPassword+`","new_password":"Another-pass-456"}`), http.StatusOK, &renewed)
+	s.expect(s.do("GET", "/v1/account", other, ""), http.StatusUnauthorized, nil)
+	s.expect(s.do("GET", "/v1/account", current, ""), http.StatusUnauthorized, nil)
+	s.expect(s.do("GET", "/v1/notes", "nsp_alice", ""), http.StatusUnauthorized, nil)
+	s.expect(s.do("GET", "/v1/account", renewed.Token, ""), http.StatusOK, nil)
+	s.login("alice", "Another-pass-456")
+}
+
+func TestChangePasswordThrottled(t *testing.T) {
+	s := newTestServer(t)
+	_, token := s.addUser("alice")
+	wrong := `{"current_password":"wrong-password","new_password":"Another-pass-456"}`
+	for i := 0; i < 4; i++ {
+		s.expect(s.do("POST", "/v1/account/password", token, wrong), http.StatusForbidden, nil)
+	}
+	s.expect(s.do("POST", "/v1/account/password", token, wrong), http.StatusTooManyRequests, nil)
+
+	// Wrong passwords count as failed logins, so even the right one is
+	// refused until the lockout ends.
+	s.expect(s.do("POST", "/v1/account/password", token, `{"current_password":"`+testPassword+`","new_password":"Another-pass-456"}`), http.StatusTooManyRequests, nil)
+	s.expect(s.do("POST", "/v1/login", "", `{"username":"alice","password":"`+testPassword+`"}`), http.StatusTooManyRequests, nil)
+}
+
+func TestDisabledAccount(t *testing.T) {
+	s := newTestServer(t)
+	alice, token := s.addUser("alice")
+	s.addPersonalToken(alice, "nsp_alice", api.ScopeNotesRead)
+	s.expect(s.do("GET", "/v1/notes", token, ""), http.StatusOK, nil)
+	s.expect(s.do("GET", "/v1/notes", "nsp_alice", ""), http.StatusOK, nil)
+	s.expect(s.do("GET", "/v1/notes", "nsp_bob", ""), http.StatusUnauthorized, nil)
+
+	if err := s.store.SetUserDisabled(context.Background(), alice.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	s.expect(s.do("GET", "/v1/notes", token, ""), http.StatusForbidden, nil)
+	s.expect(s.do("GET", "/v1/notes", "nsp_alice", ""), http.StatusForbidden, nil)
+}
+
+func TestForcePasswordResetRevokesTokens(t *testing.T) {
+	s := newTestServer(t)
+	admin, _ := s.addUser("admin")
+	if err := s.store.SetUserRole(context.Background(), admin.ID, models.RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+	adminToken := s.login("admin", testPassword)
+	alice, _ := s.addUser("alice")
+	session := s.login("alice", testPassword)
+	s.addPersonalToken(alice, "nsp_alice", api.ScopeNotesRead)
+
+	s.expect(s.do("POST", "/v1/admin/users/"+strconv.Itoa(alice.ID)+"/password-reset", adminToken, ""), http.StatusOK, nil)
+	s.expect(s.do("GET", "/v1/account", session, ""), http.StatusUnauthorized, nil)
+	s.expect(s.do("GET", "/v1/notes", "nsp_alice", ""), http.StatusUnauthorized, nil)
+	s.expect(s.do("POST", "/v1/login", "", `{"username":"alice","password":"`+testPassword+`"}`), http.StatusUnauthorized, nil)
+
+	token := s.resetToken("alice@example.com")
+	s.expect(s.do("POST", "/v1/password/reset", "", `{"token":"`+token+`","new_password":"Another-pass-456"}`), http.StatusOK, nil)
+	s.login("alice", "Another-pass-456")
+}
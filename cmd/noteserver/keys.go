@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"noteserver/internal/pkg/jwtkeys"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	defaultJWTSecret = "your-secret-key"
+	// hmacKeyID is the kid of tokens signed with --jwt-secret.
+	hmacKeyID = "hs256"
+)
+
+// keyFiles collects repeated --jwt-key flags, each "path" or "kid=path".
+type keyFiles []string
+
+func (k *keyFiles) String() string {
+	return strings.Join(*k, ",")
+}
+
+func (k *keyFiles) Set(value string) error {
+	*k = append(*k, value)
+	return nil
+}
+
+// loadJWTKeys builds the key set from the PEM files and the HMAC secret.
+// The secret is only used if it was changed from the default, or in
+// insecure development mode. Without an explicit signing key the first
+// private key file signs, or the secret when there are no files.
+func loadJWTKeys(secret string, files keyFiles, signingKeyID string, insecureDev bool) (*jwtkeys.KeySet, error) {
+	keys := jwtkeys.NewKeySet()
+	for _, file := range files {
+		id, path, ok := strings.Cut(file, "=")
+		if !ok {
+			path = file
+			id = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		}
+		key, err := jwtkeys.LoadPEMFile(id, path)
+		if err != nil {
+			return nil, err
+		}
+		if err := keys.Add(key); err != nil {
+			return nil, err
+		}
+		if signingKeyID == "" && key.CanSign() {
+			signingKeyID = key.ID
+		}
+	}
+
+	if secret != "" && (secret != defaultJWTSecret || insecureDev) {
+		if err := keys.Add(jwtkeys.NewHMACKey(hmacKeyID, []byte(secret))); err != nil {
+			return nil, err
+		}
+		if signingKeyID == "" {
+			signingKeyID = hmacKeyID
+		}
+	}
+
+	if signingKeyID == "" {
+		return nil, errors.New("no signing key: set --jwt-secret or --jwt-key with a private key, " +
+			"or pass --insecure-dev to use the default secret")
+	}
+	if err := keys.SetSigningKey(signingKeyID); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
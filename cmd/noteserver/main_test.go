@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"noteserver/internal/pkg/api"
+	"noteserver/internal/pkg/jwtkeys"
+	"noteserver/internal/pkg/loginlimit"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/notify"
+	"noteserver/internal/pkg/passwords"
+	"noteserver/internal/pkg/responses"
+	"noteserver/internal/pkg/storage/memory"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const testPassword = "Secret-pass-123"
+
+// spellerTransport answers the spellchecker requests of note handlers, so
+// that tests do not depend on the network.
+type spellerTransport struct{}
+
+func (spellerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader("[]")),
+		Request:    r,
+	}, nil
+}
+
+func TestMain(m *testing.M) {
+	http.DefaultTransport = spellerTransport{}
+	os.Exit(m.Run())
+}
+
+// testNotifier hands the messages sent to the test.
+type testNotifier chan notify.Message
+
+func (n testNotifier) Send(ctx context.Context, message notify.Message) error {
+	n <- message
+	return nil
+}
+
+// testServer serves the routes of the application from a memory store.
+type testServer struct {
+	t        *testing.T
+	store    *memory.Store
+	jwtKeys  *jwtkeys.KeySet
+	router   *mux.Router
+	messages testNotifier
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	jwtKeys := jwtkeys.NewKeySet()
+	if err := jwtKeys.Add(jwtkeys.NewHMACKey("test", []byte("test-secret"))); err != nil {
+		t.Fatal(err)
+	}
+	if err := jwtKeys.SetSigningKey("test"); err != nil {
+		t.Fatal(err)
+	}
+	policy, err := passwords.NewPolicy(8, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &testServer{t: t, store: memory.New(), jwtKeys: jwtKeys, router: mux.NewRouter(), messages: make(testNotifier, 10)}
+	lifetimes := api.TokenLifetimes{Access: time.Hour, Refresh: 24 * time.Hour}
+	limiters := api.LoginLimiters{
+		Users:     loginlimit.New(loginlimit.Config{MaxFailures: 5, BaseLockout: time.Minute, MaxLockout: time.Hour, Window: time.Hour}),
+		Addresses: loginlimit.New(loginlimit.Config{MaxFailures: 20, BaseLockout: time.Minute, MaxLockout: time.Hour, Window: time.Hour}),
+	}
+	resets := api.PasswordResetConfig{Notifier: server.messages, TTL: time.Hour, URL: "https://notes.example.com/reset"}
+	registration := api.RegistrationConfig{Mode: api.RegistrationOpen, Policy: policy, InviteTTL: time.Hour}
+
+	server.router.HandleFunc("/v1/login", func(w http.ResponseWriter, r *http.Request) {
+		api.HandleLogin(w, r, server.store, jwtKeys, lifetimes, limiters)
+	}).Methods("POST")
+	RegisterAccountRoutes(server.router, server.store, jwtKeys, lifetimes, resets, registration, limiters)
+	RegisterNoteRoutes(server.router, server.store, jwtKeys, 1)
+	RegisterNoteResourceRoutes(server.router, server.store, jwtKeys, 1)
+	RegisterNotebookRoutes(server.router, server.store, jwtKeys)
+	RegisterWorkspaceRoutes(server.router, server.store, jwtKeys)
+	RegisterTrashRoutes(server.router, server.store, jwtKeys)
+	RegisterAdminRoutes(server.router, server.store, jwtKeys, resets)
+	return server
+}
+
+// addUser creates a user and returns them with an access token.
+func (s *testServer) addUser(username string) (*models.User, string) {
+	s.t.Helper()
+	hash, err := api.HashPassword(testPassword)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := s.store.SaveUser(ctx, models.User{Username: username, Password: hash, Email: username + "@example.com"}); err != nil {
+		s.t.Fatal(err)
+	}
+	user, err := s.store.GetUserByUsername(ctx, username)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	token, err := api.GenerateJWTToken(user, s.jwtKeys, username+"-"+strconv.Itoa(user.ID), time.Hour)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	return user, token
+}
+
+// login logs a user in with a password and returns the access token.
+func (s *testServer) login(username, password string) string {
+	s.t.Helper()
+	var token responses.Token
+	s.expect(s.do("POST", "/v1/login", "", `{"username":"`+username+`","password":"`+password+`"}`), http.StatusOK, &token)
+	return token.Token
+}
+
+// do serves a request with a JSON body and the token in the Authorization
+// header; headers are given as name and value pairs.
+func (s *testServer) do(method, path, token, body string, headers ...string) *httptest.ResponseRecorder {
+	s.t.Helper()
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		r.Header.Set("Authorization", token)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		r.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+	return w
+}
+
+// expect fails the test unless the response has the status code and
+// decodes its JSON body into v, if given.
+func (s *testServer) expect(w *httptest.ResponseRecorder, status int, v interface{}) {
+	s.t.Helper()
+	if w.Code != status {
+		s.t.Fatalf("got status %d, want %d: %s", w.Code, status, w.Body.String())
+	}
+	if v != nil {
+		if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+			s.t.Fatalf("decoding %q: %v", w.Body.String(), err)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"noteserver/internal/pkg/database"
+	l "noteserver/internal/pkg/logger"
+	"noteserver/internal/pkg/storage/postgres"
+)
+
+func runMigrate(args []string) {
+	var sqlServer string
+
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	flags.StringVar(&sqlServer, "sql-server", defaultSQLServer, "Parameters of SQL-Server")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: noteserver migrate [flags] up | down [steps] | status")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	err := l.InitLogger()
+	if err != nil {
+		log.Fatal("Failed to initialize the logger", err)
+	}
+	if !checkPostgreSQL(sqlServer) {
+		log.Fatal("Incorrect SQL-server parameters")
+	}
+
+	ctx := context.Background()
+	db, err := database.Connect(ctx, database.Config{URL: sqlServer})
+	if err != nil {
+		log.Fatal("Failed to connect to database: ", err)
+	}
+	defer db.Close()
+
+	switch flags.Arg(0) {
+	case "up":
+		applied, err := postgres.MigrateUp(ctx, db)
+		for _, version := range applied {
+			fmt.Printf("Applied migration %d\n", version)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("Database schema is up to date")
+		}
+	case "down":
+		steps := 1
+		if flags.NArg() > 1 {
+			steps, err = strconv.Atoi(flags.Arg(1))
+			if err != nil || steps < 1 {
+				log.Fatal("Incorrect number of steps: ", flags.Arg(1))
+			}
+		}
+		reverted, err := postgres.MigrateDown(ctx, db, steps)
+		for _, version := range reverted {
+			fmt.Printf("Reverted migration %d\n", version)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+	case "status":
+		states, err := postgres.MigrationStatus(ctx, db)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, state := range states {
+			status := "pending"
+			if state.Applied {
+				status = "applied " + state.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%s\t%s\n", state.Version, state.Name, status)
+		}
+	default:
+		flags.Usage()
+		os.Exit(2)
+	}
+}
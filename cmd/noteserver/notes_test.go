@@ -0,0 +1,313 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/responses"
+	"noteserver/internal/pkg/storage/memory"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// TestLastModified checks that every write answers with the validators of
+// the note as it is stored afterwards.
+func TestLastModified(t *testing.T) {
+	s := newTestServer(t)
+	_, token := s.addUser("alice")
+
+	assertValidators := func(w *http.Response, step string) {
+		t.Helper()
+		var read responses.ReadNote
+		s.expect(s.do("GET", "/v1/notes/1", token, ""), http.StatusOK, &read)
+		if got, want := w.Header.Get("Last-Modified"), read.Note.UpdatedAt.UTC().Format(http.TimeFormat); got != want {
+			t.Errorf("%s: Last-Modified = %q, want %q", step, got, want)
+		}
+		if got, want := w.Header.Get("ETag"), `"1-`+strconv.Itoa(read.Note.Version)+`"`; got != want {
+			t.Errorf("%s: ETag = %q, want %q", step, got, want)
+		}
+	}
+
+	w := s.do("POST", "/v1/notes", token, `{"title":"First","content":"one"}`)
+	s.expect(w, http.StatusCreated, nil)
+	assertValidators(w.Result(), "POST")
+
+	w = s.do("PUT", "/v1/notes/1", token, `{"title":"Second","content":"two"}`)
+	s.expect(w, http.StatusOK, nil)
+	assertValidators(w.Result(), "PUT")
+
+	// Last-Modified has a resolution of seconds, so the PATCH has to come
+	// at least a second after the note was last changed to be told apart.
+	time.Sleep(time.Second)
+	w = s.do("PATCH", "/v1/notes/1", token, `{"content":"three"}`)
+	s.expect(w, http.StatusOK, nil)
+	assertValidators(w.Result(), "PATCH")
+}
+
+func TestNoteCRUD(t *testing.T) {
+	s := newTestServer(t)
+	_, alice := s.addUser("alice")
+	_, bob := s.addUser("bob")
+
+	var created responses.CreateUpdateNote
+	w := s.do("POST", "/v1/notes", alice, `{"title":"Groceries","content":"milk"}`)
+	s.expect(w, http.StatusCreated, &created)
+	if created.NoteID != "1" || w.Header().Get("Location") != "/v1/notes/1" {
+		t.Fatalf("created note %q at %q", created.NoteID, w.Header().Get("Location"))
+	}
+
+	var read responses.ReadNote
+	s.expect(s.do("GET", "/v1/notes/1", alice, ""), http.StatusOK, &read)
+	if read.Note.Title != "Groceries" || read.Note.Content != "milk" || read.Note.Version != 1 {
+		t.Errorf("read %+v", read.Note)
+	}
+
+	s.expect(s.do("PATCH", "/v1/notes/1", alice, `{"content":"milk, eggs"}`), http.StatusOK, nil)
+	s.expect(s.do("GET", "/v1/notes/1", alice, ""), http.StatusOK, &read)
+	if read.Note.Title != "Groceries" || read.Note.Content != "milk, eggs" || read.Note.Version != 2 {
+		t.Errorf("after PATCH read %+v", read.Note)
+	}
+
+	s.expect(s.do("PUT", "/v1/notes/1", alice, `{"title":"Shopping"}`), http.StatusOK, nil)
+	s.expect(s.do("GET", "/v1/notes/1", alice, ""), http.StatusOK, &read)
+	if read.Note.Title != "Shopping" || read.Note.Content != "" || read.Note.Version != 3 {
+		t.Errorf("after PUT read %+v", read.Note)
+	}
+
+	s.expect(s.do("GET", "/v1/notes/1", bob, ""), http.StatusNotFound, nil)
+	s.expect(s.do("PATCH", "/v1/notes/1", bob, `{"title":"Mine"}`), http.StatusNotFound, nil)
+	s.expect(s.do("DELETE", "/v1/notes/1", bob, ""), http.StatusNotFound, nil)
+
+	s.expect(s.do("POST", "/v1/notes", alice, `{"content":"untitled"}`), http.StatusUnprocessableEntity, nil)
+	s.expect(s.do("PUT", "/v1/notes/1", alice, `{"title":""}`), http.StatusUnprocessableEntity, nil)
+	s.expect(s.do("POST", "/v1/notes", alice, `{"title":`), http.StatusBadRequest, nil)
+	s.expect(s.do("GET", "/v1/notes/1", "", ""), http.StatusUnauthorized, nil)
+
+	s.expect(s.do("DELETE", "/v1/notes/1", alice, ""), http.StatusNoContent, nil)
+	s.expect(s.do("GET", "/v1/notes/1", alice, ""), http.StatusNotFound, nil)
+	s.expect(s.do("DELETE", "/v1/notes/1", alice, ""), http.StatusNotFound, nil)
+}
+
+func TestListNotesPagination(t *testing.T) {
+	s := newTestServer(t)
+	_, alice := s.addUser("alice")
+	_, bob := s.addUser("bob")
+	for _, title := range []string{"e", "c", "a", "d", "b"} {
+		s.expect(s.do("POST", "/v1/notes", alice, `{"title":"`+title+`"}`), http.StatusCreated, nil)
+	}
+	s.expect(s.do("POST", "/v1/notes", bob, `{"title":"bob"}`), http.StatusCreated, nil)
+
+	var titles []string
+	path := "/v1/notes?sort=title&limit=2"
+	for pages := 0; path != ""; pages++ {
+		if pages == 3 {
+			t.Fatal("more than three pages of two notes")
+		}
+		var page responses.AllNotes
+		s.expect(s.do("GET", path, alice, ""), http.StatusOK, &page)
+		for _, note := range page.Notes {
+			titles = append(titles, note.Title)
+		}
+		path = ""
+		if page.NextCursor != "" {
+			path = "/v1/notes?sort=title&limit=2&cursor=" + page.NextCursor
+		}
+	}
+	if got := strings.Join(titles, ","); got != "a,b,c,d,e" {
+		t.Errorf("listed %s, want a,b,c,d,e", got)
+	}
+
+	var page responses.AllNotes
+	s.expect(s.do("GET", "/v1/notes?order=desc&sort=title", alice, ""), http.StatusOK, &page)
+	if len(page.Notes) != 5 || page.Notes[0].Title != "e" || page.NextCursor != "" {
+		t.Errorf("descending listing %+v", page)
+	}
+
+	s.expect(s.do("GET", "/v1/notes?limit=0", alice, ""), http.StatusBadRequest, nil)
+	s.expect(s.do("GET", "/v1/notes?sort=size", alice, ""), http.StatusBadRequest, nil)
+	s.expect(s.do("GET", "/v1/notes?sort=title&cursor=bogus", alice, ""), http.StatusBadRequest, nil)
+}
+
+func TestConditionalRequests(t *testing.T) {
+	s := newTestServer(t)
+	_, alice := s.addUser("alice")
+	s.expect(s.do("POST", "/v1/notes", alice, `{"title":"Draft"}`), http.StatusCreated, nil)
+
+	w := s.do("GET", "/v1/notes/1", alice, "")
+	s.expect(w, http.StatusOK, nil)
+	etag, modified := w.Header().Get("ETag"), w.Header().Get("Last-Modified")
+	if etag != `"1-1"` {
+		t.Fatalf("ETag = %q", etag)
+	}
+
+	s.expect(s.do("GET", "/v1/notes/1", alice, "", "If-None-Match", etag), http.StatusNotModified, nil)
+	s.expect(s.do("GET", "/v1/notes/1", alice, "", "If-None-Match", `W/`+etag), http.StatusNotModified, nil)
+	s.expect(s.do("GET", "/v1/notes/1", alice, "", "If-None-Match", `"1-7"`), http.StatusOK, nil)
+	s.expect(s.do("GET", "/v1/notes/1", alice, "", "If-Modified-Since", modified), http.StatusNotModified, nil)
+	// If-None-Match takes precedence over If-Modified-Since.
+	s.expect(s.do("GET", "/v1/notes/1", alice, "", "If-None-Match", `"1-7"`, "If-Modified-Since", modified), http.StatusOK, nil)
+
+	w = s.do("PATCH", "/v1/notes/1", alice, `{"content":"first"}`, "If-Match", etag)
+	s.expect(w, http.StatusOK, nil)
+	if w.Header().Get("ETag") != `"1-2"` {
+		t.Errorf("ETag after PATCH = %q", w.Header().Get("ETag"))
+	}
+	s.expect(s.do("PATCH", "/v1/notes/1", alice, `{"content":"lost"}`, "If-Match", etag), http.StatusPreconditionFailed, nil)
+	s.expect(s.do("PUT", "/v1/notes/1", alice, `{"title":"Lost"}`, "If-Match", etag), http.StatusPreconditionFailed, nil)
+	s.expect(s.do("DELETE", "/v1/notes/1", alice, "", "If-Match", etag), http.StatusPreconditionFailed, nil)
+	s.expect(s.do("GET", "/v1/notes/1", alice, "", "If-None-Match", etag), http.StatusOK, nil)
+
+	s.expect(s.do("PUT", "/v1/notes/1", alice, `{"title":"Final"}`, "If-Match", `"1-2"`), http.StatusOK, nil)
+	s.expect(s.do("DELETE", "/v1/notes/1", alice, "", "If-Match", `"1-3"`), http.StatusNoContent, nil)
+}
+
+func TestTrash(t *testing.T) {
+	s := newTestServer(t)
+	_, alice := s.addUser("alice")
+	_, bob := s.addUser("bob")
+	for _, title := range []string{"kept", "restored", "purged"} {
+		s.expect(s.do("POST", "/v1/notes", alice, `{"title":"`+title+`"}`), http.StatusCreated, nil)
+	}
+	s.expect(s.do("DELETE", "/v1/notes/2", alice, ""), http.StatusNoContent, nil)
+	s.expect(s.do("DELETE", "/v1/notes/3", alice, ""), http.StatusNoContent, nil)
+
+	trashed := func(token string) []string {
+		t.Helper()
+		var trash responses.AllNotes
+		s.expect(s.do("GET", "/v1/trash", token, ""), http.StatusOK, &trash)
+		titles := []string{}
+		for _, note := range trash.Notes {
+			titles = append(titles, note.Title)
+		}
+		sort.Strings(titles)
+		return titles
+	}
+	if got := trashed(alice); strings.Join(got, ",") != "purged,restored" {
+		t.Errorf("trash holds %v", got)
+	}
+	if got := trashed(bob); len(got) != 0 {
+		t.Errorf("trash of another user holds %v", got)
+	}
+	var page responses.AllNotes
+	s.expect(s.do("GET", "/v1/notes", alice, ""), http.StatusOK, &page)
+	if len(page.Notes) != 1 || page.Notes[0].Title != "kept" {
+		t.Errorf("listing with trashed notes %+v", page.Notes)
+	}
+
+	s.expect(s.do("POST", "/v1/trash/2/restore", bob, ""), http.StatusNotFound, nil)
+	s.expect(s.do("POST", "/v1/trash/1/restore", alice, ""), http.StatusNotFound, nil)
+	s.expect(s.do("POST", "/v1/trash/2/restore", alice, ""), http.StatusOK, nil)
+	s.expect(s.do("GET", "/v1/notes/2", alice, ""), http.StatusOK, nil)
+
+	s.expect(s.do("DELETE", "/v1/trash/3", bob, ""), http.StatusNotFound, nil)
+	s.expect(s.do("DELETE", "/v1/trash/3", alice, ""), http.StatusNoContent, nil)
+	s.expect(s.do("POST", "/v1/trash/3/restore", alice, ""), http.StatusNotFound, nil)
+	if got := trashed(alice); len(got) != 0 {
+		t.Errorf("trash holds %v after restoring and purging", got)
+	}
+
+	s.expect(s.do("DELETE", "/v1/notes/1", alice, ""), http.StatusNoContent, nil)
+	s.expect(s.do("DELETE", "/v1/trash", alice, ""), http.StatusOK, nil)
+	if got := trashed(alice); len(got) != 0 {
+		t.Errorf("trash holds %v after emptying it", got)
+	}
+	s.expect(s.do("POST", "/v1/trash/1/restore", alice, ""), http.StatusNotFound, nil)
+}
+
+func TestPutNoteFiling(t *testing.T) {
+	s := newTestServer(t)
+	_, alice := s.addUser("alice")
+
+	var notebook responses.Notebook
+	s.expect(s.do("POST", "/v1/notebooks", alice, `{"name":"Work"}`), http.StatusCreated, &notebook)
+	filed := `"notebook_id":` + strconv.Itoa(notebook.Notebook.ID)
+	s.expect(s.do("POST", "/v1/notes", alice, `{"title":"Plan",`+filed+`}`), http.StatusCreated, nil)
+
+	// Sending back the note that was read works, refiling it does not.
+	w := s.do("GET", "/v1/notes/1", alice, "")
+	s.expect(w, http.StatusOK, nil)
+	body := `{"title":"Plan","content":"steps",` + filed + `,"workspace_id":null}`
+	s.expect(s.do("PUT", "/v1/notes/1", alice, body, "If-Match", w.Header().Get("ETag")), http.StatusOK, nil)
+
+	var failed responses.Error
+	s.expect(s.do("PUT", "/v1/notes/1", alice, `{"title":"Plan","notebook_id":99,"workspace_id":7}`), http.StatusUnprocessableEntity, &failed)
+	if failed.Errors["notebook_id"] == "" || failed.Errors["workspace_id"] == "" {
+		t.Errorf("errors %v, want notebook_id and workspace_id", failed.Errors)
+	}
+	s.expect(s.do("PUT", "/v1/notes/2", alice, `{"title":"Plan",`+filed+`}`), http.StatusNotFound, nil)
+
+	var read responses.ReadNote
+	s.expect(s.do("GET", "/v1/notes/1", alice, ""), http.StatusOK, &read)
+	if read.Note.NotebookID == nil || *read.Note.NotebookID != notebook.Notebook.ID || read.Note.Content != "steps" {
+		t.Errorf("after PUT read %+v", read.Note)
+	}
+}
+
+func TestDeprecatedCreateErrors(t *testing.T) {
+	s := newTestServer(t)
+	_, alice := s.addUser("alice")
+	s.expect(s.do("POST", "/v1/note", alice, `{"title":"`+strings.Repeat("a", 101)+`"}`), http.StatusUnprocessableEntity, nil)
+	s.expect(s.do("POST", "/v1/note", alice, `{"title":"Plan","notebook_id":99}`), http.StatusNotFound, nil)
+	s.expect(s.do("POST", "/v1/note", alice, `{"title":"Plan","workspace_id":99}`), http.StatusNotFound, nil)
+
+	var created responses.CreateUpdateNote
+	s.expect(s.do("POST", "/v1/note", alice, `{"title":"Plan"}`), http.StatusOK, &created)
+	if created.Status != "success" || created.NoteID != "1" {
+		t.Errorf("created %+v", created)
+	}
+}
+
+// racingStore updates a note elsewhere right after the next reads of it.
+type racingStore struct {
+	*memory.Store
+	races int
+}
+
+func (s *racingStore) ReadNote(ctx context.Context, user *models.User, noteID int) (models.Note, error) {
+	note, err := s.Store.ReadNote(ctx, user, noteID)
+	if err == nil && s.races > 0 {
+		s.races--
+		concurrent := note
+		concurrent.Content = "changed elsewhere " + strconv.Itoa(s.races)
+		if err := s.Store.UpdateNote(ctx, user, &concurrent); err != nil {
+			return note, err
+		}
+	}
+	return note, err
+}
+
+// TestPatchConcurrentUpdate checks that a PATCH does not overwrite an
+// update made while it was applied.
+func TestPatchConcurrentUpdate(t *testing.T) {
+	s := newTestServer(t)
+	_, alice := s.addUser("alice")
+	s.expect(s.do("POST", "/v1/notes", alice, `{"title":"Plan","content":"draft"}`), http.StatusCreated, nil)
+
+	racing := &racingStore{Store: s.store}
+	s.router = mux.NewRouter()
+	RegisterNoteResourceRoutes(s.router, racing, s.jwtKeys, 1)
+
+	racing.races = 1
+	s.expect(s.do("PATCH", "/v1/notes/1", alice, `{"title":"Final plan"}`), http.StatusOK, nil)
+	var read responses.ReadNote
+	s.expect(s.do("GET", "/v1/notes/1", alice, ""), http.StatusOK, &read)
+	if read.Note.Title != "Final plan" || read.Note.Content != "changed elsewhere 0" {
+		t.Errorf("after PATCH read %+v", read.Note)
+	}
+
+	w := s.do("GET", "/v1/notes/1", alice, "")
+	racing.races = 1
+	s.expect(s.do("PATCH", "/v1/notes/1", alice, `{"title":"Other"}`, "If-Match", w.Header().Get("ETag")), http.StatusPreconditionFailed, nil)
+
+	racing.races = 3
+	s.expect(s.do("PATCH", "/v1/notes/1", alice, `{"title":"Other"}`), http.StatusConflict, nil)
+	s.expect(s.do("GET", "/v1/notes/1", alice, ""), http.StatusOK, &read)
+	if read.Note.Title != "Final plan" {
+		t.Errorf("PATCH that kept conflicting changed the title to %q", read.Note.Title)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"noteserver/internal/pkg/oidc"
+	"os"
+	"regexp"
+	"time"
+)
+
+// providerNamePattern keeps provider names usable in URL paths.
+var providerNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// oidcConfigFile is the format of --oidc-config.
+type oidcConfigFile struct {
+	Providers []oidc.Config `json:"providers"`
+}
+
+// loadOIDCProviders reads the identity providers from a JSON file. Without
+// a file no providers are configured.
+func loadOIDCProviders(path string, timeout time.Duration) (map[string]*oidc.Provider, error) {
+	providers := make(map[string]*oidc.Provider)
+	if path == "" {
+		return providers, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file oidcConfigFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	client := &http.Client{Timeout: timeout}
+	for _, config := range file.Providers {
+		if !providerNamePattern.MatchString(config.Name) {
+			return nil, fmt.Errorf("%s: invalid provider name %q, use lowercase letters, digits, dashes and underscores", path, config.Name)
+		}
+		if _, ok := providers[config.Name]; ok {
+			return nil, fmt.Errorf("%s: duplicate provider %q", path, config.Name)
+		}
+		provider, err := oidc.NewProvider(config, client)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		providers[config.Name] = provider
+	}
+	return providers, nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"noteserver/internal/pkg/api"
+	"noteserver/internal/pkg/loginlimit"
+	"noteserver/internal/pkg/oidc"
+	"noteserver/internal/pkg/responses"
+	"testing"
+	"time"
+)
+
+// addOIDCProvider registers the OIDC routes with a provider named mock,
+// whose discovery document is served by a test server.
+func (s *testServer) addOIDCProvider() {
+	s.t.Helper()
+	var idp *httptest.Server
+	idp = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.URL,
+			"authorization_endpoint": idp.URL + "/authorize",
+			"token_endpoint":         idp.URL + "/token",
+			"jwks_uri":               idp.URL + "/jwks",
+		})
+	}))
+	s.t.Cleanup(idp.Close)
+	provider, err := oidc.NewProvider(oidc.Config{
+		Name:        "mock",
+		Issuer:      idp.URL,
+		ClientID:    "noteserver",
+		RedirectURL: "https://notes.example.com/v1/oidc/mock/callback",
+	}, idp.Client())
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	lifetimes := api.TokenLifetimes{Access: time.Hour, Refresh: 24 * time.Hour}
+	limiters := api.LoginLimiters{
+		Users:     loginlimit.New(loginlimit.Config{MaxFailures: 5, BaseLockout: time.Minute, MaxLockout: time.Hour, Window: time.Hour}),
+		Addresses: loginlimit.New(loginlimit.Config{MaxFailures: 20, BaseLockout: time.Minute, MaxLockout: time.Hour, Window: time.Hour}),
+	}
+	identities := api.OIDCConfig{Providers: map[string]*oidc.Provider{"mock": provider}, Limiters: limiters}
+	RegisterOIDCRoutes(s.router, s.store, s.jwtKeys, lifetimes, identities)
+}
+
+// startOIDCLogin starts a login and returns its state and the cookie that
+// binds it to the browser.
+func (s *testServer) startOIDCLogin() (string, *http.Cookie) {
+	s.t.Helper()
+	w := s.do("GET", "/v1/oidc/mock/login", "", "")
+	s.expect(w, http.StatusFound, nil)
+	location, err := url.Parse(w.Header().Get("Location"))
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || !cookies[0].HttpOnly || !cookies[0].Secure || cookies[0].SameSite != http.SameSiteLaxMode {
+		s.t.Fatalf("login set cookies %v, want one HttpOnly, Secure and SameSite=Lax cookie", cookies)
+	}
+	return location.Query().Get("state"), cookies[0]
+}
+
+func TestOIDCCallbackRequiresStateCookie(t *testing.T) {
+	s := newTestServer(t)
+	s.addOIDCProvider()
+	forged, forgedCookie := s.startOIDCLogin()
+	slipped, _ := s.startOIDCLogin()
+	state, cookie := s.startOIDCLogin()
+	callback := func(state string) string {
+		return "/v1/oidc/mock/callback?error=access_denied&state=" + url.QueryEscape(state)
+	}
+
+	// Callbacks with states of logins started in another browser are
+	// refused, and the logins are discarded.
+	s.expect(s.do("GET", callback(forged), "", ""), http.StatusBadRequest, nil)
+	s.expect(s.do("GET", callback(forged), "", "", "Cookie", forgedCookie.Name+"="+forgedCookie.Value), http.StatusBadRequest, nil)
+	s.expect(s.do("GET", callback(slipped), "", "", "Cookie", cookie.Name+"="+cookie.Value), http.StatusBadRequest, nil)
+
+	w := s.do("GET", callback(state), "", "", "Cookie", cookie.Name+"="+cookie.Value)
+	s.expect(w, http.StatusUnauthorized, nil)
+	if cookies := w.Result().Cookies(); len(cookies) != 1 || cookies[0].MaxAge >= 0 {
+		t.Errorf("callback set cookies %v, want the login cookie removed", cookies)
+	}
+	s.expect(s.do("GET", callback(state), "", "", "Cookie", cookie.Name+"="+cookie.Value), http.StatusBadRequest, nil)
+}
+
+// TestOIDCLinkWithoutCookie checks that API clients can link identities,
+// which are bound to the account rather than to a browser.
+func TestOIDCLinkWithoutCookie(t *testing.T) {
+	s := newTestServer(t)
+	s.addOIDCProvider()
+	_, alice := s.addUser("alice")
+
+	s.expect(s.do("POST", "/v1/account/identities/mock", alice, `{"password":"wrong-password"}`), http.StatusForbidden, nil)
+	var started responses.AuthorizationURL
+	w := s.do("POST", "/v1/account/identities/mock", alice, `{"password":"`+testPassword+`"}`)
+	s.expect(w, http.StatusOK, &started)
+	if cookies := w.Result().Cookies(); len(cookies) != 0 {
+		t.Errorf("link set cookies %v", cookies)
+	}
+	authURL, err := url.Parse(started.AuthorizationURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	callback := "/v1/oidc/mock/callback?error=access_denied&state=" + url.QueryEscape(authURL.Query().Get("state"))
+	s.expect(s.do("GET", callback, "", ""), http.StatusUnauthorized, nil)
+}
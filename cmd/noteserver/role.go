@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"noteserver/internal/pkg/database"
+	l "noteserver/internal/pkg/logger"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/storage/postgres"
+)
+
+// runSetRole changes the role of a user from the command line, which is how
+// the first admin is made.
+func runSetRole(args []string) {
+	var sqlServer string
+
+	flags := flag.NewFlagSet("set-role", flag.ExitOnError)
+	flags.StringVar(&sqlServer, "sql-server", defaultSQLServer, "Parameters of SQL-Server")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: noteserver set-role [flags] username user | admin | read-only")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 2 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	username, role := flags.Arg(0), flags.Arg(1)
+	known := false
+	for _, r := range models.Roles {
+		known = known || r == role
+	}
+	if !known {
+		log.Fatal("Unknown role: ", role)
+	}
+
+	err := l.InitLogger()
+	if err != nil {
+		log.Fatal("Failed to initialize the logger", err)
+	}
+	if !checkPostgreSQL(sqlServer) {
+		log.Fatal("Incorrect SQL-server parameters")
+	}
+
+	ctx := context.Background()
+	db, err := database.Connect(ctx, database.Config{URL: sqlServer})
+	if err != nil {
+		log.Fatal("Failed to connect to database: ", err)
+	}
+	defer db.Close()
+
+	store := postgres.New(db)
+	user, err := store.GetUserByUsername(ctx, username)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if user == nil {
+		log.Fatal("Unknown user: ", username)
+	}
+	if err := store.SetUserRole(ctx, user.ID, role); err != nil {
+		log.Fatal(err)
+	}
+	l.Logger.WithFields(map[string]interface{}{"audit": "admin", "user": username, "role": role}).Warn("Role changed from the command line")
+	fmt.Printf("%s now has the role %s\n", username, role)
+}
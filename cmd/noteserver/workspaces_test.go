@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/http"
+	"noteserver/internal/pkg/responses"
+	"strconv"
+	"testing"
+)
+
+// TestDemotedAuthor checks that authors of workspace notes who become
+// viewers can no longer change or delete their notes.
+func TestDemotedAuthor(t *testing.T) {
+	s := newTestServer(t)
+	_, alice := s.addUser("alice")
+	_, bob := s.addUser("bob")
+
+	var workspace responses.Workspace
+	s.expect(s.do("POST", "/v1/workspaces", alice, `{"name":"Team"}`), http.StatusCreated, &workspace)
+	members := "/v1/workspaces/" + strconv.Itoa(workspace.Workspace.ID) + "/members"
+	s.expect(s.do("POST", members, alice, `{"username":"bob","role":"editor"}`), http.StatusOK, nil)
+
+	var created responses.CreateUpdateNote
+	s.expect(s.do("POST", "/v1/notes", bob, `{"title":"Plan","workspace_id":`+strconv.Itoa(workspace.Workspace.ID)+`}`), http.StatusCreated, &created)
+	note := "/v1/notes/" + created.NoteID
+	s.expect(s.do("PATCH", note, bob, `{"content":"draft"}`), http.StatusOK, nil)
+
+	s.expect(s.do("POST", members, alice, `{"username":"bob","role":"viewer"}`), http.StatusOK, nil)
+	s.expect(s.do("GET", note, bob, ""), http.StatusOK, nil)
+	s.expect(s.do("PATCH", note, bob, `{"content":"changed"}`), http.StatusForbidden, nil)
+	s.expect(s.do("PUT", note, bob, `{"title":"Changed"}`), http.StatusForbidden, nil)
+	s.expect(s.do("DELETE", note, bob, ""), http.StatusForbidden, nil)
+
+	s.expect(s.do("POST", members, alice, `{"username":"bob","role":"editor"}`), http.StatusOK, nil)
+	s.expect(s.do("PATCH", note, bob, `{"content":"final"}`), http.StatusOK, nil)
+	s.expect(s.do("DELETE", note, bob, ""), http.StatusNoContent, nil)
+}
+
+// TestDemotedAuthorDeletesNotebook checks that deleting a notebook with its
+// contents does not move workspace notes of viewers to the trash.
+func TestDemotedAuthorDeletesNotebook(t *testing.T) {
+	s := newTestServer(t)
+	_, alice := s.addUser("alice")
+	_, bob := s.addUser("bob")
+
+	var workspace responses.Workspace
+	s.expect(s.do("POST", "/v1/workspaces", alice, `{"name":"Team"}`), http.StatusCreated, &workspace)
+	members := "/v1/workspaces/" + strconv.Itoa(workspace.Workspace.ID) + "/members"
+	s.expect(s.do("POST", members, alice, `{"username":"bob","role":"editor"}`), http.StatusOK, nil)
+
+	var notebook responses.Notebook
+	s.expect(s.do("POST", "/v1/notebooks", bob, `{"name":"Work"}`), http.StatusCreated, &notebook)
+	filed := `"notebook_id":` + strconv.Itoa(notebook.Notebook.ID)
+	var shared, personal responses.CreateUpdateNote
+	s.expect(s.do("POST", "/v1/notes", bob, `{"title":"Plan",`+filed+`,"workspace_id":`+strconv.Itoa(workspace.Workspace.ID)+`}`), http.StatusCreated, &shared)
+	s.expect(s.do("POST", "/v1/notes", bob, `{"title":"Draft",`+filed+`}`), http.StatusCreated, &personal)
+
+	s.expect(s.do("POST", members, alice, `{"username":"bob","role":"viewer"}`), http.StatusOK, nil)
+	s.expect(s.do("DELETE", "/v1/notebooks/"+strconv.Itoa(notebook.Notebook.ID)+"?mode=cascade", bob, ""), http.StatusNoContent, nil)
+
+	var read responses.ReadNote
+	s.expect(s.do("GET", "/v1/notes/"+shared.NoteID, alice, ""), http.StatusOK, &read)
+	if read.Note.NotebookID != nil || read.Note.DeletedAt != nil {
+		t.Errorf("workspace note %+v, want it unfiled and not in the trash", read.Note)
+	}
+	s.expect(s.do("GET", "/v1/notes/"+personal.NoteID, bob, ""), http.StatusNotFound, nil)
+}
@@ -6,6 +6,7 @@ POSTGRES_CONTAINER="notes_database"
 POSTGRES_PORT="5432"
 NOTESERVER_PORT="8080"
 NOTESERVER_CONTAINER="notes_server"
+JWT_SECRET="${JWT_SECRET:-$(openssl rand -hex 32)}"
 
  
 if ! command -v docker &> /dev/null; then
@@ -22,32 +23,6 @@ docker run --name $POSTGRES_CONTAINER -p $POSTGRES_PORT:$POSTGRES_PORT -e POSTGR
 
 sleep 10
  
-# Setting up the database
-# Connect to the PostgreSQL database using pgcli or any other client
-# Replace the hostname and port with appropriate values
- 
-# Set up tables using pgcli or other PostgreSQL client
-
-
-SETDB_COMMAND=$(cat <<EOF
-CREATE TABLE Users (
-  user_id SERIAL PRIMARY KEY,
-  username VARCHAR(50) NOT NULL,
-  password_hash VARCHAR(100) NOT NULL
-);
- 
-CREATE TABLE Notes (
-  note_id SERIAL PRIMARY KEY,
-  user_id INT REFERENCES Users(user_id),
-  title VARCHAR(100) NOT NULL,
-  content TEXT,
-  created_at TIMESTAMP DEFAULT NOW()
-);
-EOF
-)
- 
-PGPASSWORD="$POSTGRES_PASSWORD" docker exec -it "$POSTGRES_CONTAINER" psql -U postgres -d postgres -c "$SETDB_COMMAND"
- 
 # Build NoteServer Docker image
 docker build -t notes:1.0 $PWD
  
@@ -59,9 +34,9 @@ if [ -z "$POSTGRES_IP" ]; then
   exit 1
 fi
 
-# Run NoteServer Docker image
+# Run NoteServer Docker image, the database schema is created by the embedded migrations
 docker run -d --name $NOTESERVER_CONTAINER -p $NOTESERVER_PORT:8080 \
   notes:1.0 \
-  go/bin/noteserver -sql-server "postgresql://postgres:$POSTGRES_PASSWORD@$POSTGRES_IP:$POSTGRES_PORT/postgres"
+  go/bin/noteserver -auto-migrate -jwt-secret "$JWT_SECRET" -sql-server "postgresql://postgres:$POSTGRES_PASSWORD@$POSTGRES_IP:$POSTGRES_PORT/postgres"
 
  
@@ -0,0 +1,277 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"noteserver/internal/pkg/jwtkeys"
+	l "noteserver/internal/pkg/logger"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/notify"
+	"noteserver/internal/pkg/passwords"
+	"noteserver/internal/pkg/requests"
+	"noteserver/internal/pkg/responses"
+	"noteserver/internal/pkg/storage"
+	"strings"
+	"time"
+)
+
+const (
+	maxEmailLength = 254
+	// notifyTimeout bounds the delivery of a password reset message.
+	notifyTimeout = 30 * time.Second
+)
+
+// PasswordResetConfig configures the delivery of password reset tokens. When
+// URL is set, messages link to it with the token in the query string.
+type PasswordResetConfig struct {
+	Notifier notify.Notifier
+	TTL      time.Duration
+	URL      string
+}
+
+func GetAccountHandler(w http.ResponseWriter, r *http.Request, store storage.Store) {
+	user, ok := accountUser(w, r, store)
+	if !ok {
+		return
+	}
+	enabled, err := store.GetTOTP(r.Context(), user.ID)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, responses.Account{
+		Status:           "success",
+		Message:          "Account retrieved successfully",
+		User:             user,
+		TwoFactorEnabled: enabled != nil && enabled.EnabledAt != nil,
+	})
+}
+
+func PatchAccountHandler(w http.ResponseWriter, r *http.Request, store storage.Store) {
+	user, ok := accountUser(w, r, store)
+	if !ok {
+		return
+	}
+	var body requests.AccountPatch
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	if body.Email != nil {
+		email, err := normalizeEmail(*body.Email)
+		if err != nil {
+			validationError(w, map[string]string{"email": err.Error()})
+			return
+		}
+		if err := store.UpdateEmail(r.Context(), user.ID, email); err != nil {
+			accountError(w, err)
+			return
+		}
+		user.Email = email
+	}
+	writeJSON(w, http.StatusOK, responses.Account{
+		Status:  "success",
+		Message: "Account has been updated successfully",
+		User:    user,
+	})
+}
+
+// ChangePasswordHandler sets a new password after checking the current one,
+// wrong passwords count as failed logins. Every session and personal access
+// token of the user is revoked and a new session is started for the caller.
+func ChangePasswordHandler(w http.ResponseWriter, r *http.Request, store storage.Store, jwtKeys *jwtkeys.KeySet, lifetimes TokenLifetimes, policy *passwords.Policy, limiters LoginLimiters) {
+	user, ok := accountUser(w, r, store)
+	if !ok {
+		return
+	}
+	var body requests.PasswordChange
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	if !checkCurrentPassword(w, r, limiters, user, body.CurrentPassword) {
+		return
+	}
+	if err := policy.Check(body.NewPassword, user.Username); err != nil {
+		validationError(w, map[string]string{"new_password": err.Error()})
+		return
+	}
+
+	passwordHash, err := HashPassword(body.NewPassword)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	if err := store.UpdatePassword(r.Context(), user.ID, passwordHash); err != nil {
+		internalError(w, err)
+		return
+	}
+	if err := revokeCredentials(r.Context(), store, user.ID); err != nil {
+		internalError(w, err)
+		return
+	}
+	l.Logger.Info("Password changed for user ", user.ID)
+
+	response, err := issueTokens(r.Context(), store, user, jwtKeys, lifetimes)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// ForgotPasswordHandler sends a password reset token to the email address
+// if it belongs to an account. The response is the same either way and the
+// token is created and delivered in the background, so the endpoint does
+// not reveal which addresses are registered.
+func ForgotPasswordHandler(w http.ResponseWriter, r *http.Request, store storage.Store, resets PasswordResetConfig) {
+	var body requests.PasswordForgot
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	email, err := normalizeEmail(body.Email)
+	if err != nil || email == "" {
+		validationError(w, map[string]string{"email": "A valid email address is required"})
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+		defer cancel()
+		if err := sendPasswordReset(ctx, store, resets, email); err != nil {
+			l.Logger.Error("Failed to send password reset:", err)
+		}
+	}()
+
+	writeJSON(w, http.StatusAccepted, responses.NewStatus("If the address belongs to an account, a password reset token has been sent to it"))
+}
+
+// ResetPasswordHandler sets a new password with a reset token and revokes
+// every session and personal access token of the user.
+func ResetPasswordHandler(w http.ResponseWriter, r *http.Request, store storage.Store, policy *passwords.Policy) {
+	var body requests.PasswordReset
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	if err := policy.Check(body.NewPassword, ""); err != nil {
+		validationError(w, map[string]string{"new_password": err.Error()})
+		return
+	}
+	passwordHash, err := HashPassword(body.NewPassword)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+
+	userID, err := store.ResetPassword(r.Context(), hashToken(body.Token), passwordHash, time.Now())
+	if errors.Is(err, storage.ErrResetTokenNotFound) {
+		writeJSON(w, http.StatusBadRequest, responses.NewError(err.Error()))
+		return
+	}
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	if err := revokeCredentials(r.Context(), store, userID); err != nil {
+		internalError(w, err)
+		return
+	}
+	l.Logger.Info("Password reset for user ", userID)
+
+	writeJSON(w, http.StatusOK, responses.NewStatus("Password has been reset successfully"))
+}
+
+// revokeCredentials revokes the sessions of the user and deletes their
+// personal access tokens, so that nothing issued with the old password
+// stays valid.
+func revokeCredentials(ctx context.Context, store storage.Store, userID int) error {
+	if err := store.RevokeUserTokens(ctx, userID); err != nil {
+		return err
+	}
+	return store.DeleteUserPersonalTokens(ctx, userID)
+}
+
+func sendPasswordReset(ctx context.Context, store storage.Store, resets PasswordResetConfig, email string) error {
+	user, err := store.GetUserByEmail(ctx, email)
+	if err != nil || user == nil {
+		return err
+	}
+	return sendResetToken(ctx, store, resets, user)
+}
+
+// sendResetToken creates a password reset token and sends it to the email
+// address of the user.
+func sendResetToken(ctx context.Context, store storage.Store, resets PasswordResetConfig, user *models.User) error {
+	token, err := randomToken(32)
+	if err != nil {
+		return err
+	}
+	reset := models.PasswordReset{
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(resets.TTL),
+	}
+	if err := store.CreatePasswordReset(ctx, &reset); err != nil {
+		return err
+	}
+
+	var body strings.Builder
+	body.WriteString("Hello " + user.Username + ",\n\n")
+	body.WriteString("a password reset was requested for your NoteServer account.\n\n")
+	if resets.URL != "" {
+		body.WriteString("Open the following link to choose a new password:\n\n")
+		body.WriteString(resets.URL + "?" + url.Values{"token": {token}}.Encode() + "\n\n")
+	} else {
+		body.WriteString("Use the following token to choose a new password:\n\n")
+		body.WriteString(token + "\n\n")
+	}
+	body.WriteString("The token expires in " + resets.TTL.String() + ". ")
+	body.WriteString("If you did not request a reset, you can ignore this message.\n")
+
+	return resets.Notifier.Send(ctx, notify.Message{
+		To:      user.Email,
+		Subject: "Reset your NoteServer password",
+		Body:    body.String(),
+	})
+}
+
+// accountUser loads the full record of the authenticated user.
+func accountUser(w http.ResponseWriter, r *http.Request, store storage.Store) (*models.User, bool) {
+	principal, ok := requestUser(w, r)
+	if !ok {
+		return nil, false
+	}
+	user, err := store.GetUserByID(r.Context(), principal.ID)
+	if err != nil {
+		internalError(w, err)
+		return nil, false
+	}
+	if user == nil {
+		writeJSON(w, http.StatusUnauthorized, responses.NewError("Unauthorized"))
+		return nil, false
+	}
+	return user, true
+}
+
+// normalizeEmail checks a bare email address such as user@example.com. An
+// empty address is returned as is.
+func normalizeEmail(email string) (string, error) {
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return "", nil
+	}
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email || address.Name != "" || len(email) > maxEmailLength {
+		return "", errors.New("Email must be a valid address")
+	}
+	return email, nil
+}
+
+func accountError(w http.ResponseWriter, err error) {
+	if errors.Is(err, storage.ErrEmailExists) {
+		writeJSON(w, http.StatusConflict, responses.NewError(err.Error()))
+		return
+	}
+	internalError(w, err)
+}
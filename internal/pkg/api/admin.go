@@ -0,0 +1,221 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	l "noteserver/internal/pkg/logger"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/requests"
+	"noteserver/internal/pkg/responses"
+	"noteserver/internal/pkg/storage"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// The handlers of the admin API are only reachable with the ScopeAdmin
+// scope, which only admins hold. Every call is written to the log with the
+// audit=admin field.
+
+// ListUsersHandler lists users with their note counts and storage. The q
+// parameter searches usernames and email addresses.
+func ListUsersHandler(w http.ResponseWriter, r *http.Request, store storage.Store) {
+	params := r.URL.Query()
+	limit, offset, err := parsePage(params)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, responses.NewError(err.Error()))
+		return
+	}
+	users, err := store.ListUsers(r.Context(), storage.UserQuery{
+		Search: params.Get("q"),
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	adminAuditLogger(r, nil).WithField("search", params.Get("q")).Info("Users listed")
+	writeJSON(w, http.StatusOK, responses.AdminUsers{
+		Status:  "success",
+		Message: "Users retrieved successfully",
+		Users:   users,
+	})
+}
+
+func GetUserHandler(w http.ResponseWriter, r *http.Request, store storage.Store) {
+	target, ok := adminTarget(w, r, store)
+	if !ok {
+		return
+	}
+	adminAuditLogger(r, &target.User).Info("User viewed")
+	writeJSON(w, http.StatusOK, responses.AdminUser{
+		Status:  "success",
+		Message: "User retrieved successfully",
+		User:    target,
+	})
+}
+
+// PatchUserHandler changes the role of a user, or disables or enables the
+// account. Disabling revokes all sessions of the user. Admins cannot
+// demote or disable themselves, so that there is always an admin left.
+func PatchUserHandler(w http.ResponseWriter, r *http.Request, store storage.Store) {
+	target, ok := adminTarget(w, r, store)
+	if !ok {
+		return
+	}
+	var body requests.AdminUserPatch
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	if body.Role != nil && !validRole(*body.Role) {
+		validationError(w, map[string]string{"role": "Role must be one of user, admin, read-only"})
+		return
+	}
+	if isSelf(r, target.ID) && ((body.Role != nil && *body.Role != models.RoleAdmin) || (body.Disabled != nil && *body.Disabled)) {
+		writeJSON(w, http.StatusConflict, responses.NewError("Admins cannot demote or disable themselves"))
+		return
+	}
+
+	if body.Role != nil && *body.Role != target.Role {
+		if err := store.SetUserRole(r.Context(), target.ID, *body.Role); err != nil {
+			adminError(w, err)
+			return
+		}
+		adminAuditLogger(r, &target.User).WithField("role", *body.Role).Warn("Role changed from " + target.Role)
+	}
+	if body.Disabled != nil && *body.Disabled != (target.DisabledAt != nil) {
+		if err := store.SetUserDisabled(r.Context(), target.ID, *body.Disabled); err != nil {
+			adminError(w, err)
+			return
+		}
+		if *body.Disabled {
+			if err := store.RevokeUserTokens(r.Context(), target.ID); err != nil {
+				internalError(w, err)
+				return
+			}
+			adminAuditLogger(r, &target.User).Warn("Account disabled")
+		} else {
+			adminAuditLogger(r, &target.User).Warn("Account enabled")
+		}
+	}
+
+	updated, err := store.GetUserStats(r.Context(), target.ID)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, responses.AdminUser{
+		Status:  "success",
+		Message: "User has been updated successfully",
+		User:    updated,
+	})
+}
+
+// ForcePasswordResetHandler invalidates the password of a user, revokes all
+// sessions and personal access tokens and sends a password reset token to the email address of the
+// user, who has to choose a new password before logging in again.
+func ForcePasswordResetHandler(w http.ResponseWriter, r *http.Request, store storage.Store, resets PasswordResetConfig) {
+	target, ok := adminTarget(w, r, store)
+	if !ok {
+		return
+	}
+	if target.Email == "" {
+		writeJSON(w, http.StatusConflict, responses.NewError("User has no email address to send a password reset to"))
+		return
+	}
+	if err := store.UpdatePassword(r.Context(), target.ID, ""); err != nil {
+		internalError(w, err)
+		return
+	}
+	if err := revokeCredentials(r.Context(), store, target.ID); err != nil {
+		internalError(w, err)
+		return
+	}
+	adminAuditLogger(r, &target.User).Warn("Password reset forced")
+
+	ctx, cancel := context.WithTimeout(r.Context(), notifyTimeout)
+	defer cancel()
+	if err := sendResetToken(ctx, store, resets, &target.User); err != nil {
+		l.Logger.Error("Failed to send password reset:", err)
+		writeJSON(w, http.StatusBadGateway, responses.NewError("Password has been invalidated, but the reset message could not be sent"))
+		return
+	}
+	writeJSON(w, http.StatusOK, responses.NewStatus("Password has been invalidated and a reset token has been sent to the user"))
+}
+
+// PurgeUserDataHandler deletes all notes, including the trash, tags and
+// notebooks of a user for good. The account itself is kept.
+func PurgeUserDataHandler(w http.ResponseWriter, r *http.Request, store storage.Store) {
+	target, ok := adminTarget(w, r, store)
+	if !ok {
+		return
+	}
+	deleted, err := store.DeleteAllNotes(r.Context(), &target.User)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	adminAuditLogger(r, &target.User).WithField("deleted_notes", deleted).Warn("User data purged")
+	writeJSON(w, http.StatusOK, responses.PurgedNotes{
+		Status:       "success",
+		Message:      "User data has been purged",
+		DeletedNotes: deleted,
+	})
+}
+
+// adminTarget loads the user named by the id in the path.
+func adminTarget(w http.ResponseWriter, r *http.Request, store storage.Store) (*models.UserStats, bool) {
+	userID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, responses.NewError("Invalid user id"))
+		return nil, false
+	}
+	target, err := store.GetUserStats(r.Context(), userID)
+	if err != nil {
+		internalError(w, err)
+		return nil, false
+	}
+	if target == nil {
+		writeJSON(w, http.StatusNotFound, responses.NewError(storage.ErrUserNotFound.Error()))
+		return nil, false
+	}
+	return target, true
+}
+
+func isSelf(r *http.Request, userID int) bool {
+	principal, ok := PrincipalFromContext(r.Context())
+	return ok && principal.UserID == userID
+}
+
+func validRole(role string) bool {
+	for _, known := range models.Roles {
+		if role == known {
+			return true
+		}
+	}
+	return false
+}
+
+// adminAuditLogger names the admin making the request and the user it
+// concerns, if any.
+func adminAuditLogger(r *http.Request, target *models.User) *logrus.Entry {
+	fields := logrus.Fields{"audit": "admin", "ip": clientAddr(r)}
+	if principal, ok := PrincipalFromContext(r.Context()); ok {
+		fields["admin"] = principal.Username
+	}
+	if target != nil {
+		fields["user"] = target.Username
+		fields["user_id"] = target.ID
+	}
+	return l.Logger.WithFields(fields)
+}
+
+func adminError(w http.ResponseWriter, err error) {
+	if err == storage.ErrUserNotFound {
+		writeJSON(w, http.StatusNotFound, responses.NewError(err.Error()))
+		return
+	}
+	internalError(w, err)
+}
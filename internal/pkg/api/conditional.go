@@ -0,0 +1,90 @@
+package api
+
+import (
+	"net/http"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/responses"
+	"noteserver/internal/pkg/storage"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// noteETag identifies a version of a note, it changes whenever the note,
+// its tags or its notebook change.
+func noteETag(note models.Note) string {
+	return `"` + strconv.Itoa(note.ID) + "-" + strconv.Itoa(note.Version) + `"`
+}
+
+// setValidators sets the ETag and Last-Modified headers of a note.
+func setValidators(w http.ResponseWriter, note models.Note) {
+	w.Header().Set("ETag", noteETag(note))
+	w.Header().Set("Last-Modified", note.UpdatedAt.UTC().Format(http.TimeFormat))
+}
+
+// etagMatches reports whether etag is in a list of entity tags from an
+// If-Match (strong comparison) or If-None-Match (weak comparison) header.
+func etagMatches(header string, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// notModified answers a GET with 304 when the client already has the current
+// version of the note. If-Modified-Since is only used when the request has
+// no If-None-Match header.
+func notModified(w http.ResponseWriter, r *http.Request, note models.Note) bool {
+	if header := r.Header.Get("If-None-Match"); header != "" {
+		if !etagMatches(header, noteETag(note), true) {
+			return false
+		}
+	} else {
+		since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+		// Last-Modified has a precision of one second.
+		if err != nil || note.UpdatedAt.Truncate(time.Second).After(since) {
+			return false
+		}
+	}
+	setValidators(w, note)
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// ifMatchVersion evaluates If-Match against the current note and returns the
+// version the update must be applied to, zero when the header is absent.
+// The store checks the version again so that a concurrent update between
+// this check and the write is detected as well.
+func ifMatchVersion(w http.ResponseWriter, r *http.Request, note models.Note) (int, bool) {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return 0, true
+	}
+	if !etagMatches(header, noteETag(note), false) {
+		writeJSON(w, http.StatusPreconditionFailed, responses.NewError(storage.ErrVersionMismatch.Error()))
+		return 0, false
+	}
+	return note.Version, true
+}
+
+// ifMatchNote is ifMatchVersion for handlers that have not read the note.
+func ifMatchNote(w http.ResponseWriter, r *http.Request, store storage.Store, user *models.User, noteID int) (int, bool) {
+	if r.Header.Get("If-Match") == "" {
+		return 0, true
+	}
+	note, err := store.ReadNote(r.Context(), user, noteID)
+	if err != nil {
+		noteError(w, err)
+		return 0, false
+	}
+	return ifMatchVersion(w, r, note)
+}
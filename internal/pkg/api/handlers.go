@@ -2,79 +2,113 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"noteserver/internal/pkg/actions"
+	"noteserver/internal/pkg/jwtkeys"
 	l "noteserver/internal/pkg/logger"
 	"noteserver/internal/pkg/models"
-	"noteserver/internal/pkg/notes"
+	"noteserver/internal/pkg/requests"
 	"noteserver/internal/pkg/responses"
+	"noteserver/internal/pkg/storage"
 	"noteserver/internal/pkg/yandex"
 	"strconv"
-
-	"github.com/dgrijalva/jwt-go"
-	"github.com/jackc/pgx/v4"
+	"time"
 )
 
-func HandleLogin(w http.ResponseWriter, r *http.Request, db *pgx.Conn, jwtSecret []byte) {
+// HandleLogin answers unknown users and wrong passwords alike, in content
+// and in timing, and locks out usernames and client addresses after
+// repeated failures.
+func HandleLogin(w http.ResponseWriter, r *http.Request, store storage.Store, jwtKeys *jwtkeys.KeySet, lifetimes TokenLifetimes, limiters LoginLimiters) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	var user models.User
-	err := json.NewDecoder(r.Body).Decode(&user)
+	var credentials requests.Login
+	err := json.NewDecoder(r.Body).Decode(&credentials)
 	if err != nil {
 		l.Logger.Error("Error:", err)
 		http.Error(w, "Bad request", http.StatusBadRequest)
 		return
 	}
 
-	storedUser, err := GetUserByUsername(user.Username, db)
+	userKey, addrKey := limiters.keys(r, credentials.Username)
+	now := time.Now()
+	if wait, ok := limiters.attempt(userKey, addrKey, now); !ok {
+		tooManyAttempts(w, wait)
+		return
+	}
+
+	storedUser, err := store.GetUserByUsername(r.Context(), credentials.Username)
 	if err != nil {
+		limiters.refund(userKey, addrKey)
 		l.Logger.Error("Error:", err)
-		http.Error(w, "No such user", http.StatusInternalServerError)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	if storedUser == nil || !ComparePasswords(storedUser.Password, user.Password) {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	hashedPassword := string(dummyHash)
+	if storedUser != nil {
+		hashedPassword = storedUser.Password
+	}
+	if !ComparePasswords(hashedPassword, credentials.Password) || storedUser == nil {
+		if wait := limiters.fail(userKey, addrKey, now); wait > 0 {
+			auditLogger(r, credentials.Username).WithField("lockout", wait.String()).Warn("Login locked out after repeated failures")
+			tooManyAttempts(w, wait)
+			return
+		}
+		auditLogger(r, credentials.Username).Info("Failed login")
+		http.Error(w, "Invalid username or password", http.StatusUnauthorized)
 		return
 	}
 
-	tokenString, err := GenerateJWTToken(storedUser, jwtSecret)
+	if storedUser.DisabledAt != nil {
+		limiters.refund(userKey, addrKey)
+		auditLogger(r, storedUser.Username).Warn("Login of disabled account refused")
+		http.Error(w, "Account has been disabled", http.StatusForbidden)
+		return
+	}
+
+	// With two-factor authentication the failures of the username are only
+	// cleared by the second step, or a known password would allow to guess
+	// codes without ever being locked out.
+	enabled, err := store.GetTOTP(r.Context(), storedUser.ID)
 	if err != nil {
+		limiters.refund(userKey, addrKey)
 		l.Logger.Error("Error:", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
+	if enabled != nil && enabled.EnabledAt != nil {
+		limiters.refund(userKey, addrKey)
+		mfaChallenge(w, storedUser, jwtKeys)
+		return
+	}
+	limiters.succeed(userKey, addrKey)
 
-	response := struct {
-		Token string `json:"token"`
-	}{
-		Token: tokenString,
+	response, err := issueTokens(r.Context(), store, storedUser, jwtKeys, lifetimes)
+	if err != nil {
+		l.Logger.Error("Error:", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
-func HandleDeleteUser(w http.ResponseWriter, r *http.Request, db *pgx.Conn, jwtSecret []byte) {
-	tokenString := r.Header.Get("Authorization")
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return jwtSecret, nil
-	})
+func HandleDeleteUser(w http.ResponseWriter, r *http.Request, store storage.Store) {
+	user, err := contextUser(r)
 	if err != nil {
 		l.Logger.Error("Error:", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
-	user, err := GetUserFromToken(token, db)
-	if err != nil {
-		l.Logger.Error("Error:", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
+	err = store.RevokeUserTokens(r.Context(), user.ID)
+	if err == nil {
+		err = store.DeleteUser(r.Context(), *user)
 	}
-	err = DeleteUser(*user, db)
 	var response struct {
 		Message string `json:"message"`
 	}
@@ -88,43 +122,66 @@ func HandleDeleteUser(w http.ResponseWriter, r *http.Request, db *pgx.Conn, jwtS
 	json.NewEncoder(w).Encode(response)
 }
 
-func HandleRegister(w http.ResponseWriter, r *http.Request, db *pgx.Conn) {
+// HandleRegister creates an account unless registration is closed. When
+// it is invite only, the invite is consumed together with the creation.
+func HandleRegister(w http.ResponseWriter, r *http.Request, store storage.Store, registration RegistrationConfig) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
+	if registration.Mode == RegistrationClosed {
+		writeJSON(w, http.StatusForbidden, responses.NewError("Registration is closed"))
+		return
+	}
 
-	var user models.User
-	err := json.NewDecoder(r.Body).Decode(&user)
+	var body requests.Register
+	err := json.NewDecoder(r.Body).Decode(&body)
 	if err != nil {
 		l.Logger.Error("Error:", err)
 		http.Error(w, "Bad request", http.StatusBadRequest)
 		return
 	}
 
-	existingUser, err := GetUserByUsername(user.Username, db)
+	fieldErrors := make(map[string]string)
+	if err := checkUsername(body.Username); err != nil {
+		fieldErrors["username"] = err.Error()
+	}
+	if err := registration.Policy.Check(body.Password, body.Username); err != nil {
+		fieldErrors["password"] = err.Error()
+	}
+	email, err := normalizeEmail(body.Email)
 	if err != nil {
-		l.Logger.Error("Error:", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
+		fieldErrors["email"] = err.Error()
 	}
-
-	if existingUser != nil {
-		http.Error(w, "Username already exists", http.StatusConflict)
+	if registration.Mode == RegistrationInvite && body.InviteCode == "" {
+		fieldErrors["invite_code"] = "Invite code is required"
+	}
+	if len(fieldErrors) > 0 {
+		validationError(w, fieldErrors)
 		return
 	}
 
-	hashedPassword, err := HashPassword(user.Password)
+	hashedPassword, err := HashPassword(body.Password)
 	if err != nil {
 		l.Logger.Error("Error:", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
+	user := models.User{Username: body.Username, Password: hashedPassword, Email: email}
 
-	user.Password = hashedPassword
-
-	err = SaveUser(user, db)
-	if err != nil {
+	if registration.Mode == RegistrationInvite {
+		err = store.RegisterWithInvite(r.Context(), user, hashToken(body.InviteCode), time.Now())
+	} else {
+		err = store.SaveUser(r.Context(), user)
+	}
+	switch {
+	case errors.Is(err, storage.ErrInviteNotFound):
+		validationError(w, map[string]string{"invite_code": err.Error()})
+		return
+	case errors.Is(err, storage.ErrUserExists), errors.Is(err, storage.ErrEmailExists):
+		writeJSON(w, http.StatusConflict, responses.NewError(err.Error()))
+		return
+	case err != nil:
 		l.Logger.Error("Error:", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
@@ -139,24 +196,20 @@ func HandleRegister(w http.ResponseWriter, r *http.Request, db *pgx.Conn) {
 	json.NewEncoder(w).Encode(response)
 }
 
-func HandleNotesAction(w http.ResponseWriter, r *http.Request, db *pgx.Conn, jwtSecret []byte, action actions.Type, apiTimeout int) {
-	tokenString := r.Header.Get("Authorization")
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return jwtSecret, nil
-	})
+func HandleNotesAction(w http.ResponseWriter, r *http.Request, store storage.Store, action actions.Type, apiTimeout int) {
+	user, err := contextUser(r)
 	if err != nil {
 		l.Logger.Error("Error:", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
-	user, err := GetUserFromToken(token, db)
-	if err != nil {
-		l.Logger.Error("Error:", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	var note models.Note
+	err = json.NewDecoder(http.MaxBytesReader(w, r.Body, maxNoteBodySize)).Decode(&note)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		http.Error(w, "Request body is too large", http.StatusRequestEntityTooLarge)
 		return
 	}
-	var note models.Note
-	err = json.NewDecoder(r.Body).Decode(&note)
 	if err != nil {
 		l.Logger.Error("Error:", err)
 		http.Error(w, "Bad request", http.StatusBadRequest)
@@ -164,27 +217,29 @@ func HandleNotesAction(w http.ResponseWriter, r *http.Request, db *pgx.Conn, jwt
 	}
 	switch action {
 	case 1:
-		CreateNoteHandler(w, r, db, user, &note, apiTimeout)
+		CreateNoteHandler(w, r, store, user, &note, apiTimeout)
 		return
 	case 2:
-		ReadNoteHandler(w, r, db, user, &note)
+		ReadNoteHandler(w, r, store, user, &note)
 		return
 	case 3:
-		UpdateNoteHandler(w, r, db, user, &note, apiTimeout)
+		UpdateNoteHandler(w, r, store, user, &note, apiTimeout)
 		return
 	case 4:
-		DeleteNoteHandler(w, r, db, user, &note)
+		DeleteNoteHandler(w, r, store, user, &note)
 		return
 	default:
 		return
 	}
 }
 
-func CreateNoteHandler(w http.ResponseWriter, r *http.Request, db *pgx.Conn, user *models.User, note *models.Note, apiTimeout int) {
-	note_id, err := notes.CreateNote(db, note, user)
+func CreateNoteHandler(w http.ResponseWriter, r *http.Request, store storage.Store, user *models.User, note *models.Note, apiTimeout int) {
+	if !validateNote(w, note) {
+		return
+	}
+	note_id, err := store.CreateNote(r.Context(), user, note)
 	if err != nil {
-		l.Logger.Error("Error:", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		workspaceError(w, err)
 		return
 	}
 	note_id_string := strconv.Itoa(note_id)
@@ -194,17 +249,7 @@ func CreateNoteHandler(w http.ResponseWriter, r *http.Request, db *pgx.Conn, use
 		response.Status = "success"
 		response.Message = "Note has been created successfully"
 		response.NoteID = note_id_string
-		spellcheck, err := yandex.Spellcheck(note.Content, apiTimeout)
-		if err == nil {
-			if len(spellcheck) == 0 {
-				response.Spelling = "correct"
-			} else {
-				response.Spelling = "suggestions"
-				response.SpellingSuggestions = &spellcheck
-			}
-		} else {
-			response.Spelling = err.Error()
-		}
+		spellcheckNote(&response, note.Content, apiTimeout)
 	} else {
 		response.SetError(err.Error())
 	}
@@ -213,9 +258,9 @@ func CreateNoteHandler(w http.ResponseWriter, r *http.Request, db *pgx.Conn, use
 	json.NewEncoder(w).Encode(response)
 }
 
-func ReadNoteHandler(w http.ResponseWriter, r *http.Request, db *pgx.Conn, user *models.User, note *models.Note) {
-	readnote, err := notes.ReadNote(db, note, user)
-	if err != nil && err.Error() != "No matching notes found" {
+func ReadNoteHandler(w http.ResponseWriter, r *http.Request, store storage.Store, user *models.User, note *models.Note) {
+	readnote, err := store.ReadNote(r.Context(), user, note.ID)
+	if err != nil && !errors.Is(err, storage.ErrNoteNotFound) {
 		l.Logger.Error("Error:", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
@@ -233,9 +278,12 @@ func ReadNoteHandler(w http.ResponseWriter, r *http.Request, db *pgx.Conn, user
 	json.NewEncoder(w).Encode(response)
 }
 
-func UpdateNoteHandler(w http.ResponseWriter, r *http.Request, db *pgx.Conn, user *models.User, note *models.Note, apiTimeout int) {
-	err := notes.UpdateNote(db, note, user)
-	if err != nil && err.Error() != "No matching notes found" {
+func UpdateNoteHandler(w http.ResponseWriter, r *http.Request, store storage.Store, user *models.User, note *models.Note, apiTimeout int) {
+	// This endpoint predates versioning, a version echoed back from a read
+	// must not turn the update into a conditional one.
+	note.Version = 0
+	err := store.UpdateNote(r.Context(), user, note)
+	if err != nil && !errors.Is(err, storage.ErrNoteNotFound) && !errors.Is(err, storage.ErrNoteReadOnly) {
 		l.Logger.Error("Error:", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
@@ -245,17 +293,7 @@ func UpdateNoteHandler(w http.ResponseWriter, r *http.Request, db *pgx.Conn, use
 		response.Status = "success"
 		response.Message = "Note has been updated successfully"
 		response.NoteID = strconv.Itoa(note.ID)
-		spellcheck, err := yandex.Spellcheck(note.Content, apiTimeout)
-		if err == nil {
-			if len(spellcheck) == 0 {
-				response.Spelling = "correct"
-			} else {
-				response.Spelling = "suggestions"
-				response.SpellingSuggestions = &spellcheck
-			}
-		} else {
-			response.Spelling = err.Error()
-		}
+		spellcheckNote(&response, note.Content, apiTimeout)
 	} else {
 		response.SetError(err.Error())
 	}
@@ -263,8 +301,8 @@ func UpdateNoteHandler(w http.ResponseWriter, r *http.Request, db *pgx.Conn, use
 	json.NewEncoder(w).Encode(response)
 }
 
-func DeleteNoteHandler(w http.ResponseWriter, r *http.Request, db *pgx.Conn, user *models.User, note *models.Note) {
-	err := notes.DeleteNote(db, note, user)
+func DeleteNoteHandler(w http.ResponseWriter, r *http.Request, store storage.Store, user *models.User, note *models.Note) {
+	err := store.DeleteNote(r.Context(), user, note.ID, 0)
 	response := responses.DeleteNote{}
 	if err == nil {
 		response.Status = "success"
@@ -277,40 +315,45 @@ func DeleteNoteHandler(w http.ResponseWriter, r *http.Request, db *pgx.Conn, use
 	json.NewEncoder(w).Encode(response)
 }
 
-func HandleMultipleNotesAction(w http.ResponseWriter, r *http.Request, db *pgx.Conn, jwtSecret []byte) {
-	tokenString := r.Header.Get("Authorization")
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return jwtSecret, nil
-	})
+func HandleMultipleNotesAction(w http.ResponseWriter, r *http.Request, store storage.Store) {
+	user, err := contextUser(r)
 	if err != nil {
 		l.Logger.Error("Error:", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
-	user, err := GetUserFromToken(token, db)
+	GetAllNotesHandler(w, r, store, user)
+}
+
+func GetAllNotesHandler(w http.ResponseWriter, r *http.Request, store storage.Store, user *models.User) {
+	opts, err := parseListOptions(r.URL.Query(), 0)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	page, err := store.ListNotes(r.Context(), user, opts)
+	if errors.Is(err, storage.ErrWorkspaceNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		l.Logger.Error("Error:", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
-	GetAllNotesHandler(w, r, db, user)
+	writeNotePage(w, page, opts)
 }
 
-func GetAllNotesHandler(w http.ResponseWriter, r *http.Request, db *pgx.Conn, user *models.User) {
-	notes, err := notes.GetAllNotes(db, user)
-	if err != nil && err.Error() != "No notes found for the user" {
-		l.Logger.Error("Error:", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+func spellcheckNote(response *responses.CreateUpdateNote, content string, apiTimeout int) {
+	spellcheck, err := yandex.Spellcheck(content, apiTimeout)
+	if err != nil {
+		response.Spelling = err.Error()
 		return
 	}
-	response := responses.AllNotes{}
-	response.Status = "success"
-	if len(notes) > 0 {
-		response.Message = "All notes retrieved successfully"
-		response.Notes = notes
+	if len(spellcheck) == 0 {
+		response.Spelling = "correct"
 	} else {
-		response.Message = "No notes found"
+		response.Spelling = "suggestions"
+		response.SpellingSuggestions = &spellcheck
 	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
 }
@@ -0,0 +1,191 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/responses"
+	"noteserver/internal/pkg/storage"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+// parseListOptions reads listing parameters from the query string:
+// limit, cursor, sort (created, updated, title), order (asc, desc),
+// created_after, created_before, updated_after, updated_before, tag with
+// tag_mode (and, or), view (full, summary) and workspace, which lists the
+// notes of a workspace instead of the personal notes.
+// A zero defaultLimit means the listing is not paginated unless the
+// client asks for it.
+func parseListOptions(query url.Values, defaultLimit int) (storage.ListOptions, error) {
+	opts := storage.ListOptions{Limit: defaultLimit, Sort: storage.SortCreated}
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxPageSize {
+			return opts, errors.New("limit must be between 1 and " + strconv.Itoa(maxPageSize))
+		}
+		opts.Limit = limit
+	}
+
+	switch sortBy := query.Get("sort"); sortBy {
+	case "", storage.SortCreated, storage.SortUpdated, storage.SortTitle:
+		if sortBy != "" {
+			opts.Sort = sortBy
+		}
+	default:
+		return opts, errors.New("sort must be one of created, updated, title")
+	}
+
+	switch order := strings.ToLower(query.Get("order")); order {
+	case "":
+		opts.Descending = opts.Sort != storage.SortTitle
+	case "asc", "desc":
+		opts.Descending = order == "desc"
+	default:
+		return opts, errors.New("order must be asc or desc")
+	}
+
+	var err error
+	if opts.CreatedAfter, err = parseTimeParam(query, "created_after"); err != nil {
+		return opts, err
+	}
+	if opts.CreatedBefore, err = parseTimeParam(query, "created_before"); err != nil {
+		return opts, err
+	}
+	if opts.UpdatedAfter, err = parseTimeParam(query, "updated_after"); err != nil {
+		return opts, err
+	}
+	if opts.UpdatedBefore, err = parseTimeParam(query, "updated_before"); err != nil {
+		return opts, err
+	}
+
+	seen := make(map[string]bool)
+	for _, value := range query["tag"] {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.ToLower(strings.TrimSpace(name))
+			if name != "" && !seen[name] {
+				seen[name] = true
+				opts.Tags = append(opts.Tags, name)
+			}
+		}
+	}
+	switch mode := strings.ToLower(query.Get("tag_mode")); mode {
+	case "", "and":
+	case "or":
+		opts.AnyTag = true
+	default:
+		return opts, errors.New("tag_mode must be and or or")
+	}
+
+	if opts.WorkspaceID, err = parseWorkspaceParam(query); err != nil {
+		return opts, err
+	}
+
+	switch view := query.Get("view"); view {
+	case "", "full":
+	case "summary":
+		opts.SummaryOnly = true
+	default:
+		return opts, errors.New("view must be full or summary")
+	}
+
+	if value := query.Get("cursor"); value != "" {
+		if opts.Limit == 0 {
+			opts.Limit = defaultPageSize
+		}
+		opts.Cursor, err = storage.DecodeCursor(value, opts)
+		if err != nil {
+			return opts, err
+		}
+	}
+
+	return opts, nil
+}
+
+func parseWorkspaceParam(query url.Values) (*int, error) {
+	value := query.Get("workspace")
+	if value == "" {
+		return nil, nil
+	}
+	workspaceID, err := strconv.Atoi(value)
+	if err != nil || workspaceID < 1 {
+		return nil, errors.New("workspace must be a workspace id")
+	}
+	return &workspaceID, nil
+}
+
+// parseTimeParam accepts RFC 3339 timestamps and plain dates (YYYY-MM-DD).
+func parseTimeParam(query url.Values, name string) (*time.Time, error) {
+	value := query.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		parsed, err = time.Parse("2006-01-02", value)
+		if err != nil {
+			return nil, errors.New(name + " must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+		}
+	}
+	parsed = parsed.UTC()
+	return &parsed, nil
+}
+
+func writeNotePage(w http.ResponseWriter, page storage.NotePage, opts storage.ListOptions) {
+	var nextCursor string
+	if page.Next != nil {
+		nextCursor = page.Next.Encode()
+	}
+	message := "All notes retrieved successfully"
+	if len(page.Notes) == 0 {
+		message = "No notes found"
+	}
+
+	if opts.SummaryOnly {
+		summaries := make([]models.NoteSummary, 0, len(page.Notes))
+		for _, note := range page.Notes {
+			summaries = append(summaries, models.NoteSummary{
+				ID:        note.ID,
+				Title:     note.Title,
+				Snippet:   snippet(note.Content),
+				Tags:      note.Tags,
+				CreatedAt: note.CreatedAt,
+				UpdatedAt: note.UpdatedAt,
+			})
+		}
+		writeJSON(w, http.StatusOK, responses.NoteSummaries{
+			Status:     "success",
+			Message:    message,
+			Notes:      summaries,
+			NextCursor: nextCursor,
+		})
+		return
+	}
+
+	notes := page.Notes
+	if notes == nil {
+		notes = []models.Note{}
+	}
+	writeJSON(w, http.StatusOK, responses.AllNotes{
+		Status:     "success",
+		Message:    message,
+		Notes:      notes,
+		NextCursor: nextCursor,
+	})
+}
+
+func snippet(content string) string {
+	runes := []rune(content)
+	if len(runes) <= storage.SnippetLength {
+		return content
+	}
+	return strings.TrimSpace(string(runes[:storage.SnippetLength])) + "…"
+}
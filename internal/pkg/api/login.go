@@ -0,0 +1,92 @@
+package api
+
+import (
+	"math"
+	"net"
+	"net/http"
+	l "noteserver/internal/pkg/logger"
+	"noteserver/internal/pkg/loginlimit"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// LoginLimiters track failed logins per username and per client address.
+// A failure counts against both, a success only clears the username.
+type LoginLimiters struct {
+	Users     *loginlimit.Limiter
+	Addresses *loginlimit.Limiter
+}
+
+func (limiters LoginLimiters) keys(r *http.Request, username string) (string, string) {
+	return strings.ToLower(strings.TrimSpace(username)), clientAddr(r)
+}
+
+// attempt reserves an attempt against both keys before the credentials are
+// checked. It returns false and how long to wait while either key is
+// locked out, otherwise true and the lockout a failure would cause. Every
+// reserved attempt has to be settled with fail, refund or succeed.
+func (limiters LoginLimiters) attempt(userKey, addrKey string, now time.Time) (time.Duration, bool) {
+	wait, ok := limiters.Users.Attempt(userKey, now)
+	if !ok {
+		return wait, false
+	}
+	addrWait, ok := limiters.Addresses.Attempt(addrKey, now)
+	if !ok {
+		limiters.Users.Refund(userKey)
+		return addrWait, false
+	}
+	if addrWait > wait {
+		wait = addrWait
+	}
+	return wait, true
+}
+
+func (limiters LoginLimiters) fail(userKey, addrKey string, now time.Time) time.Duration {
+	wait := limiters.Users.Fail(userKey, now)
+	if addrWait := limiters.Addresses.Fail(addrKey, now); addrWait > wait {
+		wait = addrWait
+	}
+	return wait
+}
+
+// refund takes back an attempt that neither failed nor succeeded.
+func (limiters LoginLimiters) refund(userKey, addrKey string) {
+	limiters.Users.Refund(userKey)
+	limiters.Addresses.Refund(addrKey)
+}
+
+// succeed clears the failures of the username after a successful attempt.
+func (limiters LoginLimiters) succeed(userKey, addrKey string) {
+	limiters.Users.Reset(userKey)
+	limiters.Addresses.Refund(addrKey)
+}
+
+func tooManyAttempts(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	http.Error(w, "Too many failed login attempts, try again later", http.StatusTooManyRequests)
+}
+
+// clientAddr is the IP address of the client without the port.
+func clientAddr(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func auditLogger(r *http.Request, username string) *logrus.Entry {
+	return l.Logger.WithFields(logrus.Fields{
+		"audit":    "login",
+		"username": username,
+		"ip":       clientAddr(r),
+	})
+}
+
+// dummyHash is compared against when the user does not exist, so that
+// unknown usernames take as long to reject as wrong passwords.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
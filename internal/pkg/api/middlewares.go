@@ -2,12 +2,19 @@ package api
 
 import (
 	"net/http"
+	"noteserver/internal/pkg/jwtkeys"
 	l "noteserver/internal/pkg/logger"
-
-	"github.com/dgrijalva/jwt-go"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/storage"
+	"strings"
 )
 
-func AuthenticateMiddleware(next http.HandlerFunc, jwtSecret []byte) http.HandlerFunc {
+// AuthenticateMiddleware accepts access tokens and personal access tokens
+// and rejects callers whose token or role lacks scope. The user is loaded
+// for every request together with the token, so that disabling an account
+// or changing its role takes effect immediately. An empty scope admits
+// every authenticated caller.
+func AuthenticateMiddleware(next http.HandlerFunc, jwtKeys *jwtkeys.KeySet, store storage.Store, scope string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		tokenString := r.Header.Get("Authorization")
 		if tokenString == "" {
@@ -15,16 +22,69 @@ func AuthenticateMiddleware(next http.HandlerFunc, jwtSecret []byte) http.Handle
 			return
 		}
 
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			return jwtSecret, nil
-		})
+		var principal *Principal
+		var user *models.User
+		if strings.HasPrefix(tokenString, personalTokenPrefix) {
+			var err error
+			principal, user, err = personalTokenPrincipal(r.Context(), store, tokenString)
+			if err != nil {
+				l.Logger.Error("Error:", err)
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+			if principal == nil {
+				http.Error(w, "Token is not valid", http.StatusUnauthorized)
+				return
+			}
+		} else {
+			var err error
+			principal, err = parseAccessToken(tokenString, jwtKeys)
+			if err != nil {
+				l.Logger.Error("Error:", err)
+				http.Error(w, "Token is not valid", http.StatusUnauthorized)
+				return
+			}
+
+			var revoked bool
+			user, revoked, err = store.GetTokenUser(r.Context(), principal.TokenID, principal.UserID)
+			if err != nil {
+				l.Logger.Error("Error:", err)
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+			if revoked {
+				http.Error(w, "Token has been revoked", http.StatusUnauthorized)
+				return
+			}
+		}
 
-		if err != nil || !token.Valid {
-			l.Logger.Error("Error:", err)
+		if user == nil {
 			http.Error(w, "Token is not valid", http.StatusUnauthorized)
 			return
 		}
+		if user.DisabledAt != nil {
+			http.Error(w, "Account has been disabled", http.StatusForbidden)
+			return
+		}
+		principal.Username, principal.Role = user.Username, user.Role
+
+		if !roleAllows(principal.Role, scope) {
+			http.Error(w, "The "+principal.Role+" role does not allow this", http.StatusForbidden)
+			return
+		}
+		if !principal.HasScope(scope) {
+			http.Error(w, "Token lacks the "+scope+" scope", http.StatusForbidden)
+			return
+		}
 
+		next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+	}
+}
+
+func DeprecatedMiddleware(next http.HandlerFunc, successor string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", "<"+successor+">; rel=\"successor-version\"")
 		next.ServeHTTP(w, r)
 	}
 }
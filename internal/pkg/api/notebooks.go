@@ -0,0 +1,345 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/requests"
+	"noteserver/internal/pkg/responses"
+	"noteserver/internal/pkg/storage"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+)
+
+const maxNotebookNameLength = 100
+
+func ListNotebooksHandler(w http.ResponseWriter, r *http.Request, store storage.Store) {
+	user, ok := requestUser(w, r)
+	if !ok {
+		return
+	}
+	notebooks, err := store.ListNotebooks(r.Context(), user)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, responses.Notebooks{
+		Status:    "success",
+		Message:   "Notebooks retrieved successfully",
+		Notebooks: notebooks,
+	})
+}
+
+func CreateNotebookHandler(w http.ResponseWriter, r *http.Request, store storage.Store) {
+	user, ok := requestUser(w, r)
+	if !ok {
+		return
+	}
+	var body requests.NotebookCreate
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	name, err := normalizeNotebookName(body.Name)
+	if err != nil {
+		validationError(w, map[string]string{"name": err.Error()})
+		return
+	}
+
+	notebook := models.Notebook{Name: name, ParentID: body.ParentID}
+	notebookID, err := store.CreateNotebook(r.Context(), user, &notebook)
+	if err != nil {
+		notebookError(w, err)
+		return
+	}
+	created, descendants, err := findNotebook(r, store, user, notebookID)
+	if err != nil {
+		notebookError(w, err)
+		return
+	}
+	w.Header().Set("Location", "/v1/notebooks/"+strconv.Itoa(notebookID))
+	writeJSON(w, http.StatusCreated, responses.Notebook{
+		Status:      "success",
+		Message:     "Notebook has been created successfully",
+		Notebook:    created,
+		Descendants: descendants,
+	})
+}
+
+func GetNotebookHandler(w http.ResponseWriter, r *http.Request, store storage.Store) {
+	user, ok := requestUser(w, r)
+	if !ok {
+		return
+	}
+	notebookID, ok := notebookIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	notebook, descendants, err := findNotebook(r, store, user, notebookID)
+	if err != nil {
+		notebookError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, responses.Notebook{
+		Status:      "success",
+		Message:     "Notebook has been read successfully",
+		Notebook:    notebook,
+		Descendants: descendants,
+	})
+}
+
+func PatchNotebookHandler(w http.ResponseWriter, r *http.Request, store storage.Store) {
+	user, ok := requestUser(w, r)
+	if !ok {
+		return
+	}
+	notebookID, ok := notebookIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	var body requests.NotebookPatch
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	notebook, _, err := findNotebook(r, store, user, notebookID)
+	if err != nil {
+		notebookError(w, err)
+		return
+	}
+	if body.Name != nil {
+		name, err := normalizeNotebookName(*body.Name)
+		if err != nil {
+			validationError(w, map[string]string{"name": err.Error()})
+			return
+		}
+		notebook.Name = name
+	}
+	if body.ParentID.Set {
+		notebook.ParentID = body.ParentID.Value
+	}
+
+	if err := store.UpdateNotebook(r.Context(), user, notebook); err != nil {
+		notebookError(w, err)
+		return
+	}
+	updated, descendants, err := findNotebook(r, store, user, notebookID)
+	if err != nil {
+		notebookError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, responses.Notebook{
+		Status:      "success",
+		Message:     "Notebook has been updated successfully",
+		Notebook:    updated,
+		Descendants: descendants,
+	})
+}
+
+// DeleteNotebookHandler deletes a notebook, the mode query parameter
+// (restrict, cascade, reparent) decides what happens to its contents.
+func DeleteNotebookHandler(w http.ResponseWriter, r *http.Request, store storage.Store) {
+	user, ok := requestUser(w, r)
+	if !ok {
+		return
+	}
+	notebookID, ok := notebookIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	mode := storage.NotebookDeleteMode(strings.ToLower(r.URL.Query().Get("mode")))
+	switch mode {
+	case "":
+		mode = storage.DeleteRestrict
+	case storage.DeleteRestrict, storage.DeleteCascade, storage.DeleteReparent:
+	default:
+		writeJSON(w, http.StatusBadRequest, responses.NewError("mode must be one of restrict, cascade, reparent"))
+		return
+	}
+
+	if err := store.DeleteNotebook(r.Context(), user, notebookID, mode); err != nil {
+		notebookError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListNotebookNotesHandler lists the notes filed in a notebook, or in the
+// notebook and everything nested in it when recursive=true. It accepts the
+// same parameters as the note listing.
+func ListNotebookNotesHandler(w http.ResponseWriter, r *http.Request, store storage.Store) {
+	user, ok := requestUser(w, r)
+	if !ok {
+		return
+	}
+	notebookID, ok := notebookIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	query := r.URL.Query()
+	opts, err := parseListOptions(query, defaultPageSize)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, responses.NewError(err.Error()))
+		return
+	}
+	recursive, err := strconv.ParseBool(query.Get("recursive"))
+	if err != nil && query.Get("recursive") != "" {
+		writeJSON(w, http.StatusBadRequest, responses.NewError("recursive must be true or false"))
+		return
+	}
+
+	notebooks, err := store.ListNotebooks(r.Context(), user)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	if !containsNotebook(notebooks, notebookID) {
+		notebookError(w, storage.ErrNotebookNotFound)
+		return
+	}
+	opts.NotebookIDs = []int{notebookID}
+	if recursive {
+		opts.NotebookIDs = storage.NotebookSubtree(notebooks, notebookID)
+	}
+
+	page, err := store.ListNotes(r.Context(), user, opts)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	writeNotePage(w, page, opts)
+}
+
+func MoveNoteHandler(w http.ResponseWriter, r *http.Request, store storage.Store) {
+	user, ok := requestUser(w, r)
+	if !ok {
+		return
+	}
+	noteID, ok := noteIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	var body requests.NoteMove
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	if err := store.MoveNote(r.Context(), user, noteID, body.NotebookID); err != nil {
+		notebookError(w, err)
+		return
+	}
+	note, err := store.ReadNote(r.Context(), user, noteID)
+	if err != nil {
+		noteError(w, err)
+		return
+	}
+	setValidators(w, note)
+	writeJSON(w, http.StatusOK, responses.ReadNote{
+		Status:  "success",
+		Message: "Note has been moved successfully",
+		Note:    &note,
+	})
+}
+
+func CopyNoteHandler(w http.ResponseWriter, r *http.Request, store storage.Store) {
+	user, ok := requestUser(w, r)
+	if !ok {
+		return
+	}
+	noteID, ok := noteIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	var body requests.NoteMove
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	copyID, err := store.CopyNote(r.Context(), user, noteID, body.NotebookID)
+	if err != nil {
+		notebookError(w, err)
+		return
+	}
+	note, err := store.ReadNote(r.Context(), user, copyID)
+	if err != nil {
+		noteError(w, err)
+		return
+	}
+	w.Header().Set("Location", "/v1/notes/"+strconv.Itoa(copyID))
+	setValidators(w, note)
+	writeJSON(w, http.StatusCreated, responses.ReadNote{
+		Status:  "success",
+		Message: "Note has been copied successfully",
+		Note:    &note,
+	})
+}
+
+// findNotebook returns a notebook of the user together with all notebooks
+// nested in it.
+func findNotebook(r *http.Request, store storage.Store, user *models.User, notebookID int) (*models.Notebook, []models.Notebook, error) {
+	notebooks, err := store.ListNotebooks(r.Context(), user)
+	if err != nil {
+		return nil, nil, err
+	}
+	inSubtree := make(map[int]bool)
+	for _, id := range storage.NotebookSubtree(notebooks, notebookID) {
+		inSubtree[id] = true
+	}
+
+	var notebook *models.Notebook
+	descendants := []models.Notebook{}
+	for i := range notebooks {
+		switch {
+		case notebooks[i].ID == notebookID:
+			notebook = &notebooks[i]
+		case inSubtree[notebooks[i].ID]:
+			descendants = append(descendants, notebooks[i])
+		}
+	}
+	if notebook == nil {
+		return nil, nil, storage.ErrNotebookNotFound
+	}
+	return notebook, descendants, nil
+}
+
+func containsNotebook(notebooks []models.Notebook, notebookID int) bool {
+	for _, notebook := range notebooks {
+		if notebook.ID == notebookID {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeNotebookName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	switch {
+	case name == "":
+		return "", errors.New("Notebook names must not be empty")
+	case utf8.RuneCountInString(name) > maxNotebookNameLength:
+		return "", errors.New("Notebook names must be at most " + strconv.Itoa(maxNotebookNameLength) + " characters long")
+	case strings.Contains(name, storage.NotebookPathSeparator):
+		return "", errors.New("Notebook names must not contain slashes")
+	}
+	return name, nil
+}
+
+func notebookIDFromPath(w http.ResponseWriter, r *http.Request) (int, bool) {
+	notebookID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, responses.NewError("Invalid notebook id"))
+		return 0, false
+	}
+	return notebookID, true
+}
+
+func notebookError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, storage.ErrNotebookNotFound):
+		writeJSON(w, http.StatusNotFound, responses.NewError(err.Error()))
+	case errors.Is(err, storage.ErrNotebookExists), errors.Is(err, storage.ErrNotebookNotEmpty),
+		errors.Is(err, storage.ErrNotebookCycle):
+		writeJSON(w, http.StatusConflict, responses.NewError(err.Error()))
+	default:
+		noteError(w, err)
+	}
+}
@@ -0,0 +1,318 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	l "noteserver/internal/pkg/logger"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/requests"
+	"noteserver/internal/pkg/responses"
+	"noteserver/internal/pkg/storage"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	maxTitleLength = 100
+	// maxNoteBodySize bounds the request bodies that create and update
+	// notes.
+	maxNoteBodySize = 1 << 20
+	// maxPatchAttempts bounds how often a PATCH without If-Match is applied
+	// again to a note that changed while it was patched.
+	maxPatchAttempts = 3
+)
+
+func ListNotesHandler(w http.ResponseWriter, r *http.Request, store storage.Store) {
+	user, ok := requestUser(w, r)
+	if !ok {
+		return
+	}
+	opts, err := parseListOptions(r.URL.Query(), defaultPageSize)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, responses.NewError(err.Error()))
+		return
+	}
+	page, err := store.ListNotes(r.Context(), user, opts)
+	if err != nil {
+		workspaceError(w, err)
+		return
+	}
+	writeNotePage(w, page, opts)
+}
+
+func CreateNoteResourceHandler(w http.ResponseWriter, r *http.Request, store storage.Store, apiTimeout int) {
+	user, ok := requestUser(w, r)
+	if !ok {
+		return
+	}
+	var body requests.NotePut
+	r.Body = http.MaxBytesReader(w, r.Body, maxNoteBodySize)
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	note := models.Note{Title: body.Title, Content: body.Content, NotebookID: body.NotebookID, WorkspaceID: body.WorkspaceID}
+	if !validateNote(w, &note) {
+		return
+	}
+
+	noteID, err := store.CreateNote(r.Context(), user, &note)
+	if err != nil {
+		workspaceError(w, err)
+		return
+	}
+
+	response := responses.CreateUpdateNote{
+		Status:  "success",
+		Message: "Note has been created successfully",
+		NoteID:  strconv.Itoa(noteID),
+	}
+	spellcheckNote(&response, note.Content, apiTimeout)
+	note.ID = noteID
+	setValidators(w, note)
+	w.Header().Set("Location", "/v1/notes/"+response.NoteID)
+	writeJSON(w, http.StatusCreated, response)
+}
+
+func GetNoteHandler(w http.ResponseWriter, r *http.Request, store storage.Store) {
+	user, ok := requestUser(w, r)
+	if !ok {
+		return
+	}
+	noteID, ok := noteIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	note, err := store.ReadNote(r.Context(), user, noteID)
+	if err != nil {
+		noteError(w, err)
+		return
+	}
+	if notModified(w, r, note) {
+		return
+	}
+	setValidators(w, note)
+	writeJSON(w, http.StatusOK, responses.ReadNote{
+		Status:  "success",
+		Message: "Note has been read successfully",
+		Note:    &note,
+	})
+}
+
+func PatchNoteHandler(w http.ResponseWriter, r *http.Request, store storage.Store, apiTimeout int) {
+	user, ok := requestUser(w, r)
+	if !ok {
+		return
+	}
+	noteID, ok := noteIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	var body requests.NotePatch
+	r.Body = http.MaxBytesReader(w, r.Body, maxNoteBodySize)
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	// The patch is applied to the version that was read, so that updates
+	// in between are not overwritten but make the patch start over.
+	for attempt := 1; ; attempt++ {
+		note, err := store.ReadNote(r.Context(), user, noteID)
+		if err != nil {
+			noteError(w, err)
+			return
+		}
+		if _, ok := ifMatchVersion(w, r, note); !ok {
+			return
+		}
+		if body.Title != nil {
+			note.Title = *body.Title
+		}
+		if body.Content != nil {
+			note.Content = *body.Content
+		}
+		if !validateNote(w, &note) {
+			return
+		}
+		err = store.UpdateNote(r.Context(), user, &note)
+		if errors.Is(err, storage.ErrVersionMismatch) && r.Header.Get("If-Match") == "" {
+			if attempt < maxPatchAttempts {
+				continue
+			}
+			writeJSON(w, http.StatusConflict, responses.NewError(err.Error()))
+			return
+		}
+		if err != nil {
+			noteError(w, err)
+			return
+		}
+		writeSavedNote(w, note, apiTimeout)
+		return
+	}
+}
+
+func PutNoteHandler(w http.ResponseWriter, r *http.Request, store storage.Store, apiTimeout int) {
+	user, ok := requestUser(w, r)
+	if !ok {
+		return
+	}
+	noteID, ok := noteIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	var body requests.NotePut
+	r.Body = http.MaxBytesReader(w, r.Body, maxNoteBodySize)
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	if !checkFiling(w, r, store, user, noteID, body) {
+		return
+	}
+	version, ok := ifMatchNote(w, r, store, user, noteID)
+	if !ok {
+		return
+	}
+	note := models.Note{ID: noteID, Title: body.Title, Content: body.Content, Version: version}
+	saveNote(w, r, store, user, &note, apiTimeout)
+}
+
+func DeleteNoteResourceHandler(w http.ResponseWriter, r *http.Request, store storage.Store) {
+	user, ok := requestUser(w, r)
+	if !ok {
+		return
+	}
+	noteID, ok := noteIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	version, ok := ifMatchNote(w, r, store, user, noteID)
+	if !ok {
+		return
+	}
+	err := store.DeleteNote(r.Context(), user, noteID, version)
+	if err != nil {
+		noteError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// checkFiling rejects PUT bodies that would file a note in another notebook
+// or workspace, which PUT does not do. The current ones are accepted, so
+// that a note that was read can be sent back.
+func checkFiling(w http.ResponseWriter, r *http.Request, store storage.Store, user *models.User, noteID int, body requests.NotePut) bool {
+	if body.NotebookID == nil && body.WorkspaceID == nil {
+		return true
+	}
+	current, err := store.ReadNote(r.Context(), user, noteID)
+	if err != nil {
+		noteError(w, err)
+		return false
+	}
+	fieldErrors := make(map[string]string)
+	if body.NotebookID != nil && (current.NotebookID == nil || *current.NotebookID != *body.NotebookID) {
+		fieldErrors["notebook_id"] = "Notes are moved to another notebook with POST /v1/notes/{id}/move"
+	}
+	if body.WorkspaceID != nil && (current.WorkspaceID == nil || *current.WorkspaceID != *body.WorkspaceID) {
+		fieldErrors["workspace_id"] = "The workspace of a note cannot be changed"
+	}
+	if len(fieldErrors) == 0 {
+		return true
+	}
+	validationError(w, fieldErrors)
+	return false
+}
+
+func saveNote(w http.ResponseWriter, r *http.Request, store storage.Store, user *models.User, note *models.Note, apiTimeout int) {
+	if !validateNote(w, note) {
+		return
+	}
+	err := store.UpdateNote(r.Context(), user, note)
+	if err != nil {
+		noteError(w, err)
+		return
+	}
+	writeSavedNote(w, *note, apiTimeout)
+}
+
+func writeSavedNote(w http.ResponseWriter, note models.Note, apiTimeout int) {
+	response := responses.CreateUpdateNote{
+		Status:  "success",
+		Message: "Note has been updated successfully",
+		NoteID:  strconv.Itoa(note.ID),
+	}
+	spellcheckNote(&response, note.Content, apiTimeout)
+	setValidators(w, note)
+	writeJSON(w, http.StatusOK, response)
+}
+
+func validateNote(w http.ResponseWriter, note *models.Note) bool {
+	fieldErrors := make(map[string]string)
+	if note.Title == "" {
+		fieldErrors["title"] = "Title is required"
+	} else if utf8.RuneCountInString(note.Title) > maxTitleLength {
+		fieldErrors["title"] = "Title must be at most " + strconv.Itoa(maxTitleLength) + " characters long"
+	}
+	if len(fieldErrors) == 0 {
+		return true
+	}
+	validationError(w, fieldErrors)
+	return false
+}
+
+func validationError(w http.ResponseWriter, fieldErrors map[string]string) {
+	response := responses.NewError("Validation failed")
+	response.Errors = fieldErrors
+	writeJSON(w, http.StatusUnprocessableEntity, response)
+}
+
+func noteIDFromPath(w http.ResponseWriter, r *http.Request) (int, bool) {
+	noteID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, responses.NewError("Invalid note id"))
+		return 0, false
+	}
+	return noteID, true
+}
+
+func noteError(w http.ResponseWriter, err error) {
+	if errors.Is(err, storage.ErrNoteNotFound) || errors.Is(err, storage.ErrRevisionNotFound) {
+		writeJSON(w, http.StatusNotFound, responses.NewError(err.Error()))
+		return
+	}
+	if errors.Is(err, storage.ErrVersionMismatch) {
+		writeJSON(w, http.StatusPreconditionFailed, responses.NewError(err.Error()))
+		return
+	}
+	if errors.Is(err, storage.ErrNoteReadOnly) || errors.Is(err, storage.ErrNotNoteOwner) {
+		writeJSON(w, http.StatusForbidden, responses.NewError(err.Error()))
+		return
+	}
+	internalError(w, err)
+}
+
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	err := json.NewDecoder(r.Body).Decode(v)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeJSON(w, http.StatusRequestEntityTooLarge, responses.NewError("Request body is too large"))
+		return false
+	}
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, responses.NewError("Bad request"))
+		return false
+	}
+	return true
+}
+
+func internalError(w http.ResponseWriter, err error) {
+	l.Logger.Error("Error:", err)
+	writeJSON(w, http.StatusInternalServerError, responses.NewError("Internal server error"))
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,335 @@
+package api
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"noteserver/internal/pkg/jwtkeys"
+	l "noteserver/internal/pkg/logger"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/oidc"
+	"noteserver/internal/pkg/requests"
+	"noteserver/internal/pkg/responses"
+	"noteserver/internal/pkg/storage"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// oidcLoginTTL is how long the user may take to log in at the identity
+// provider.
+const oidcLoginTTL = 10 * time.Minute
+
+// oidcStateCookie binds a login to the browser that started it. It holds
+// the hash of the state, so that the callback refuses states of logins
+// that were started elsewhere and slipped to the user. Links are started
+// by API clients instead and are bound to the user in the stored login.
+const oidcStateCookie = "noteserver_oidc_state"
+
+// OIDCConfig holds the identity providers by name. Limiters throttle the
+// password confirmation of account links.
+type OIDCConfig struct {
+	Providers map[string]*oidc.Provider
+	Limiters  LoginLimiters
+}
+
+// OIDCLoginHandler sends the user to the login page of an identity
+// provider, which sends them back to OIDCCallbackHandler.
+func OIDCLoginHandler(w http.ResponseWriter, r *http.Request, store storage.Store, config OIDCConfig) {
+	provider, ok := oidcProvider(w, r, config)
+	if !ok {
+		return
+	}
+	authURL, ok := startOIDCLogin(w, r, store, provider, 0)
+	if !ok {
+		return
+	}
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// OIDCCallbackHandler completes a login at an identity provider. The
+// identity logs in the user it is linked to, or a new user if the provider
+// allows auto-provisioning, and is answered like HandleLogin. Logins
+// started by LinkIdentityHandler link the identity instead.
+func OIDCCallbackHandler(w http.ResponseWriter, r *http.Request, store storage.Store, config OIDCConfig, jwtKeys *jwtkeys.KeySet, lifetimes TokenLifetimes) {
+	provider, ok := oidcProvider(w, r, config)
+	if !ok {
+		return
+	}
+	query := r.URL.Query()
+	stateHash := hashToken(query.Get("state"))
+	login, err := store.ConsumeOIDCLogin(r.Context(), stateHash, time.Now())
+	if errors.Is(err, storage.ErrOIDCLoginNotFound) || (err == nil && login.Provider != provider.Name) {
+		writeJSON(w, http.StatusBadRequest, responses.NewError(storage.ErrOIDCLoginNotFound.Error()))
+		return
+	}
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	if login.LinkUserID == 0 {
+		cookie, err := r.Cookie(oidcStateCookie)
+		if err != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(stateHash)) != 1 {
+			writeJSON(w, http.StatusBadRequest, responses.NewError(storage.ErrOIDCLoginNotFound.Error()))
+			return
+		}
+		setStateCookie(w, provider, "", -1)
+	}
+	if query.Get("error") != "" {
+		l.Logger.Info("Login at identity provider ", provider.Name, " failed: ", query.Get("error"), " ", query.Get("error_description"))
+		writeJSON(w, http.StatusUnauthorized, responses.NewError("Login at the identity provider failed"))
+		return
+	}
+
+	identity, err := provider.Authenticate(r.Context(), query.Get("code"), login.Verifier, login.Nonce)
+	if err != nil {
+		l.Logger.Warn("Authentication with identity provider ", provider.Name, " failed: ", err)
+		writeJSON(w, http.StatusUnauthorized, responses.NewError("Authentication with the identity provider failed"))
+		return
+	}
+
+	if login.LinkUserID != 0 {
+		linkIdentity(w, r, store, provider, login.LinkUserID, identity)
+		return
+	}
+
+	user, err := store.GetUserByIdentity(r.Context(), provider.Name, identity.Subject)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	if user == nil {
+		user, ok = provisionUser(w, r, store, provider, identity)
+		if !ok {
+			return
+		}
+	}
+	if user.DisabledAt != nil {
+		auditLogger(r, user.Username).WithField("provider", provider.Name).Warn("Login of disabled account refused")
+		writeJSON(w, http.StatusForbidden, responses.NewError("Account has been disabled"))
+		return
+	}
+
+	enabled, err := store.GetTOTP(r.Context(), user.ID)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	if enabled != nil && enabled.EnabledAt != nil {
+		mfaChallenge(w, user, jwtKeys)
+		return
+	}
+	auditLogger(r, user.Username).WithField("provider", provider.Name).Info("Logged in with identity provider")
+	response, err := issueTokens(r.Context(), store, user, jwtKeys, lifetimes)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// provisionUser creates a user for an identity that is not linked yet.
+// Identities are never linked to existing users by username or email
+// address, which anyone may claim at some identity providers; the user has
+// to link them while logged in.
+func provisionUser(w http.ResponseWriter, r *http.Request, store storage.Store, provider *oidc.Provider, identity *oidc.Identity) (*models.User, bool) {
+	if !provider.AutoProvision {
+		writeJSON(w, http.StatusForbidden, responses.NewError("No account is linked to this identity"))
+		return nil, false
+	}
+	if err := checkUsername(identity.Username); err != nil {
+		writeJSON(w, http.StatusForbidden, responses.NewError("The identity provider sent no usable username: "+err.Error()))
+		return nil, false
+	}
+	email, err := normalizeEmail(identity.Email)
+	if err != nil {
+		email = ""
+	}
+
+	// Provisioned users have no password, they can set one with a password
+	// reset.
+	user := models.User{Username: identity.Username, Email: email}
+	link := models.Identity{Provider: provider.Name, Subject: identity.Subject}
+	err = store.RegisterWithIdentity(r.Context(), &user, &link)
+	switch {
+	case errors.Is(err, storage.ErrUserExists), errors.Is(err, storage.ErrEmailExists):
+		writeJSON(w, http.StatusConflict, responses.NewError(err.Error()+", log in and link the identity to your account instead"))
+		return nil, false
+	case err != nil:
+		identityError(w, err)
+		return nil, false
+	}
+	auditLogger(r, user.Username).WithField("provider", provider.Name).Info("User provisioned from identity provider")
+	return &user, true
+}
+
+func linkIdentity(w http.ResponseWriter, r *http.Request, store storage.Store, provider *oidc.Provider, userID int, identity *oidc.Identity) {
+	user, err := store.GetUserByID(r.Context(), userID)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	if user == nil {
+		writeJSON(w, http.StatusBadRequest, responses.NewError(storage.ErrOIDCLoginNotFound.Error()))
+		return
+	}
+	link := models.Identity{UserID: user.ID, Provider: provider.Name, Subject: identity.Subject}
+	if err := store.LinkIdentity(r.Context(), &link); err != nil {
+		identityError(w, err)
+		return
+	}
+	auditLogger(r, user.Username).WithField("provider", provider.Name).Info("Identity linked")
+	writeJSON(w, http.StatusOK, responses.Identity{
+		Status:   "success",
+		Message:  "Identity has been linked successfully",
+		Identity: &link,
+	})
+}
+
+func ListIdentitiesHandler(w http.ResponseWriter, r *http.Request, store storage.Store) {
+	user, ok := requestUser(w, r)
+	if !ok {
+		return
+	}
+	identities, err := store.ListIdentities(r.Context(), user.ID)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, responses.Identities{
+		Status:     "success",
+		Message:    "Identities retrieved successfully",
+		Identities: identities,
+	})
+}
+
+// LinkIdentityHandler starts a login at an identity provider that links the
+// identity to the user. The user has to confirm it with their password,
+// as the identity can log in as the user afterwards.
+func LinkIdentityHandler(w http.ResponseWriter, r *http.Request, store storage.Store, config OIDCConfig) {
+	provider, ok := oidcProvider(w, r, config)
+	if !ok {
+		return
+	}
+	user, ok := accountUser(w, r, store)
+	if !ok {
+		return
+	}
+	var body requests.IdentityLink
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	if user.Password != "" && !checkCurrentPassword(w, r, config.Limiters, user, body.Password) {
+		return
+	}
+	authURL, ok := startOIDCLogin(w, r, store, provider, user.ID)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, responses.AuthorizationURL{
+		Status:           "success",
+		Message:          "Log in at the identity provider to link the identity",
+		AuthorizationURL: authURL,
+	})
+}
+
+// UnlinkIdentityHandler removes the identity of a provider from the user,
+// unless it is the only way for a user without password to log in.
+func UnlinkIdentityHandler(w http.ResponseWriter, r *http.Request, store storage.Store) {
+	user, ok := accountUser(w, r, store)
+	if !ok {
+		return
+	}
+	provider := mux.Vars(r)["provider"]
+	if user.Password == "" {
+		identities, err := store.ListIdentities(r.Context(), user.ID)
+		if err != nil {
+			internalError(w, err)
+			return
+		}
+		if len(identities) == 1 && identities[0].Provider == provider {
+			writeJSON(w, http.StatusConflict, responses.NewError("Set a password before unlinking the last identity"))
+			return
+		}
+	}
+	if err := store.UnlinkIdentity(r.Context(), user.ID, provider); err != nil {
+		identityError(w, err)
+		return
+	}
+	auditLogger(r, user.Username).WithField("provider", provider).Info("Identity unlinked")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// startOIDCLogin records a new login and returns the address of the login
+// page of the provider.
+func startOIDCLogin(w http.ResponseWriter, r *http.Request, store storage.Store, provider *oidc.Provider, linkUserID int) (string, bool) {
+	var values [3]string
+	for i := range values {
+		value, err := oidc.RandomString()
+		if err != nil {
+			internalError(w, err)
+			return "", false
+		}
+		values[i] = value
+	}
+	state, nonce, verifier := values[0], values[1], values[2]
+
+	authURL, err := provider.AuthCodeURL(r.Context(), state, nonce, verifier)
+	if err != nil {
+		l.Logger.Error("Error:", err)
+		writeJSON(w, http.StatusBadGateway, responses.NewError("Identity provider is unavailable"))
+		return "", false
+	}
+	login := models.OIDCLogin{
+		StateHash:  hashToken(state),
+		Provider:   provider.Name,
+		Nonce:      nonce,
+		Verifier:   verifier,
+		LinkUserID: linkUserID,
+		ExpiresAt:  time.Now().Add(oidcLoginTTL),
+	}
+	if err := store.CreateOIDCLogin(r.Context(), &login); err != nil {
+		internalError(w, err)
+		return "", false
+	}
+	if linkUserID == 0 {
+		setStateCookie(w, provider, login.StateHash, int(oidcLoginTTL.Seconds()))
+	}
+	return authURL, true
+}
+
+// setStateCookie sets the cookie that binds a login to the browser, a
+// negative maxAge removes it. The cookie is sent along with the redirect
+// from the provider, but not with requests that other sites embed.
+func setStateCookie(w http.ResponseWriter, provider *oidc.Provider, stateHash string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    stateHash,
+		Path:     "/v1/oidc/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(provider.RedirectURL, "https://"),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func oidcProvider(w http.ResponseWriter, r *http.Request, config OIDCConfig) (*oidc.Provider, bool) {
+	provider, ok := config.Providers[mux.Vars(r)["provider"]]
+	if !ok {
+		writeJSON(w, http.StatusNotFound, responses.NewError("Unknown identity provider"))
+		return nil, false
+	}
+	return provider, true
+}
+
+func identityError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, storage.ErrIdentityExists):
+		writeJSON(w, http.StatusConflict, responses.NewError(err.Error()))
+	case errors.Is(err, storage.ErrIdentityNotFound):
+		writeJSON(w, http.StatusNotFound, responses.NewError(err.Error()))
+	default:
+		internalError(w, err)
+	}
+}
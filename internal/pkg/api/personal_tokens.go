@@ -0,0 +1,181 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/requests"
+	"noteserver/internal/pkg/responses"
+	"noteserver/internal/pkg/storage"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+)
+
+// personalTokenPrefix tells personal access tokens apart from JWTs and makes
+// leaked tokens easy to find with secret scanners.
+const personalTokenPrefix = "nsp_"
+
+const maxPersonalTokenNameLength = 100
+
+func ListPersonalTokensHandler(w http.ResponseWriter, r *http.Request, store storage.Store) {
+	user, ok := requestUser(w, r)
+	if !ok {
+		return
+	}
+	tokens, err := store.ListPersonalTokens(r.Context(), user.ID)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, responses.PersonalTokens{
+		Status:  "success",
+		Message: "Access tokens retrieved successfully",
+		Tokens:  tokens,
+	})
+}
+
+// CreatePersonalTokenHandler creates a personal access token. A caller can
+// only grant scopes it holds itself.
+func CreatePersonalTokenHandler(w http.ResponseWriter, r *http.Request, store storage.Store) {
+	principal, ok := PrincipalFromContext(r.Context())
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, responses.NewError("Unauthorized"))
+		return
+	}
+	var body requests.PersonalTokenCreate
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	fieldErrors := make(map[string]string)
+	name := strings.TrimSpace(body.Name)
+	if name == "" {
+		fieldErrors["name"] = "Name is required"
+	} else if utf8.RuneCountInString(name) > maxPersonalTokenNameLength {
+		fieldErrors["name"] = "Name must be at most " + strconv.Itoa(maxPersonalTokenNameLength) + " characters long"
+	}
+	tokenScopes, err := normalizeScopes(body.Scopes)
+	if err != nil {
+		fieldErrors["scopes"] = err.Error()
+	}
+	if body.ExpiresAt != nil && !body.ExpiresAt.After(time.Now()) {
+		fieldErrors["expires_at"] = "Expiry must be in the future"
+	}
+	if len(fieldErrors) > 0 {
+		validationError(w, fieldErrors)
+		return
+	}
+	for _, scope := range tokenScopes {
+		if !principal.HasScope(scope) {
+			writeJSON(w, http.StatusForbidden, responses.NewError("Cannot grant the "+scope+" scope"))
+			return
+		}
+	}
+
+	secret, err := randomToken(32)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	secret = personalTokenPrefix + secret
+	token := models.PersonalToken{
+		UserID:    principal.UserID,
+		Name:      name,
+		TokenHash: hashToken(secret),
+		Scopes:    tokenScopes,
+		ExpiresAt: body.ExpiresAt,
+	}
+	if err := store.CreatePersonalToken(r.Context(), &token); err != nil {
+		personalTokenError(w, err)
+		return
+	}
+	w.Header().Set("Location", "/v1/tokens/"+strconv.Itoa(token.ID))
+	writeJSON(w, http.StatusCreated, responses.PersonalToken{
+		Status:        "success",
+		Message:       "Access token has been created successfully",
+		Token:         secret,
+		PersonalToken: &token,
+	})
+}
+
+func DeletePersonalTokenHandler(w http.ResponseWriter, r *http.Request, store storage.Store) {
+	user, ok := requestUser(w, r)
+	if !ok {
+		return
+	}
+	tokenID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, responses.NewError("Invalid token id"))
+		return
+	}
+	if err := store.DeletePersonalToken(r.Context(), user.ID, tokenID); err != nil {
+		personalTokenError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// personalTokenPrincipal looks up a personal access token and its user. It
+// returns nil for unknown and expired tokens.
+func personalTokenPrincipal(ctx context.Context, store storage.Store, tokenString string) (*Principal, *models.User, error) {
+	token, user, err := store.UsePersonalToken(ctx, hashToken(tokenString), time.Now())
+	if err != nil || token == nil {
+		return nil, nil, err
+	}
+	principal := &Principal{
+		UserID:          token.UserID,
+		Scopes:          token.Scopes,
+		PersonalTokenID: token.ID,
+	}
+	if token.ExpiresAt != nil {
+		principal.ExpiresAt = *token.ExpiresAt
+	}
+	return principal, user, nil
+}
+
+// normalizeScopes checks that scopes are known and removes duplicates.
+func normalizeScopes(requested []string) ([]string, error) {
+	if len(requested) == 0 {
+		return nil, errors.New("At least one scope is required")
+	}
+	normalized := make([]string, 0, len(requested))
+	for _, scope := range scopes {
+		for _, name := range requested {
+			if strings.TrimSpace(name) == scope {
+				normalized = append(normalized, scope)
+				break
+			}
+		}
+	}
+	for _, name := range requested {
+		if !containsScope(normalized, strings.TrimSpace(name)) {
+			return nil, errors.New("Unknown scope " + name + ", must be one of " + strings.Join(scopes, ", "))
+		}
+	}
+	return normalized, nil
+}
+
+func containsScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func personalTokenError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, storage.ErrPersonalTokenNotFound):
+		writeJSON(w, http.StatusNotFound, responses.NewError(err.Error()))
+	case errors.Is(err, storage.ErrPersonalTokenExists):
+		writeJSON(w, http.StatusConflict, responses.NewError(err.Error()))
+	default:
+		internalError(w, err)
+	}
+}
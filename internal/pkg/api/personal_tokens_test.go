@@ -0,0 +1,86 @@
+package api
+
+import (
+	"noteserver/internal/pkg/models"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeScopes(t *testing.T) {
+	tests := []struct {
+		requested []string
+		want      []string
+	}{
+		{[]string{"notes:read"}, []string{ScopeNotesRead}},
+		{[]string{" notes:write ", "notes:read"}, []string{ScopeNotesRead, ScopeNotesWrite}},
+		{[]string{"notes:read", "notes:read", "account:admin"}, []string{ScopeNotesRead, ScopeAccountAdmin}},
+		{[]string{"admin", "notes:write"}, []string{ScopeNotesWrite, ScopeAdmin}},
+		{nil, nil},
+		{[]string{}, nil},
+		{[]string{"notes:read", "notes:delete"}, nil},
+		{[]string{"Notes:Read"}, nil},
+		{[]string{""}, nil},
+	}
+	for _, test := range tests {
+		got, err := normalizeScopes(test.requested)
+		if test.want == nil {
+			if err == nil {
+				t.Errorf("normalizeScopes(%q) = %q, want an error", test.requested, got)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("normalizeScopes(%q) = %q, %v, want %q", test.requested, got, err, test.want)
+		}
+	}
+}
+
+func TestHasScope(t *testing.T) {
+	tests := []struct {
+		role   string
+		scopes []string
+		scope  string
+		want   bool
+	}{
+		{models.RoleUser, nil, ScopeNotesWrite, true},
+		{models.RoleUser, nil, ScopeAccountAdmin, true},
+		{models.RoleUser, nil, ScopeAdmin, false},
+		{models.RoleUser, nil, "", true},
+		{models.RoleUser, []string{ScopeNotesRead}, ScopeNotesRead, true},
+		{models.RoleUser, []string{ScopeNotesRead}, ScopeNotesWrite, false},
+		{models.RoleUser, []string{ScopeNotesRead}, ScopeAccountAdmin, false},
+		{models.RoleUser, []string{ScopeNotesRead}, "", true},
+		{models.RoleUser, []string{ScopeAdmin}, ScopeAdmin, false},
+		{models.RoleReadOnly, nil, ScopeNotesRead, true},
+		{models.RoleReadOnly, nil, ScopeNotesWrite, false},
+		{models.RoleReadOnly, nil, ScopeAccountAdmin, true},
+		{models.RoleReadOnly, []string{ScopeNotesWrite}, ScopeNotesWrite, false},
+		{models.RoleAdmin, nil, ScopeAdmin, true},
+		{models.RoleAdmin, []string{ScopeNotesRead}, ScopeAdmin, false},
+		{models.RoleAdmin, []string{ScopeNotesRead, ScopeAdmin}, ScopeAdmin, true},
+	}
+	for _, test := range tests {
+		principal := &Principal{Role: test.role, Scopes: test.scopes}
+		if got := principal.HasScope(test.scope); got != test.want {
+			t.Errorf("%s with scopes %q: HasScope(%q) = %v, want %v", test.role, test.scopes, test.scope, got, test.want)
+		}
+	}
+}
+
+// TestNormalizedScopesAreGranted checks that every scope a personal token
+// can be created with is granted to the token of a user whose role allows
+// it, and no other scope is.
+func TestNormalizedScopesAreGranted(t *testing.T) {
+	for _, scope := range scopes {
+		normalized, err := normalizeScopes([]string{scope})
+		if err != nil {
+			t.Fatal(err)
+		}
+		principal := &Principal{Role: models.RoleAdmin, Scopes: normalized}
+		for _, other := range scopes {
+			if got := principal.HasScope(other); got != (other == scope) {
+				t.Errorf("token with %q: HasScope(%q) = %v", scope, other, got)
+			}
+		}
+	}
+}
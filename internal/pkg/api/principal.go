@@ -0,0 +1,150 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"noteserver/internal/pkg/jwtkeys"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/responses"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const (
+	ScopeNotesRead    = "notes:read"
+	ScopeNotesWrite   = "notes:write"
+	ScopeAccountAdmin = "account:admin"
+	// ScopeAdmin allows to manage other users, only admins hold it.
+	ScopeAdmin = "admin"
+)
+
+var scopes = []string{ScopeNotesRead, ScopeNotesWrite, ScopeAccountAdmin, ScopeAdmin}
+
+// Principal is the authenticated caller of a request, taken from the claims
+// of a verified access token or from a personal access token.
+type Principal struct {
+	UserID   int
+	Username string
+	// Role is the current role of the user, loaded for every request.
+	Role string
+	// Scopes limits what the token may be used for. Tokens issued by login
+	// have no scope claim and are not limited.
+	Scopes []string
+	// TokenID and ExpiresAt identify the access token for revocation.
+	TokenID   string
+	ExpiresAt time.Time
+	// PersonalTokenID is set when the caller used a personal access token.
+	PersonalTokenID int
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+	return principal, ok
+}
+
+// User returns the principal as a user for the storage layer. Only the id,
+// the username and the role are set.
+func (p *Principal) User() *models.User {
+	return &models.User{ID: p.UserID, Username: p.Username, Role: p.Role}
+}
+
+// HasScope reports whether the principal may act within scope, which both
+// its token and its role have to allow. An empty scope is granted to every
+// principal.
+func (p *Principal) HasScope(scope string) bool {
+	if scope == "" {
+		return true
+	}
+	return roleAllows(p.Role, scope) && (p.Scopes == nil || containsScope(p.Scopes, scope))
+}
+
+// roleAllows reports whether users with the role may act within scope
+// at all. Read-only users cannot change notes, only admins administer.
+func roleAllows(role string, scope string) bool {
+	switch role {
+	case models.RoleAdmin:
+		return true
+	case models.RoleReadOnly:
+		return scope != ScopeNotesWrite && scope != ScopeAdmin
+	}
+	return scope != ScopeAdmin
+}
+
+// parseAccessToken verifies an access token and reads its claims. Only the
+// algorithms of the configured keys are accepted, whatever algorithm the
+// token header names.
+func parseAccessToken(tokenString string, jwtKeys *jwtkeys.KeySet) (*Principal, error) {
+	return parseToken(tokenString, jwtKeys, "")
+}
+
+// parseMFAToken verifies a token issued by HandleLogin for the second step
+// of a login with two-factor authentication.
+func parseMFAToken(tokenString string, jwtKeys *jwtkeys.KeySet) (*Principal, error) {
+	return parseToken(tokenString, jwtKeys, tokenUseMFA)
+}
+
+// parseToken verifies a token whose token_use claim is tokenUse, access
+// tokens have none.
+func parseToken(tokenString string, jwtKeys *jwtkeys.KeySet, tokenUse string) (*Principal, error) {
+	parser := jwt.Parser{ValidMethods: jwtKeys.Algorithms()}
+	token, err := parser.Parse(tokenString, jwtKeys.Keyfunc)
+	if err != nil {
+		return nil, err
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, errors.New("Invalid token claims")
+	}
+	if use, _ := claims["token_use"].(string); use != tokenUse {
+		return nil, errors.New("Token cannot be used here")
+	}
+
+	var principal Principal
+	subject, _ := claims["sub"].(string)
+	principal.UserID, err = strconv.Atoi(subject)
+	if err != nil {
+		return nil, errors.New("User ID not found in token claims")
+	}
+	principal.Username, _ = claims["username"].(string)
+	// Tokens without an id predate revocation support and cannot be
+	// revoked, so they are not accepted.
+	principal.TokenID, _ = claims["jti"].(string)
+	if principal.TokenID == "" {
+		return nil, errors.New("Token ID not found in token claims")
+	}
+	if exp, ok := claims["exp"].(float64); ok {
+		principal.ExpiresAt = time.Unix(int64(exp), 0)
+	}
+	if scope, ok := claims["scope"].(string); ok {
+		principal.Scopes = strings.Fields(scope)
+	}
+	return &principal, nil
+}
+
+// contextUser returns the user authenticated by AuthenticateMiddleware.
+func contextUser(r *http.Request) (*models.User, error) {
+	principal, ok := PrincipalFromContext(r.Context())
+	if !ok {
+		return nil, errors.New("No authenticated user in request context")
+	}
+	return principal.User(), nil
+}
+
+func requestUser(w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	user, err := contextUser(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, responses.NewError("Unauthorized"))
+		return nil, false
+	}
+	return user, true
+}
@@ -0,0 +1,191 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"html/template"
+	"mime"
+	"net/http"
+	l "noteserver/internal/pkg/logger"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/requests"
+	"noteserver/internal/pkg/responses"
+	"noteserver/internal/pkg/storage"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// maxPublicBodySize bounds the body of password submissions to share links.
+const maxPublicBodySize = 4096
+
+var publicPage = template.Must(template.New("public").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>{{if .Note}}{{.Note.Title}}{{else}}{{.Message}}{{end}}</title>
+<style>
+body { max-width: 48rem; margin: 2rem auto; padding: 0 1rem; font-family: sans-serif; line-height: 1.5; }
+pre { white-space: pre-wrap; overflow-wrap: break-word; font: inherit; }
+small { color: #666; }
+</style>
+</head>
+<body>
+{{- if .Note}}
+<h1>{{.Note.Title}}</h1>
+<pre>{{.Note.Content}}</pre>
+<p><small>Last updated {{.Note.UpdatedAt.Format "2006-01-02 15:04 MST"}}</small></p>
+{{- else if .PasswordForm}}
+<h1>{{.Message}}</h1>
+<form method="post">
+<input type="password" name="password" aria-label="Password" autofocus required>
+<button type="submit">Open note</button>
+</form>
+{{- else}}
+<h1>{{.Message}}</h1>
+{{- end}}
+</body>
+</html>
+`))
+
+type publicPageData struct {
+	Note         *models.PublicNote
+	Message      string
+	PasswordForm bool
+}
+
+// PublicNoteHandler shows the note of a share link to anyone who has the
+// link, without authentication. Links with a password answer 401 until
+// the password is posted, as a form field or as JSON. Browsers get an HTML
+// page, other clients JSON, depending on the Accept header.
+func PublicNoteHandler(w http.ResponseWriter, r *http.Request, store storage.Store, links ShareLinkConfig) {
+	asHTML := prefersHTML(r)
+	w.Header().Set("Vary", "Accept")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Header().Set("X-Robots-Tag", "noindex")
+
+	now := time.Now()
+	link, err := store.GetShareLink(r.Context(), hashToken(mux.Vars(r)["token"]), now)
+	if err != nil {
+		l.Logger.Error("Error:", err)
+		writePublic(w, asHTML, http.StatusInternalServerError, publicPageData{Message: "Internal server error"})
+		return
+	}
+	if link == nil {
+		writePublic(w, asHTML, http.StatusNotFound, publicPageData{Message: "This link does not exist or has expired"})
+		return
+	}
+
+	if link.PasswordHash != "" {
+		password := submittedPassword(w, r)
+		if password == "" {
+			writePublic(w, asHTML, http.StatusUnauthorized, publicPageData{Message: "This note is protected by a password", PasswordForm: true})
+			return
+		}
+		linkKey, addrKey := "link:"+strconv.Itoa(link.ID), clientAddr(r)
+		if wait, ok := links.Limiters.attempt(linkKey, addrKey, now); !ok {
+			tooManyAttempts(w, wait)
+			return
+		}
+		if bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte(password)) != nil {
+			if wait := links.Limiters.fail(linkKey, addrKey, now); wait > 0 {
+				l.Logger.WithField("link_id", link.ID).WithField("ip", addrKey).Warn("Share link locked after failed passwords")
+			}
+			writePublic(w, asHTML, http.StatusUnauthorized, publicPageData{Message: "Wrong password, try again", PasswordForm: true})
+			return
+		}
+		links.Limiters.succeed(linkKey, addrKey)
+	}
+
+	note, err := store.ViewShareLink(r.Context(), link.ID, now)
+	if errors.Is(err, storage.ErrNoteNotFound) {
+		writePublic(w, asHTML, http.StatusNotFound, publicPageData{Message: "This link does not exist or has expired"})
+		return
+	}
+	if err != nil {
+		l.Logger.Error("Error:", err)
+		writePublic(w, asHTML, http.StatusInternalServerError, publicPageData{Message: "Internal server error"})
+		return
+	}
+	writePublic(w, asHTML, http.StatusOK, publicPageData{Note: &models.PublicNote{
+		Title:     note.Title,
+		Content:   note.Content,
+		UpdatedAt: note.UpdatedAt,
+		ExpiresAt: link.ExpiresAt,
+	}})
+}
+
+func writePublic(w http.ResponseWriter, asHTML bool, status int, data publicPageData) {
+	if !asHTML {
+		if data.Note == nil {
+			writeJSON(w, status, responses.NewError(data.Message))
+			return
+		}
+		writeJSON(w, status, responses.PublicNote{
+			Status:  "success",
+			Message: "Note has been read successfully",
+			Note:    data.Note,
+		})
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; form-action 'self'; frame-ancestors 'none'")
+	w.WriteHeader(status)
+	if err := publicPage.Execute(w, data); err != nil {
+		l.Logger.Error("Error:", err)
+	}
+}
+
+// submittedPassword reads the password of a POST request from a JSON body
+// or a form.
+func submittedPassword(w http.ResponseWriter, r *http.Request) string {
+	if r.Method != http.MethodPost {
+		return ""
+	}
+	body := http.MaxBytesReader(w, r.Body, maxPublicBodySize)
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/json" {
+		var access requests.ShareLinkAccess
+		if json.NewDecoder(body).Decode(&access) != nil {
+			return ""
+		}
+		return access.Password
+	}
+	r.Body = body
+	return r.PostFormValue("password")
+}
+
+// prefersHTML reports whether the Accept header ranks text/html above
+// JSON. Clients that accept anything get JSON.
+func prefersHTML(r *http.Request) bool {
+	var htmlQ, jsonQ float64
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		params := strings.Split(part, ";")
+		q := 1.0
+		for _, param := range params[1:] {
+			name, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if ok && strings.EqualFold(name, "q") {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		switch strings.ToLower(strings.TrimSpace(params[0])) {
+		case "text/html", "application/xhtml+xml":
+			if q > htmlQ {
+				htmlQ = q
+			}
+		case "application/json", "application/*", "*/*":
+			if q > jsonQ {
+				jsonQ = q
+			}
+		}
+	}
+	return htmlQ > jsonQ
+}
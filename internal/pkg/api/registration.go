@@ -0,0 +1,117 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/passwords"
+	"noteserver/internal/pkg/responses"
+	"noteserver/internal/pkg/storage"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	RegistrationOpen   = "open"
+	RegistrationClosed = "closed"
+	RegistrationInvite = "invite"
+)
+
+const (
+	minUsernameLength = 3
+	maxUsernameLength = 50
+)
+
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// RegistrationConfig decides who may register, Mode is one of
+// RegistrationOpen, RegistrationClosed and RegistrationInvite.
+type RegistrationConfig struct {
+	Mode      string
+	Policy    *passwords.Policy
+	InviteTTL time.Duration
+}
+
+func ListInvitesHandler(w http.ResponseWriter, r *http.Request, store storage.Store) {
+	user, ok := requestUser(w, r)
+	if !ok {
+		return
+	}
+	invites, err := store.ListInvites(r.Context(), user.ID)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, responses.Invites{
+		Status:  "success",
+		Message: "Invites retrieved successfully",
+		Invites: invites,
+	})
+}
+
+// CreateInviteHandler creates a single-use invite code that expires after
+// the configured time.
+func CreateInviteHandler(w http.ResponseWriter, r *http.Request, store storage.Store, registration RegistrationConfig) {
+	user, ok := requestUser(w, r)
+	if !ok {
+		return
+	}
+	code, err := randomToken(18)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	invite := models.Invite{
+		CreatedBy: user.ID,
+		CodeHash:  hashToken(code),
+		ExpiresAt: time.Now().Add(registration.InviteTTL),
+	}
+	if err := store.CreateInvite(r.Context(), &invite); err != nil {
+		internalError(w, err)
+		return
+	}
+	w.Header().Set("Location", "/v1/invites/"+strconv.Itoa(invite.ID))
+	writeJSON(w, http.StatusCreated, responses.Invite{
+		Status:  "success",
+		Message: "Invite has been created successfully",
+		Code:    code,
+		Invite:  &invite,
+	})
+}
+
+func DeleteInviteHandler(w http.ResponseWriter, r *http.Request, store storage.Store) {
+	user, ok := requestUser(w, r)
+	if !ok {
+		return
+	}
+	inviteID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, responses.NewError("Invalid invite id"))
+		return
+	}
+	err = store.DeleteInvite(r.Context(), user.ID, inviteID)
+	if errors.Is(err, storage.ErrInviteNotFound) {
+		writeJSON(w, http.StatusNotFound, responses.NewError(err.Error()))
+		return
+	}
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func checkUsername(username string) error {
+	switch {
+	case username == "":
+		return errors.New("Username is required")
+	case len(username) < minUsernameLength || len(username) > maxUsernameLength:
+		return errors.New("Username must be " + strconv.Itoa(minUsernameLength) + " to " + strconv.Itoa(maxUsernameLength) + " characters long")
+	case !usernamePattern.MatchString(username):
+		return errors.New("Username may only contain letters, digits, dots, dashes and underscores and must start with a letter or digit")
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"noteserver/internal/pkg/diff"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/responses"
+	"noteserver/internal/pkg/storage"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	currentRevision = "current"
+	diffContext     = 3
+)
+
+var errInvalidRevision = errors.New("Invalid revision id")
+
+func ListRevisionsHandler(w http.ResponseWriter, r *http.Request, store storage.Store) {
+	user, ok := requestUser(w, r)
+	if !ok {
+		return
+	}
+	noteID, ok := noteIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	revisions, err := store.ListRevisions(r.Context(), user, noteID)
+	if err != nil {
+		noteError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, responses.Revisions{
+		Status:    "success",
+		Message:   "Revisions retrieved successfully",
+		Revisions: revisions,
+	})
+}
+
+func GetRevisionHandler(w http.ResponseWriter, r *http.Request, store storage.Store) {
+	user, ok := requestUser(w, r)
+	if !ok {
+		return
+	}
+	noteID, ok := noteIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	revisionID, ok := revisionIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	revision, err := store.ReadRevision(r.Context(), user, noteID, revisionID)
+	if err != nil {
+		noteError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, responses.Revision{
+		Status:   "success",
+		Message:  "Revision has been read successfully",
+		Revision: &revision,
+	})
+}
+
+// DiffRevisionsHandler compares two versions of a note given by the from and
+// to query parameters. Each is a revision id or "current" for the note as
+// it is now; to defaults to "current".
+func DiffRevisionsHandler(w http.ResponseWriter, r *http.Request, store storage.Store) {
+	user, ok := requestUser(w, r)
+	if !ok {
+		return
+	}
+	noteID, ok := noteIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	from := r.URL.Query().Get("from")
+	to := r.URL.Query().Get("to")
+	if to == "" {
+		to = currentRevision
+	}
+	if from == "" {
+		writeJSON(w, http.StatusBadRequest, responses.NewError("from is required"))
+		return
+	}
+
+	fromNote, err := noteVersion(r, store, user, noteID, from)
+	if err != nil {
+		versionError(w, err)
+		return
+	}
+	toNote, err := noteVersion(r, store, user, noteID, to)
+	if err != nil {
+		versionError(w, err)
+		return
+	}
+
+	if diff.CountLines(fromNote.Content) > diff.MaxLines || diff.CountLines(toNote.Content) > diff.MaxLines {
+		writeJSON(w, http.StatusUnprocessableEntity,
+			responses.NewError("Notes with more than "+strconv.Itoa(diff.MaxLines)+" lines cannot be compared"))
+		return
+	}
+	lines := diff.Lines(fromNote.Content, toNote.Content)
+	writeJSON(w, http.StatusOK, responses.RevisionDiff{
+		Status:       "success",
+		Message:      "Diff has been computed successfully",
+		From:         from,
+		To:           to,
+		TitleChanged: fromNote.Title != toNote.Title,
+		FromTitle:    fromNote.Title,
+		ToTitle:      toNote.Title,
+		Lines:        lines,
+		Unified:      diff.Unified(lines, diffContext),
+	})
+}
+
+// RestoreRevisionHandler brings back the title and content of a revision.
+// The restore is a regular update, so the replaced state becomes a new
+// revision and can be restored in turn.
+func RestoreRevisionHandler(w http.ResponseWriter, r *http.Request, store storage.Store) {
+	user, ok := requestUser(w, r)
+	if !ok {
+		return
+	}
+	noteID, ok := noteIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	revisionID, ok := revisionIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	revision, err := store.ReadRevision(r.Context(), user, noteID, revisionID)
+	if err != nil {
+		noteError(w, err)
+		return
+	}
+
+	note := models.Note{ID: noteID, Title: revision.Title, Content: revision.Content}
+	if err := store.UpdateNote(r.Context(), user, &note); err != nil {
+		noteError(w, err)
+		return
+	}
+	restored, err := store.ReadNote(r.Context(), user, noteID)
+	if err != nil {
+		noteError(w, err)
+		return
+	}
+	setValidators(w, restored)
+	writeJSON(w, http.StatusOK, responses.ReadNote{
+		Status:  "success",
+		Message: "Revision has been restored successfully",
+		Note:    &restored,
+	})
+}
+
+func noteVersion(r *http.Request, store storage.Store, user *models.User, noteID int, version string) (models.Note, error) {
+	if version == currentRevision {
+		return store.ReadNote(r.Context(), user, noteID)
+	}
+	revisionID, err := strconv.Atoi(version)
+	if err != nil {
+		return models.Note{}, errInvalidRevision
+	}
+	revision, err := store.ReadRevision(r.Context(), user, noteID, revisionID)
+	if err != nil {
+		return models.Note{}, err
+	}
+	return models.Note{ID: noteID, Title: revision.Title, Content: revision.Content}, nil
+}
+
+func versionError(w http.ResponseWriter, err error) {
+	if err == errInvalidRevision {
+		writeJSON(w, http.StatusBadRequest, responses.NewError(err.Error()))
+		return
+	}
+	noteError(w, err)
+}
+
+func revisionIDFromPath(w http.ResponseWriter, r *http.Request) (int, bool) {
+	revisionID, err := strconv.Atoi(mux.Vars(r)["revision"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, responses.NewError(errInvalidRevision.Error()))
+		return 0, false
+	}
+	return revisionID, true
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"noteserver/internal/pkg/responses"
+	"noteserver/internal/pkg/storage"
+	"strconv"
+)
+
+func SearchNotesHandler(w http.ResponseWriter, r *http.Request, store storage.Store) {
+	user, ok := requestUser(w, r)
+	if !ok {
+		return
+	}
+
+	params := r.URL.Query()
+	query, err := storage.ParseSearchQuery(params.Get("q"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, responses.NewError(err.Error()))
+		return
+	}
+	limit, offset, err := parsePage(params)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, responses.NewError(err.Error()))
+		return
+	}
+	query.WorkspaceID, err = parseWorkspaceParam(params)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, responses.NewError(err.Error()))
+		return
+	}
+
+	results, err := store.SearchNotes(r.Context(), user, query, limit, offset)
+	if err != nil {
+		workspaceError(w, err)
+		return
+	}
+	response := responses.SearchNotes{
+		Status:  "success",
+		Message: "Search completed successfully",
+		Results: results,
+	}
+	if len(results) == 0 {
+		response.Message = "No matching notes found"
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// parsePage reads the limit and offset parameters of offset paginated
+// listings.
+func parsePage(params url.Values) (int, int, error) {
+	limit := defaultPageSize
+	if value := params.Get("limit"); value != "" {
+		var err error
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxPageSize {
+			return 0, 0, errors.New("limit must be between 1 and " + strconv.Itoa(maxPageSize))
+		}
+	}
+	offset := 0
+	if value := params.Get("offset"); value != "" {
+		var err error
+		offset, err = strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			return 0, 0, errors.New("offset must be a non-negative number")
+		}
+	}
+	return limit, offset, nil
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/passwords"
+	"noteserver/internal/pkg/requests"
+	"noteserver/internal/pkg/responses"
+	"noteserver/internal/pkg/storage"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ShareLinkConfig holds the policy for the passwords of share links and the
+// limiters that throttle guessing them. Failures count against the link
+// like against a username.
+type ShareLinkConfig struct {
+	Policy   *passwords.Policy
+	Limiters LoginLimiters
+}
+
+func ListShareLinksHandler(w http.ResponseWriter, r *http.Request, store storage.Store) {
+	user, ok := requestUser(w, r)
+	if !ok {
+		return
+	}
+	noteID, ok := noteIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	links, err := store.ListShareLinks(r.Context(), user, noteID)
+	if err != nil {
+		shareLinkError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, responses.ShareLinks{
+		Status:  "success",
+		Message: "Share links retrieved successfully",
+		Links:   links,
+	})
+}
+
+// CreateShareLinkHandler creates a public link to a note. Anyone who knows
+// the link can read the note, and the password if it has one, until it
+// expires or is deleted.
+func CreateShareLinkHandler(w http.ResponseWriter, r *http.Request, store storage.Store, links ShareLinkConfig) {
+	user, ok := requestUser(w, r)
+	if !ok {
+		return
+	}
+	noteID, ok := noteIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	var body requests.ShareLinkCreate
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	fieldErrors := make(map[string]string)
+	if body.Password != "" {
+		if err := links.Policy.Check(body.Password, ""); err != nil {
+			fieldErrors["password"] = err.Error()
+		}
+	}
+	if body.ExpiresAt != nil && !body.ExpiresAt.After(time.Now()) {
+		fieldErrors["expires_at"] = "Expiry must be in the future"
+	}
+	if len(fieldErrors) > 0 {
+		validationError(w, fieldErrors)
+		return
+	}
+
+	secret, err := randomToken(32)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	link := models.ShareLink{
+		NoteID:    noteID,
+		TokenHash: hashToken(secret),
+		ExpiresAt: body.ExpiresAt,
+	}
+	if body.Password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(body.Password), bcrypt.DefaultCost)
+		if err != nil {
+			internalError(w, err)
+			return
+		}
+		link.PasswordHash = string(hash)
+	}
+	if err := store.CreateShareLink(r.Context(), user, &link); err != nil {
+		shareLinkError(w, err)
+		return
+	}
+	w.Header().Set("Location", "/v1/notes/"+strconv.Itoa(noteID)+"/links/"+strconv.Itoa(link.ID))
+	writeJSON(w, http.StatusCreated, responses.ShareLink{
+		Status:  "success",
+		Message: "Share link has been created successfully",
+		Token:   secret,
+		URL:     "/s/" + secret,
+		Link:    &link,
+	})
+}
+
+func DeleteShareLinkHandler(w http.ResponseWriter, r *http.Request, store storage.Store) {
+	user, ok := requestUser(w, r)
+	if !ok {
+		return
+	}
+	noteID, ok := noteIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	linkID, err := strconv.Atoi(mux.Vars(r)["link_id"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, responses.NewError("Invalid link id"))
+		return
+	}
+	if err := store.DeleteShareLink(r.Context(), user, noteID, linkID); err != nil {
+		shareLinkError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func shareLinkError(w http.ResponseWriter, err error) {
+	if errors.Is(err, storage.ErrShareLinkNotFound) {
+		writeJSON(w, http.StatusNotFound, responses.NewError(err.Error()))
+		return
+	}
+	noteError(w, err)
+}
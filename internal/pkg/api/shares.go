@@ -0,0 +1,147 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/requests"
+	"noteserver/internal/pkg/responses"
+	"noteserver/internal/pkg/storage"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// ListNoteSharesHandler lists the users a note is shared with. Only the
+// owner of the note can see them.
+func ListNoteSharesHandler(w http.ResponseWriter, r *http.Request, store storage.Store) {
+	user, ok := requestUser(w, r)
+	if !ok {
+		return
+	}
+	noteID, ok := noteIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	shares, err := store.ListNoteShares(r.Context(), user, noteID)
+	if err != nil {
+		shareError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, responses.NoteShares{
+		Status:  "success",
+		Message: "Shares retrieved successfully",
+		Shares:  shares,
+	})
+}
+
+// ShareNoteHandler shares a note with another user, or changes the
+// permission of an existing share.
+func ShareNoteHandler(w http.ResponseWriter, r *http.Request, store storage.Store) {
+	user, ok := requestUser(w, r)
+	if !ok {
+		return
+	}
+	noteID, ok := noteIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	var body requests.NoteShare
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	fieldErrors := make(map[string]string)
+	if body.Username == "" {
+		fieldErrors["username"] = "Username is required"
+	}
+	if body.Permission != models.PermissionRead && body.Permission != models.PermissionEdit {
+		fieldErrors["permission"] = "Permission must be read or edit"
+	}
+	if len(fieldErrors) > 0 {
+		validationError(w, fieldErrors)
+		return
+	}
+
+	recipient, err := store.GetUserByUsername(r.Context(), body.Username)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	if recipient == nil {
+		writeJSON(w, http.StatusNotFound, responses.NewError(storage.ErrUserNotFound.Error()))
+		return
+	}
+	if recipient.ID == user.ID {
+		validationError(w, map[string]string{"username": "Notes cannot be shared with their owner"})
+		return
+	}
+
+	share := models.NoteShare{
+		NoteID:     noteID,
+		UserID:     recipient.ID,
+		Username:   recipient.Username,
+		Permission: body.Permission,
+	}
+	if err := store.ShareNote(r.Context(), user, &share); err != nil {
+		shareError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, responses.NoteShare{
+		Status:  "success",
+		Message: "Note has been shared successfully",
+		Share:   &share,
+	})
+}
+
+func RevokeShareHandler(w http.ResponseWriter, r *http.Request, store storage.Store) {
+	user, ok := requestUser(w, r)
+	if !ok {
+		return
+	}
+	noteID, ok := noteIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	userID, err := strconv.Atoi(mux.Vars(r)["user_id"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, responses.NewError("Invalid user id"))
+		return
+	}
+	if err := store.RevokeShare(r.Context(), user, noteID, userID); err != nil {
+		shareError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListSharedNotesHandler lists the notes other users have shared with the
+// user, most recently updated first.
+func ListSharedNotesHandler(w http.ResponseWriter, r *http.Request, store storage.Store) {
+	user, ok := requestUser(w, r)
+	if !ok {
+		return
+	}
+	limit, offset, err := parsePage(r.URL.Query())
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, responses.NewError(err.Error()))
+		return
+	}
+	notes, err := store.ListSharedNotes(r.Context(), user, limit, offset)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, responses.SharedNotes{
+		Status:  "success",
+		Message: "Shared notes retrieved successfully",
+		Notes:   notes,
+	})
+}
+
+func shareError(w http.ResponseWriter, err error) {
+	if errors.Is(err, storage.ErrShareNotFound) {
+		writeJSON(w, http.StatusNotFound, responses.NewError(err.Error()))
+		return
+	}
+	noteError(w, err)
+}
@@ -0,0 +1,150 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"noteserver/internal/pkg/requests"
+	"noteserver/internal/pkg/responses"
+	"noteserver/internal/pkg/storage"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+)
+
+const maxTagLength = 50
+
+func ListTagsHandler(w http.ResponseWriter, r *http.Request, store storage.Store) {
+	user, ok := requestUser(w, r)
+	if !ok {
+		return
+	}
+	tags, err := store.ListTags(r.Context(), user)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, responses.Tags{
+		Status:  "success",
+		Message: "Tags retrieved successfully",
+		Tags:    tags,
+	})
+}
+
+func AddNoteTagsHandler(w http.ResponseWriter, r *http.Request, store storage.Store) {
+	user, ok := requestUser(w, r)
+	if !ok {
+		return
+	}
+	noteID, ok := noteIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	var body requests.NoteTags
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	if len(body.Tags) == 0 {
+		validationError(w, map[string]string{"tags": "At least one tag is required"})
+		return
+	}
+	names := make([]string, 0, len(body.Tags))
+	for _, name := range body.Tags {
+		name, err := normalizeTag(name)
+		if err != nil {
+			validationError(w, map[string]string{"tags": err.Error()})
+			return
+		}
+		names = append(names, name)
+	}
+
+	if err := store.AddNoteTags(r.Context(), user, noteID, names); err != nil {
+		noteError(w, err)
+		return
+	}
+	note, err := store.ReadNote(r.Context(), user, noteID)
+	if err != nil {
+		noteError(w, err)
+		return
+	}
+	setValidators(w, note)
+	writeJSON(w, http.StatusOK, responses.ReadNote{
+		Status:  "success",
+		Message: "Tags have been added successfully",
+		Note:    &note,
+	})
+}
+
+func RemoveNoteTagHandler(w http.ResponseWriter, r *http.Request, store storage.Store) {
+	user, ok := requestUser(w, r)
+	if !ok {
+		return
+	}
+	noteID, ok := noteIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	if err := store.RemoveNoteTag(r.Context(), user, noteID, mux.Vars(r)["tag"]); err != nil {
+		tagError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func RenameTagHandler(w http.ResponseWriter, r *http.Request, store storage.Store) {
+	user, ok := requestUser(w, r)
+	if !ok {
+		return
+	}
+	var body requests.TagRename
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	newName, err := normalizeTag(body.Name)
+	if err != nil {
+		validationError(w, map[string]string{"name": err.Error()})
+		return
+	}
+	if err := store.RenameTag(r.Context(), user, mux.Vars(r)["tag"], newName); err != nil {
+		tagError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, responses.NewStatus("Tag has been renamed successfully"))
+}
+
+func DeleteTagHandler(w http.ResponseWriter, r *http.Request, store storage.Store) {
+	user, ok := requestUser(w, r)
+	if !ok {
+		return
+	}
+	if err := store.DeleteTag(r.Context(), user, mux.Vars(r)["tag"]); err != nil {
+		tagError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func normalizeTag(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	switch {
+	case name == "":
+		return "", errors.New("Tag names must not be empty")
+	case utf8.RuneCountInString(name) > maxTagLength:
+		return "", errors.New("Tag names must be at most " + strconv.Itoa(maxTagLength) + " characters long")
+	case strings.ContainsAny(name, ",/"):
+		return "", errors.New("Tag names must not contain commas or slashes")
+	}
+	return name, nil
+}
+
+func tagError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, storage.ErrTagNotFound):
+		writeJSON(w, http.StatusNotFound, responses.NewError(err.Error()))
+	case errors.Is(err, storage.ErrTagExists):
+		writeJSON(w, http.StatusConflict, responses.NewError(err.Error()))
+	default:
+		noteError(w, err)
+	}
+}
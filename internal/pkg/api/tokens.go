@@ -0,0 +1,191 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"noteserver/internal/pkg/jwtkeys"
+	l "noteserver/internal/pkg/logger"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/requests"
+	"noteserver/internal/pkg/responses"
+	"noteserver/internal/pkg/storage"
+	"time"
+)
+
+// TokenLifetimes configures how long issued tokens stay valid.
+type TokenLifetimes struct {
+	Access  time.Duration
+	Refresh time.Duration
+}
+
+// RefreshTokenHandler exchanges a refresh token for a new access token and a
+// new refresh token. Each refresh token can be used once; using it again
+// means it has leaked, so the whole family of tokens descending from the
+// same login is revoked.
+func RefreshTokenHandler(w http.ResponseWriter, r *http.Request, store storage.Store, jwtKeys *jwtkeys.KeySet, lifetimes TokenLifetimes) {
+	var body requests.RefreshToken
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	refreshToken, next, err := newRefreshToken(lifetimes)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+
+	current, err := store.RotateRefreshToken(r.Context(), hashToken(body.RefreshToken), &next)
+	switch {
+	case errors.Is(err, storage.ErrTokenReused):
+		l.Logger.Warn("Refresh token reuse detected, revoking token family of user ", current.UserID)
+		if err := store.RevokeTokenFamily(r.Context(), current.FamilyID); err != nil {
+			internalError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusUnauthorized, responses.NewError(err.Error()))
+		return
+	case errors.Is(err, storage.ErrTokenNotFound):
+		writeJSON(w, http.StatusUnauthorized, responses.NewError(err.Error()))
+		return
+	case err != nil:
+		internalError(w, err)
+		return
+	}
+
+	user, err := store.GetUserByID(r.Context(), current.UserID)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	if user == nil {
+		writeJSON(w, http.StatusUnauthorized, responses.NewError(storage.ErrTokenNotFound.Error()))
+		return
+	}
+	if user.DisabledAt != nil {
+		writeJSON(w, http.StatusForbidden, responses.NewError("Account has been disabled"))
+		return
+	}
+	accessToken, err := GenerateJWTToken(user, jwtKeys, next.AccessJTI, lifetimes.Access)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, responses.Token{
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(lifetimes.Access.Seconds()),
+	})
+}
+
+// LogoutHandler revokes the access token of the request, personal access
+// tokens are deleted. The optional body
+// also revokes a refresh token with its family, or all tokens of the user.
+func LogoutHandler(w http.ResponseWriter, r *http.Request, store storage.Store) {
+	principal, ok := PrincipalFromContext(r.Context())
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, responses.NewError("Unauthorized"))
+		return
+	}
+	user := principal.User()
+	var body requests.Logout
+	if r.ContentLength != 0 && !decodeBody(w, r, &body) {
+		return
+	}
+
+	var err error
+	if principal.PersonalTokenID != 0 {
+		err = store.DeletePersonalToken(r.Context(), user.ID, principal.PersonalTokenID)
+	} else {
+		err = store.RevokeToken(r.Context(), principal.TokenID, principal.ExpiresAt)
+	}
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+
+	switch {
+	case body.All:
+		err = store.RevokeUserTokens(r.Context(), user.ID)
+	case body.RefreshToken != "":
+		var refreshToken *models.RefreshToken
+		refreshToken, err = store.GetRefreshToken(r.Context(), hashToken(body.RefreshToken))
+		if err == nil && refreshToken != nil && refreshToken.UserID == user.ID {
+			err = store.RevokeTokenFamily(r.Context(), refreshToken.FamilyID)
+		}
+	}
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// issueTokens starts a new session for the user with a new family of
+// refresh tokens.
+func issueTokens(ctx context.Context, store storage.Store, user *models.User, jwtKeys *jwtkeys.KeySet, lifetimes TokenLifetimes) (responses.Token, error) {
+	refreshToken, record, err := newRefreshToken(lifetimes)
+	if err != nil {
+		return responses.Token{}, err
+	}
+	record.UserID = user.ID
+	record.FamilyID, err = randomToken(16)
+	if err != nil {
+		return responses.Token{}, err
+	}
+	if err := store.CreateRefreshToken(ctx, &record); err != nil {
+		return responses.Token{}, err
+	}
+	accessToken, err := GenerateJWTToken(user, jwtKeys, record.AccessJTI, lifetimes.Access)
+	if err != nil {
+		return responses.Token{}, err
+	}
+	return responses.Token{
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(lifetimes.Access.Seconds()),
+	}, nil
+}
+
+// newRefreshToken returns a new refresh token and the record to store for
+// it, with the id of the access token issued alongside.
+func newRefreshToken(lifetimes TokenLifetimes) (string, models.RefreshToken, error) {
+	refreshToken, err := randomToken(32)
+	if err != nil {
+		return "", models.RefreshToken{}, err
+	}
+	jti, err := randomToken(16)
+	if err != nil {
+		return "", models.RefreshToken{}, err
+	}
+	return refreshToken, models.RefreshToken{
+		TokenHash: hashToken(refreshToken),
+		AccessJTI: jti,
+		ExpiresAt: time.Now().Add(lifetimes.Refresh),
+	}, nil
+}
+
+func randomToken(size int) (string, error) {
+	data := make([]byte, size)
+	if _, err := rand.Read(data); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// hashToken hashes a refresh token for storage. Refresh tokens are random,
+// so unlike passwords they need neither a salt nor a slow hash.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// JWKSHandler publishes the public keys that verify access tokens so that
+// other services can check tokens without sharing a secret.
+func JWKSHandler(w http.ResponseWriter, r *http.Request, jwtKeys *jwtkeys.KeySet) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	writeJSON(w, http.StatusOK, jwtKeys.JWKS())
+}
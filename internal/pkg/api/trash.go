@@ -0,0 +1,84 @@
+package api
+
+import (
+	"net/http"
+	"noteserver/internal/pkg/responses"
+	"noteserver/internal/pkg/storage"
+	"strconv"
+)
+
+func ListTrashHandler(w http.ResponseWriter, r *http.Request, store storage.Store) {
+	user, ok := requestUser(w, r)
+	if !ok {
+		return
+	}
+	notes, err := store.ListTrash(r.Context(), user)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	response := responses.AllNotes{
+		Status:  "success",
+		Message: "Trash retrieved successfully",
+		Notes:   notes,
+	}
+	if len(notes) == 0 {
+		response.Message = "Trash is empty"
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+func RestoreNoteHandler(w http.ResponseWriter, r *http.Request, store storage.Store) {
+	user, ok := requestUser(w, r)
+	if !ok {
+		return
+	}
+	noteID, ok := noteIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	if err := store.RestoreNote(r.Context(), user, noteID); err != nil {
+		noteError(w, err)
+		return
+	}
+	note, err := store.ReadNote(r.Context(), user, noteID)
+	if err != nil {
+		noteError(w, err)
+		return
+	}
+	setValidators(w, note)
+	writeJSON(w, http.StatusOK, responses.ReadNote{
+		Status:  "success",
+		Message: "Note has been restored successfully",
+		Note:    &note,
+	})
+}
+
+func PurgeNoteHandler(w http.ResponseWriter, r *http.Request, store storage.Store) {
+	user, ok := requestUser(w, r)
+	if !ok {
+		return
+	}
+	noteID, ok := noteIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	if err := store.PurgeNote(r.Context(), user, noteID); err != nil {
+		noteError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func EmptyTrashHandler(w http.ResponseWriter, r *http.Request, store storage.Store) {
+	user, ok := requestUser(w, r)
+	if !ok {
+		return
+	}
+	purged, err := store.EmptyTrash(r.Context(), user)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, responses.NewStatus(strconv.FormatInt(purged, 10) + " notes have been deleted permanently"))
+}
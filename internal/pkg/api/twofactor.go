@@ -0,0 +1,370 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"net/http"
+	"noteserver/internal/pkg/jwtkeys"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/requests"
+	"noteserver/internal/pkg/responses"
+	"noteserver/internal/pkg/storage"
+	"noteserver/internal/pkg/totp"
+	"strings"
+	"time"
+)
+
+const (
+	// mfaTokenTTL is how long the second step of a login may take.
+	mfaTokenTTL       = 5 * time.Minute
+	recoveryCodeCount = 10
+)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TwoFactorConfig configures TOTP enrollment. Issuer names the service in
+// authenticator apps; Limiters also throttle guessing of second factors.
+type TwoFactorConfig struct {
+	Issuer   string
+	Limiters LoginLimiters
+}
+
+// LoginTwoFactorHandler completes a login started by HandleLogin with a TOTP
+// code or a recovery code. Wrong codes count as failed logins.
+func LoginTwoFactorHandler(w http.ResponseWriter, r *http.Request, store storage.Store, jwtKeys *jwtkeys.KeySet, lifetimes TokenLifetimes, limiters LoginLimiters) {
+	var body requests.LoginTwoFactor
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	principal, err := parseMFAToken(body.MFAToken, jwtKeys)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, responses.NewError("Invalid or expired MFA token"))
+		return
+	}
+	revoked, err := store.IsTokenRevoked(r.Context(), principal.TokenID)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	if revoked {
+		writeJSON(w, http.StatusUnauthorized, responses.NewError("Invalid or expired MFA token"))
+		return
+	}
+
+	userKey, addrKey := limiters.keys(r, principal.Username)
+	now := time.Now()
+	if wait, ok := limiters.attempt(userKey, addrKey, now); !ok {
+		tooManyAttempts(w, wait)
+		return
+	}
+	user, err := store.GetUserByID(r.Context(), principal.UserID)
+	if err != nil {
+		limiters.refund(userKey, addrKey)
+		internalError(w, err)
+		return
+	}
+	if user == nil {
+		limiters.refund(userKey, addrKey)
+		writeJSON(w, http.StatusUnauthorized, responses.NewError("Invalid or expired MFA token"))
+		return
+	}
+	if user.DisabledAt != nil {
+		limiters.refund(userKey, addrKey)
+		writeJSON(w, http.StatusForbidden, responses.NewError("Account has been disabled"))
+		return
+	}
+
+	ok, err := verifySecondFactor(r.Context(), store, user.ID, body.Code, body.RecoveryCode)
+	if err != nil {
+		limiters.refund(userKey, addrKey)
+		internalError(w, err)
+		return
+	}
+	if !ok {
+		if wait := limiters.fail(userKey, addrKey, now); wait > 0 {
+			auditLogger(r, user.Username).WithField("lockout", wait.String()).Warn("Login locked out after repeated failures")
+			tooManyAttempts(w, wait)
+			return
+		}
+		auditLogger(r, user.Username).Info("Failed second factor")
+		writeJSON(w, http.StatusUnauthorized, responses.NewError("Invalid two-factor code"))
+		return
+	}
+	if body.RecoveryCode != "" {
+		auditLogger(r, user.Username).Warn("Recovery code used to log in")
+	}
+
+	if err := store.RevokeToken(r.Context(), principal.TokenID, principal.ExpiresAt); err != nil {
+		limiters.refund(userKey, addrKey)
+		internalError(w, err)
+		return
+	}
+	limiters.succeed(userKey, addrKey)
+	response, err := issueTokens(r.Context(), store, user, jwtKeys, lifetimes)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// mfaChallenge answers the first step of a login with a token for the
+// second step.
+func mfaChallenge(w http.ResponseWriter, user *models.User, jwtKeys *jwtkeys.KeySet) {
+	jti, err := randomToken(16)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	mfaToken, err := GenerateMFAToken(user, jwtKeys, jti, mfaTokenTTL)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, responses.MFAChallenge{
+		MFARequired: true,
+		MFAToken:    mfaToken,
+		ExpiresIn:   int(mfaTokenTTL.Seconds()),
+	})
+}
+
+// SetupTOTPHandler starts an enrollment with a new secret. It only takes
+// effect after ConfirmTOTPHandler has seen a code generated from it.
+func SetupTOTPHandler(w http.ResponseWriter, r *http.Request, store storage.Store, twoFactor TwoFactorConfig) {
+	user, ok := accountUser(w, r, store)
+	if !ok {
+		return
+	}
+	var body requests.TOTPSetup
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	if !checkCurrentPassword(w, r, twoFactor.Limiters, user, body.Password) {
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	if err := store.SaveTOTPSecret(r.Context(), user.ID, secret); err != nil {
+		twoFactorError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, responses.TOTPSetup{
+		Status:          "success",
+		Message:         "Scan the provisioning URI and confirm with a code to enable two-factor authentication",
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(twoFactor.Issuer, user.Username, secret),
+	})
+}
+
+// ConfirmTOTPHandler enables two-factor authentication and returns the
+// recovery codes, which are not shown again.
+func ConfirmTOTPHandler(w http.ResponseWriter, r *http.Request, store storage.Store) {
+	user, ok := accountUser(w, r, store)
+	if !ok {
+		return
+	}
+	var body requests.TOTPConfirm
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	pending, err := store.GetTOTP(r.Context(), user.ID)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	if pending == nil {
+		twoFactorError(w, storage.ErrTOTPNotFound)
+		return
+	}
+	if pending.EnabledAt != nil {
+		twoFactorError(w, storage.ErrTOTPEnabled)
+		return
+	}
+	step, ok := totp.Verify(pending.Secret, body.Code, time.Now())
+	if !ok {
+		validationError(w, map[string]string{"code": "Invalid two-factor code"})
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	if err := store.EnableTOTP(r.Context(), user.ID, step, hashes); err != nil {
+		twoFactorError(w, err)
+		return
+	}
+	auditLogger(r, user.Username).Info("Two-factor authentication enabled")
+	writeJSON(w, http.StatusOK, responses.RecoveryCodes{
+		Status:        "success",
+		Message:       "Two-factor authentication has been enabled",
+		RecoveryCodes: codes,
+	})
+}
+
+func DisableTOTPHandler(w http.ResponseWriter, r *http.Request, store storage.Store, twoFactor TwoFactorConfig) {
+	user, ok := accountUser(w, r, store)
+	if !ok {
+		return
+	}
+	var body requests.TwoFactorReauth
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	if !reauthenticate(w, r, store, twoFactor, user, body) {
+		return
+	}
+	if err := store.DeleteTOTP(r.Context(), user.ID); err != nil {
+		internalError(w, err)
+		return
+	}
+	auditLogger(r, user.Username).Warn("Two-factor authentication disabled")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RegenerateRecoveryCodesHandler replaces all recovery codes of the user.
+func RegenerateRecoveryCodesHandler(w http.ResponseWriter, r *http.Request, store storage.Store, twoFactor TwoFactorConfig) {
+	user, ok := accountUser(w, r, store)
+	if !ok {
+		return
+	}
+	var body requests.TwoFactorReauth
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	if !reauthenticate(w, r, store, twoFactor, user, body) {
+		return
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	if err := store.ReplaceRecoveryCodes(r.Context(), user.ID, hashes); err != nil {
+		internalError(w, err)
+		return
+	}
+	auditLogger(r, user.Username).Info("Recovery codes regenerated")
+	writeJSON(w, http.StatusOK, responses.RecoveryCodes{
+		Status:        "success",
+		Message:       "Recovery codes have been regenerated",
+		RecoveryCodes: codes,
+	})
+}
+
+// reauthenticate checks the password and the second factor of a user with
+// two-factor authentication enabled.
+func reauthenticate(w http.ResponseWriter, r *http.Request, store storage.Store, twoFactor TwoFactorConfig, user *models.User, body requests.TwoFactorReauth) bool {
+	if !checkCurrentPassword(w, r, twoFactor.Limiters, user, body.Password) {
+		return false
+	}
+	enabled, err := store.GetTOTP(r.Context(), user.ID)
+	if err != nil {
+		internalError(w, err)
+		return false
+	}
+	if enabled == nil || enabled.EnabledAt == nil {
+		twoFactorError(w, storage.ErrTOTPNotFound)
+		return false
+	}
+	limiters := twoFactor.Limiters
+	userKey, addrKey := limiters.keys(r, user.Username)
+	if wait, ok := limiters.attempt(userKey, addrKey, time.Now()); !ok {
+		tooManyAttempts(w, wait)
+		return false
+	}
+	ok, err := verifySecondFactor(r.Context(), store, user.ID, body.Code, body.RecoveryCode)
+	if err != nil {
+		limiters.refund(userKey, addrKey)
+		internalError(w, err)
+		return false
+	}
+	if !ok {
+		reauthFailed(w, r, limiters, user, "Invalid two-factor code")
+		return false
+	}
+	limiters.refund(userKey, addrKey)
+	return true
+}
+
+// checkCurrentPassword confirms a sensitive change with the password of the
+// user. Wrong passwords count as failed logins.
+func checkCurrentPassword(w http.ResponseWriter, r *http.Request, limiters LoginLimiters, user *models.User, password string) bool {
+	userKey, addrKey := limiters.keys(r, user.Username)
+	if wait, ok := limiters.attempt(userKey, addrKey, time.Now()); !ok {
+		tooManyAttempts(w, wait)
+		return false
+	}
+	if !ComparePasswords(user.Password, password) {
+		reauthFailed(w, r, limiters, user, "Current password is incorrect")
+		return false
+	}
+	limiters.refund(userKey, addrKey)
+	return true
+}
+
+// reauthFailed settles an attempt reserved for the user as failed.
+func reauthFailed(w http.ResponseWriter, r *http.Request, limiters LoginLimiters, user *models.User, message string) {
+	userKey, addrKey := limiters.keys(r, user.Username)
+	if wait := limiters.fail(userKey, addrKey, time.Now()); wait > 0 {
+		auditLogger(r, user.Username).WithField("lockout", wait.String()).Warn("Login locked out after repeated failures")
+		tooManyAttempts(w, wait)
+		return
+	}
+	writeJSON(w, http.StatusForbidden, responses.NewError(message))
+}
+
+// verifySecondFactor accepts a TOTP code that has not been used before or an
+// unused recovery code, which is used up.
+func verifySecondFactor(ctx context.Context, store storage.Store, userID int, code string, recoveryCode string) (bool, error) {
+	if recoveryCode != "" {
+		return store.UseRecoveryCode(ctx, userID, hashToken(normalizeRecoveryCode(recoveryCode)))
+	}
+	enabled, err := store.GetTOTP(ctx, userID)
+	if err != nil || enabled == nil || enabled.EnabledAt == nil {
+		return false, err
+	}
+	step, ok := totp.Verify(enabled.Secret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+	return store.UseTOTPStep(ctx, userID, step)
+}
+
+// newRecoveryCodes returns recovery codes such as abcd-efgh and their
+// hashes for storage.
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		data := make([]byte, 5)
+		if _, err := rand.Read(data); err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(recoveryCodeEncoding.EncodeToString(data))
+		codes[i] = code[:4] + "-" + code[4:]
+		hashes[i] = hashToken(code)
+	}
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+func twoFactorError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, storage.ErrTOTPEnabled), errors.Is(err, storage.ErrTOTPNotFound):
+		writeJSON(w, http.StatusConflict, responses.NewError(err.Error()))
+	default:
+		internalError(w, err)
+	}
+}
@@ -0,0 +1,260 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/requests"
+	"noteserver/internal/pkg/responses"
+	"noteserver/internal/pkg/storage"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+)
+
+const maxWorkspaceNameLength = 100
+
+// ListWorkspacesHandler lists the workspaces the user is a member of, with
+// their role in each.
+func ListWorkspacesHandler(w http.ResponseWriter, r *http.Request, store storage.Store) {
+	user, ok := requestUser(w, r)
+	if !ok {
+		return
+	}
+	workspaces, err := store.ListWorkspaces(r.Context(), user)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, responses.Workspaces{
+		Status:     "success",
+		Message:    "Workspaces retrieved successfully",
+		Workspaces: workspaces,
+	})
+}
+
+// CreateWorkspaceHandler creates a workspace owned by the user.
+func CreateWorkspaceHandler(w http.ResponseWriter, r *http.Request, store storage.Store) {
+	user, ok := requestUser(w, r)
+	if !ok {
+		return
+	}
+	var body requests.Workspace
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	name, err := normalizeWorkspaceName(body.Name)
+	if err != nil {
+		validationError(w, map[string]string{"name": err.Error()})
+		return
+	}
+
+	workspace := models.Workspace{Name: name}
+	if err := store.CreateWorkspace(r.Context(), user, &workspace); err != nil {
+		internalError(w, err)
+		return
+	}
+	writeWorkspace(w, r, store, user, workspace.ID, http.StatusCreated, "Workspace has been created successfully")
+}
+
+func GetWorkspaceHandler(w http.ResponseWriter, r *http.Request, store storage.Store) {
+	user, ok := requestUser(w, r)
+	if !ok {
+		return
+	}
+	workspaceID, ok := workspaceIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	writeWorkspace(w, r, store, user, workspaceID, http.StatusOK, "Workspace has been read successfully")
+}
+
+// PatchWorkspaceHandler renames a workspace, only owners can do this.
+func PatchWorkspaceHandler(w http.ResponseWriter, r *http.Request, store storage.Store) {
+	user, ok := requestUser(w, r)
+	if !ok {
+		return
+	}
+	workspaceID, ok := workspaceIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	var body requests.Workspace
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	name, err := normalizeWorkspaceName(body.Name)
+	if err != nil {
+		validationError(w, map[string]string{"name": err.Error()})
+		return
+	}
+	if err := store.RenameWorkspace(r.Context(), user, workspaceID, name); err != nil {
+		workspaceError(w, err)
+		return
+	}
+	writeWorkspace(w, r, store, user, workspaceID, http.StatusOK, "Workspace has been updated successfully")
+}
+
+// DeleteWorkspaceHandler deletes a workspace, only owners can do this. The
+// notes of the workspace are not deleted, they become personal notes of
+// their authors.
+func DeleteWorkspaceHandler(w http.ResponseWriter, r *http.Request, store storage.Store) {
+	user, ok := requestUser(w, r)
+	if !ok {
+		return
+	}
+	workspaceID, ok := workspaceIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	if err := store.DeleteWorkspace(r.Context(), user, workspaceID); err != nil {
+		workspaceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SetWorkspaceMemberHandler adds a user to a workspace or changes the role
+// of a member, only owners can do this.
+func SetWorkspaceMemberHandler(w http.ResponseWriter, r *http.Request, store storage.Store) {
+	user, ok := requestUser(w, r)
+	if !ok {
+		return
+	}
+	workspaceID, ok := workspaceIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	var body requests.WorkspaceMember
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	fieldErrors := make(map[string]string)
+	if body.Username == "" {
+		fieldErrors["username"] = "Username is required"
+	}
+	if !validWorkspaceRole(body.Role) {
+		fieldErrors["role"] = "Role must be one of " + strings.Join(models.WorkspaceRoles, ", ")
+	}
+	if len(fieldErrors) > 0 {
+		validationError(w, fieldErrors)
+		return
+	}
+
+	member, err := store.GetUserByUsername(r.Context(), body.Username)
+	if err != nil {
+		internalError(w, err)
+		return
+	}
+	if member == nil {
+		writeJSON(w, http.StatusNotFound, responses.NewError(storage.ErrUserNotFound.Error()))
+		return
+	}
+
+	membership := models.WorkspaceMember{
+		WorkspaceID: workspaceID,
+		UserID:      member.ID,
+		Username:    member.Username,
+		Role:        body.Role,
+	}
+	if err := store.SetWorkspaceMember(r.Context(), user, &membership); err != nil {
+		workspaceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, responses.WorkspaceMember{
+		Status:  "success",
+		Message: "Workspace member has been saved successfully",
+		Member:  &membership,
+	})
+}
+
+// RemoveWorkspaceMemberHandler removes a member from a workspace. Owners
+// can remove anyone, other members only themselves. The notes the member
+// wrote in the workspace stay in it.
+func RemoveWorkspaceMemberHandler(w http.ResponseWriter, r *http.Request, store storage.Store) {
+	user, ok := requestUser(w, r)
+	if !ok {
+		return
+	}
+	workspaceID, ok := workspaceIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	userID, err := strconv.Atoi(mux.Vars(r)["user_id"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, responses.NewError("Invalid user id"))
+		return
+	}
+	if err := store.RemoveWorkspaceMember(r.Context(), user, workspaceID, userID); err != nil {
+		workspaceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeWorkspace responds with a workspace and its members.
+func writeWorkspace(w http.ResponseWriter, r *http.Request, store storage.Store, user *models.User, workspaceID int, status int, message string) {
+	workspace, err := store.GetWorkspace(r.Context(), user, workspaceID)
+	if err != nil {
+		workspaceError(w, err)
+		return
+	}
+	members, err := store.ListWorkspaceMembers(r.Context(), user, workspaceID)
+	if err != nil {
+		workspaceError(w, err)
+		return
+	}
+	if status == http.StatusCreated {
+		w.Header().Set("Location", "/v1/workspaces/"+strconv.Itoa(workspaceID))
+	}
+	writeJSON(w, status, responses.Workspace{
+		Status:    "success",
+		Message:   message,
+		Workspace: &workspace,
+		Members:   members,
+	})
+}
+
+func normalizeWorkspaceName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	switch {
+	case name == "":
+		return "", errors.New("Workspace names must not be empty")
+	case utf8.RuneCountInString(name) > maxWorkspaceNameLength:
+		return "", errors.New("Workspace names must be at most " + strconv.Itoa(maxWorkspaceNameLength) + " characters long")
+	}
+	return name, nil
+}
+
+func validWorkspaceRole(role string) bool {
+	for _, known := range models.WorkspaceRoles {
+		if role == known {
+			return true
+		}
+	}
+	return false
+}
+
+func workspaceIDFromPath(w http.ResponseWriter, r *http.Request) (int, bool) {
+	workspaceID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, responses.NewError("Invalid workspace id"))
+		return 0, false
+	}
+	return workspaceID, true
+}
+
+func workspaceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, storage.ErrWorkspaceNotFound), errors.Is(err, storage.ErrMemberNotFound):
+		writeJSON(w, http.StatusNotFound, responses.NewError(err.Error()))
+	case errors.Is(err, storage.ErrNotWorkspaceOwner), errors.Is(err, storage.ErrWorkspaceReadOnly):
+		writeJSON(w, http.StatusForbidden, responses.NewError(err.Error()))
+	case errors.Is(err, storage.ErrLastWorkspaceOwner):
+		writeJSON(w, http.StatusConflict, responses.NewError(err.Error()))
+	default:
+		notebookError(w, err)
+	}
+}
@@ -0,0 +1,63 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	l "noteserver/internal/pkg/logger"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type Config struct {
+	URL               string
+	MaxConns          int32
+	MinConns          int32
+	HealthCheckPeriod time.Duration
+	ConnectRetries    int
+	RetryInterval     time.Duration
+}
+
+// Connect opens a connection pool and waits until the server answers a ping.
+// Broken connections are dropped by the pool and replaced on demand, so a
+// database restart does not require restarting NoteServer.
+func Connect(ctx context.Context, cfg Config) (*pgxpool.Pool, error) {
+	poolConfig, err := pgxpool.ParseConfig(cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.MaxConns > 0 {
+		poolConfig.MaxConns = cfg.MaxConns
+	}
+	if cfg.MinConns > 0 {
+		poolConfig.MinConns = cfg.MinConns
+	}
+	if cfg.HealthCheckPeriod > 0 {
+		poolConfig.HealthCheckPeriod = cfg.HealthCheckPeriod
+	}
+
+	poolConfig.LazyConnect = true
+
+	pool, err := pgxpool.ConnectConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	for attempt := 0; ; attempt++ {
+		err = pool.Ping(ctx)
+		if err == nil {
+			return pool, nil
+		}
+		if attempt >= cfg.ConnectRetries {
+			pool.Close()
+			return nil, err
+		}
+		l.Logger.Warn("Database is not available, retrying:", err)
+		select {
+		case <-ctx.Done():
+			pool.Close()
+			return nil, ctx.Err()
+		case <-time.After(cfg.RetryInterval):
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Op string
+
+const (
+	Equal  Op = "equal"
+	Insert Op = "insert"
+	Delete Op = "delete"
+)
+
+// Line is one line of a line-based diff. OldLine and NewLine are 1-based
+// line numbers in the old and new text, zero when the line is absent there.
+type Line struct {
+	Op      Op     `json:"op"`
+	OldLine int    `json:"old_line,omitempty"`
+	NewLine int    `json:"new_line,omitempty"`
+	Text    string `json:"text"`
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// MaxLines bounds the number of lines of each text passed to Lines by
+// callers, the running time grows with the size of the texts times the
+// number of changes.
+const MaxLines = 10000
+
+// CountLines returns the number of lines Lines sees in a text.
+func CountLines(text string) int {
+	if text == "" {
+		return 0
+	}
+	return strings.Count(strings.TrimSuffix(text, "\n"), "\n") + 1
+}
+
+// Lines computes a shortest line-based edit script between two texts using
+// the linear space variant of Myers' algorithm.
+func Lines(oldText, newText string) []Line {
+	a, b := splitLines(oldText), splitLines(newText)
+	size := 2*((len(a)+len(b)+1)/2) + 3
+	d := differ{a: a, b: b, forward: make([]int, size), backward: make([]int, size)}
+	d.compare(0, len(a), 0, len(b))
+	return d.lines
+}
+
+// differ holds the state of a diff: the furthest reaching x per diagonal of
+// the forward and backward searches, reused by every step, and the lines
+// found so far.
+type differ struct {
+	a, b              []string
+	forward, backward []int
+	lines             []Line
+}
+
+// compare appends the edit script of a[aLo:aHi] and b[bLo:bHi], splitting it
+// at the middle snake of a shortest path.
+func (d *differ) compare(aLo, aHi, bLo, bHi int) {
+	for aLo < aHi && bLo < bHi && d.a[aLo] == d.b[bLo] {
+		d.equal(aLo, bLo)
+		aLo++
+		bLo++
+	}
+	aEnd, bEnd := aHi, bHi
+	for aLo < aEnd && bLo < bEnd && d.a[aEnd-1] == d.b[bEnd-1] {
+		aEnd--
+		bEnd--
+	}
+
+	switch {
+	case aLo == aEnd:
+		for y := bLo; y < bEnd; y++ {
+			d.lines = append(d.lines, Line{Op: Insert, NewLine: y + 1, Text: d.b[y]})
+		}
+	case bLo == bEnd:
+		for x := aLo; x < aEnd; x++ {
+			d.lines = append(d.lines, Line{Op: Delete, OldLine: x + 1, Text: d.a[x]})
+		}
+	default:
+		x, y := d.middleSnake(aLo, aEnd, bLo, bEnd)
+		d.compare(aLo, x, bLo, y)
+		d.compare(x, aEnd, y, bEnd)
+	}
+
+	for x, y := aEnd, bEnd; x < aHi; x, y = x+1, y+1 {
+		d.equal(x, y)
+	}
+}
+
+func (d *differ) equal(x, y int) {
+	d.lines = append(d.lines, Line{Op: Equal, OldLine: x + 1, NewLine: y + 1, Text: d.a[x]})
+}
+
+// middleSnake searches from both corners of the edit graph at once and
+// returns a point where the searches meet. Both ranges must be non-empty
+// and differ in their first and last lines, the point then lies strictly
+// between the corners.
+func (d *differ) middleSnake(aLo, aHi, bLo, bHi int) (int, int) {
+	n, m := aHi-aLo, bHi-bLo
+	delta := n - m
+	odd := delta%2 != 0
+	max := (n + m + 1) / 2
+	offset := max + 1
+	forward, backward := d.forward, d.backward
+	forward[offset+1], backward[offset+1] = 0, 0
+
+	for step := 0; step <= max; step++ {
+		for k := -step; k <= step; k += 2 {
+			x := furthest(forward, offset, k, step)
+			y := x - k
+			for x < n && y < m && d.a[aLo+x] == d.b[bLo+y] {
+				x++
+				y++
+			}
+			forward[offset+k] = x
+			// The backward search on diagonal delta-k has made step-1 moves.
+			if odd && delta-k >= -(step-1) && delta-k <= step-1 && x+backward[offset+delta-k] >= n {
+				return aLo + x, bLo + y
+			}
+		}
+		for k := -step; k <= step; k += 2 {
+			x := furthest(backward, offset, k, step)
+			y := x - k
+			for x < n && y < m && d.a[aHi-1-x] == d.b[bHi-1-y] {
+				x++
+				y++
+			}
+			backward[offset+k] = x
+			if !odd && delta-k >= -step && delta-k <= step && x+forward[offset+delta-k] >= n {
+				return aHi - x, bHi - y
+			}
+		}
+	}
+	panic("diff: no middle snake")
+}
+
+// furthest returns the x a path with step moves starts from on diagonal k,
+// before following the diagonal.
+func furthest(v []int, offset, k, step int) int {
+	if k == -step || (k != step && v[offset+k-1] < v[offset+k+1]) {
+		return v[offset+k+1]
+	}
+	return v[offset+k-1] + 1
+}
+
+// Unified renders a diff in the unified format with the given number of
+// context lines around each change.
+func Unified(lines []Line, context int) string {
+	var builder strings.Builder
+	for start := 0; start < len(lines); {
+		if lines[start].Op == Equal {
+			start++
+			continue
+		}
+
+		// Grow the hunk until the gap to the next change is larger than
+		// twice the context.
+		from := start - context
+		if from < 0 {
+			from = 0
+		}
+		to := start
+		for i := start; i < len(lines); i++ {
+			if lines[i].Op != Equal {
+				to = i
+			} else if i-to > 2*context {
+				break
+			}
+		}
+		to += context + 1
+		if to > len(lines) {
+			to = len(lines)
+		}
+
+		oldStart, newStart, oldCount, newCount := 0, 0, 0, 0
+		for _, line := range lines[from:to] {
+			if line.OldLine > 0 {
+				if oldStart == 0 {
+					oldStart = line.OldLine
+				}
+				oldCount++
+			}
+			if line.NewLine > 0 {
+				if newStart == 0 {
+					newStart = line.NewLine
+				}
+				newCount++
+			}
+		}
+		fmt.Fprintf(&builder, "@@ -%d,%d +%d,%d @@\n", oldStart, oldCount, newStart, newCount)
+		for _, line := range lines[from:to] {
+			switch line.Op {
+			case Equal:
+				builder.WriteString(" ")
+			case Insert:
+				builder.WriteString("+")
+			case Delete:
+				builder.WriteString("-")
+			}
+			builder.WriteString(line.Text + "\n")
+		}
+		start = to
+	}
+	return builder.String()
+}
@@ -0,0 +1,108 @@
+package diff
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// lcs returns the length of the longest common subsequence of a and b.
+func lcs(a, b []string) int {
+	table := make([][]int, len(a)+1)
+	for i := range table {
+		table[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				table[i][j] = table[i+1][j+1] + 1
+			case table[i+1][j] > table[i][j+1]:
+				table[i][j] = table[i+1][j]
+			default:
+				table[i][j] = table[i][j+1]
+			}
+		}
+	}
+	return table[0][0]
+}
+
+// checkScript verifies that lines turns oldText into newText with numbered
+// lines and the fewest possible insertions and deletions.
+func checkScript(t *testing.T, oldText, newText string, lines []Line) {
+	t.Helper()
+	a, b := splitLines(oldText), splitLines(newText)
+	var old, updated []string
+	edits := 0
+	for _, line := range lines {
+		switch line.Op {
+		case Equal:
+			old = append(old, line.Text)
+			updated = append(updated, line.Text)
+		case Delete:
+			old = append(old, line.Text)
+			edits++
+		case Insert:
+			updated = append(updated, line.Text)
+			edits++
+		}
+		if line.OldLine > 0 && (line.OldLine != len(old) || a[line.OldLine-1] != line.Text) {
+			t.Fatalf("%q -> %q: wrong old line number in %+v", oldText, newText, line)
+		}
+		if line.NewLine > 0 && (line.NewLine != len(updated) || b[line.NewLine-1] != line.Text) {
+			t.Fatalf("%q -> %q: wrong new line number in %+v", oldText, newText, line)
+		}
+	}
+	if strings.Join(old, "\n") != strings.Join(a, "\n") || strings.Join(updated, "\n") != strings.Join(b, "\n") {
+		t.Fatalf("%q -> %q: script %+v does not reproduce the texts", oldText, newText, lines)
+	}
+	if want := len(a) + len(b) - 2*lcs(a, b); edits != want {
+		t.Fatalf("%q -> %q: %d edits, want %d", oldText, newText, edits, want)
+	}
+}
+
+func TestLines(t *testing.T) {
+	tests := []struct{ old, new string }{
+		{"", ""},
+		{"", "a\nb\n"},
+		{"a\nb\n", ""},
+		{"a\nb\nc\n", "a\nb\nc\n"},
+		{"a\nb\nc\n", "a\nx\nc\n"},
+		{"a\nb\nc\na\nb\nb\na\n", "c\nb\na\nb\na\nc\n"},
+		{"a\nb\n", "b\na\n"},
+	}
+	for _, test := range tests {
+		checkScript(t, test.old, test.new, Lines(test.old, test.new))
+	}
+}
+
+func TestLinesRandom(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	text := func() string {
+		words := make([]string, random.Intn(30))
+		for i := range words {
+			words[i] = string(rune('a' + random.Intn(4)))
+		}
+		return strings.Join(words, "\n")
+	}
+	for i := 0; i < 2000; i++ {
+		oldText, newText := text(), text()
+		checkScript(t, oldText, newText, Lines(oldText, newText))
+	}
+}
+
+func TestCountLines(t *testing.T) {
+	for text, want := range map[string]int{"": 0, "a": 1, "a\n": 1, "a\nb": 2, "\n\n": 2} {
+		if got := CountLines(text); got != want || got != len(splitLines(text)) {
+			t.Errorf("CountLines(%q) = %d, want %d", text, got, want)
+		}
+	}
+}
+
+func TestUnified(t *testing.T) {
+	got := Unified(Lines("a\nb\nc\n", "a\nx\nc\n"), 1)
+	want := "@@ -1,3 +1,3 @@\n a\n-b\n+x\n c\n"
+	if got != want {
+		t.Errorf("Unified = %q, want %q", got, want)
+	}
+}
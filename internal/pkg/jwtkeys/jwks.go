@@ -0,0 +1,164 @@
+package jwtkeys
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// JWK is a public key in the JSON Web Key format (RFC 7517).
+type JWK struct {
+	KeyType   string `json:"kty"`
+	ID        string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Curve     string `json:"crv,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	X         string `json:"x,omitempty"`
+	Y         string `json:"y,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public parts of all asymmetric keys. HMAC secrets are
+// never published.
+func (s *KeySet) JWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+	for _, id := range s.order {
+		key := s.keys[id]
+		if !key.Public() {
+			continue
+		}
+		jwk := JWK{ID: key.ID, Use: "sig", Algorithm: key.Method.Alg()}
+		switch k := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = encode(k.N.Bytes())
+			jwk.E = encode(big.NewInt(int64(k.E)).Bytes())
+		case *ecdsa.PublicKey:
+			size := (k.Curve.Params().BitSize + 7) / 8
+			jwk.KeyType = "EC"
+			jwk.Curve = k.Curve.Params().Name
+			jwk.X = encode(k.X.FillBytes(make([]byte, size)))
+			jwk.Y = encode(k.Y.FillBytes(make([]byte, size)))
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = encode(k)
+		default:
+			continue
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	return jwks
+}
+
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// ParseJWKS builds a key set that verifies tokens with the keys of a JWKS
+// published by another party, such as an identity provider. Keys that are
+// not for signatures or of unsupported types are skipped.
+func ParseJWKS(jwks JWKS) (*KeySet, error) {
+	keys := NewKeySet()
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.Key()
+		if err != nil {
+			continue
+		}
+		if err := keys.Add(key); err != nil {
+			return nil, err
+		}
+	}
+	if len(keys.order) == 0 {
+		return nil, errors.New("no usable signing keys in JWKS")
+	}
+	return keys, nil
+}
+
+// Key returns the key as a verification key. Without an alg member, RSA
+// keys are taken to be used with RS256.
+func (jwk JWK) Key() (*Key, error) {
+	key := &Key{ID: jwk.ID}
+	switch jwk.KeyType {
+	case "RSA":
+		n, err := decode(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		if len(n) == 0 || len(e) == 0 || len(e) > 4 {
+			return nil, errors.New("invalid RSA key")
+		}
+		key.Method = jwt.SigningMethodRS256
+		key.verifyKey = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Curve {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported elliptic curve %q", jwk.Curve)
+		}
+		x, err := decode(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		publicKey := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(publicKey.X, publicKey.Y) {
+			return nil, errors.New("invalid EC key")
+		}
+		key.Method = ecdsaMethod(curve)
+		key.verifyKey = publicKey
+	case "OKP":
+		x, err := decode(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		if jwk.Curve != "Ed25519" || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("unsupported OKP key")
+		}
+		key.Method = SigningMethodEdDSA
+		key.verifyKey = ed25519.PublicKey(x)
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", jwk.KeyType)
+	}
+
+	if jwk.Algorithm != "" && jwk.Algorithm != key.Method.Alg() {
+		method, ok := jwt.GetSigningMethod(jwk.Algorithm).(*jwt.SigningMethodRSA)
+		if !ok || jwk.KeyType != "RSA" {
+			return nil, fmt.Errorf("algorithm %q does not match key type %q", jwk.Algorithm, jwk.KeyType)
+		}
+		key.Method = method
+	}
+	return key, nil
+}
+
+func decode(data string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(data)
+}
@@ -0,0 +1,115 @@
+// Package jwtkeys manages the keys that sign and verify access tokens.
+// Every key has an id that is written to the kid header of the tokens it
+// signs, so that keys can be rotated: a new key signs new tokens while the
+// old one still verifies tokens issued before the rotation.
+package jwtkeys
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+type Key struct {
+	ID     string
+	Method jwt.SigningMethod
+	// signKey is nil for keys that only verify tokens.
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+func (k *Key) CanSign() bool {
+	return k.signKey != nil
+}
+
+// Public reports whether the key can be published in a JWKS, which is not
+// the case for shared secrets.
+func (k *Key) Public() bool {
+	_, hmac := k.Method.(*jwt.SigningMethodHMAC)
+	return !hmac
+}
+
+func NewHMACKey(id string, secret []byte) *Key {
+	return &Key{ID: id, Method: jwt.SigningMethodHS256, signKey: secret, verifyKey: secret}
+}
+
+func LoadPEMFile(id string, path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := ParsePEM(id, data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return key, nil
+}
+
+// ParsePEM reads an RSA, ECDSA or Ed25519 key. Private keys sign and verify
+// tokens, public keys only verify them.
+func ParsePEM(id string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		parsed, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &Key{ID: id}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Method, key.signKey, key.verifyKey = jwt.SigningMethodRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.Method, key.verifyKey = jwt.SigningMethodRS256, k
+	case *ecdsa.PrivateKey:
+		key.Method, key.signKey, key.verifyKey = ecdsaMethod(k.Curve), k, &k.PublicKey
+	case *ecdsa.PublicKey:
+		key.Method, key.verifyKey = ecdsaMethod(k.Curve), k
+	case ed25519.PrivateKey:
+		key.Method, key.signKey, key.verifyKey = SigningMethodEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.Method, key.verifyKey = SigningMethodEdDSA, k
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", parsed)
+	}
+	if key.Method == nil {
+		return nil, errors.New("unsupported elliptic curve, use P-256, P-384 or P-521")
+	}
+	return key, nil
+}
+
+func ecdsaMethod(curve elliptic.Curve) jwt.SigningMethod {
+	switch curve {
+	case elliptic.P256():
+		return jwt.SigningMethodES256
+	case elliptic.P384():
+		return jwt.SigningMethodES384
+	case elliptic.P521():
+		return jwt.SigningMethodES512
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package jwtkeys
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// KeySet holds the keys accepted for verification and the one key that
+// signs new tokens.
+type KeySet struct {
+	keys    map[string]*Key
+	order   []string
+	signing *Key
+}
+
+func NewKeySet() *KeySet {
+	return &KeySet{keys: make(map[string]*Key)}
+}
+
+func (s *KeySet) Add(key *Key) error {
+	if _, ok := s.keys[key.ID]; ok {
+		return fmt.Errorf("duplicate key id %q", key.ID)
+	}
+	s.keys[key.ID] = key
+	s.order = append(s.order, key.ID)
+	return nil
+}
+
+func (s *KeySet) SetSigningKey(id string) error {
+	key, ok := s.keys[id]
+	if !ok {
+		return fmt.Errorf("unknown key id %q", id)
+	}
+	if !key.CanSign() {
+		return fmt.Errorf("key %q is a public key and cannot sign tokens", id)
+	}
+	s.signing = key
+	return nil
+}
+
+func (s *KeySet) SigningKey() *Key {
+	return s.signing
+}
+
+// Sign signs claims with the signing key and names it in the kid header.
+func (s *KeySet) Sign(claims jwt.Claims) (string, error) {
+	if s.signing == nil {
+		return "", errors.New("no signing key configured")
+	}
+	token := jwt.NewWithClaims(s.signing.Method, claims)
+	token.Header["kid"] = s.signing.ID
+	return token.SignedString(s.signing.signKey)
+}
+
+// Keyfunc returns the verification key named by the kid header of a token.
+// The token must use the algorithm of that key, so a public key can never
+// be used as an HMAC secret. Tokens without kid were issued before keys had
+// ids and are checked against the HS256 key, if there is one, or else
+// against the only key of the set.
+func (s *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	var key *Key
+	if kid, ok := token.Header["kid"].(string); ok {
+		key = s.keys[kid]
+	} else {
+		for _, id := range s.order {
+			if s.keys[id].Method == jwt.SigningMethodHS256 {
+				key = s.keys[id]
+				break
+			}
+		}
+		if key == nil && len(s.order) == 1 {
+			key = s.keys[s.order[0]]
+		}
+	}
+	if key == nil {
+		return nil, ErrUnknownKey
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s for key %q", token.Method.Alg(), key.ID)
+	}
+	return key.verifyKey, nil
+}
+
+// Algorithms lists the signing algorithms of all keys.
+func (s *KeySet) Algorithms() []string {
+	var algorithms []string
+	seen := make(map[string]bool)
+	for _, id := range s.order {
+		alg := s.keys[id].Method.Alg()
+		if !seen[alg] {
+			seen[alg] = true
+			algorithms = append(algorithms, alg)
+		}
+	}
+	return algorithms
+}
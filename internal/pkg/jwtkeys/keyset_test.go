@@ -0,0 +1,126 @@
+package jwtkeys
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"testing"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// pemKey encodes a private key in PKCS #8 and parses it as a key named id.
+func pemKey(t *testing.T, id string, private interface{}) (*Key, []byte) {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ParsePEM(id, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	public, err := x509.MarshalPKIXPublicKey(key.verifyKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: public})
+}
+
+func TestKeyfunc(t *testing.T) {
+	ecPrivate, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hs := NewHMACKey("hs", []byte("secret"))
+	ec, ecPublicPEM := pemKey(t, "ec", ecPrivate)
+	ed, _ := pemKey(t, "ed", edPrivate)
+
+	tests := []struct {
+		name   string
+		keys   []*Key
+		method jwt.SigningMethod
+		kid    interface{}
+		sign   interface{}
+		ok     bool
+	}{
+		{"HS256", []*Key{hs, ec}, jwt.SigningMethodHS256, "hs", []byte("secret"), true},
+		{"ES256", []*Key{hs, ec}, jwt.SigningMethodES256, "ec", ecPrivate, true},
+		{"EdDSA", []*Key{hs, ed}, SigningMethodEdDSA, "ed", edPrivate, true},
+		{"without kid uses the HS256 key", []*Key{ec, hs}, jwt.SigningMethodHS256, nil, []byte("secret"), true},
+		{"without kid uses the only key", []*Key{ec}, jwt.SigningMethodES256, nil, ecPrivate, true},
+		{"without kid and several keys", []*Key{ec, ed}, jwt.SigningMethodES256, nil, ecPrivate, false},
+		{"unknown kid", []*Key{hs, ec}, jwt.SigningMethodES256, "other", ecPrivate, false},
+		{"kid that is not a string", []*Key{hs, ec}, jwt.SigningMethodES256, 1, ecPrivate, false},
+		{"public key as HMAC secret", []*Key{hs, ec}, jwt.SigningMethodHS256, "ec", ecPublicPEM, false},
+		{"HMAC key with another algorithm", []*Key{hs}, jwt.SigningMethodHS512, "hs", []byte("secret"), false},
+		{"other asymmetric algorithm", []*Key{hs, ec, ed}, SigningMethodEdDSA, "ec", edPrivate, false},
+		{"none", []*Key{hs}, jwt.SigningMethodNone, "hs", jwt.UnsafeAllowNoneSignatureType, false},
+	}
+	for _, test := range tests {
+		keys := NewKeySet()
+		for _, key := range test.keys {
+			if err := keys.Add(key); err != nil {
+				t.Fatal(err)
+			}
+		}
+		token := jwt.NewWithClaims(test.method, jwt.StandardClaims{Subject: "alice"})
+		if test.kid != nil {
+			token.Header["kid"] = test.kid
+		}
+		signed, err := token.SignedString(test.sign)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		_, err = jwt.Parse(signed, keys.Keyfunc)
+		if ok := err == nil; ok != test.ok {
+			t.Errorf("%s: Parse error %v, want success %v", test.name, err, test.ok)
+		}
+	}
+}
+
+func TestKeyfuncUnknownKey(t *testing.T) {
+	keys := NewKeySet()
+	keys.Add(NewHMACKey("hs", []byte("secret")))
+	token := jwt.New(jwt.SigningMethodHS256)
+	token.Header["kid"] = "other"
+	if _, err := keys.Keyfunc(token); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Keyfunc error %v, want %v", err, ErrUnknownKey)
+	}
+}
+
+func TestSign(t *testing.T) {
+	ecPrivate, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ec, _ := pemKey(t, "ec", ecPrivate)
+	keys := NewKeySet()
+	keys.Add(NewHMACKey("hs", []byte("secret")))
+	keys.Add(ec)
+	if _, err := keys.Sign(jwt.StandardClaims{}); err == nil {
+		t.Error("Sign without a signing key succeeded")
+	}
+	if err := keys.SetSigningKey("ec"); err != nil {
+		t.Fatal(err)
+	}
+	signed, err := keys.Sign(jwt.StandardClaims{Subject: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := jwt.Parse(signed, keys.Keyfunc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.Header["kid"] != "ec" || token.Method != jwt.SigningMethodES256 {
+		t.Errorf("signed with %v by %v, want ES256 by ec", token.Method.Alg(), token.Header["kid"])
+	}
+}
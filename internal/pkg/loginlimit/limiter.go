@@ -0,0 +1,191 @@
+// Package loginlimit slows down password guessing by locking out keys, such
+// as usernames or client addresses, after repeated failed logins.
+package loginlimit
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultMaxKeys is the number of keys remembered when Config.MaxKeys is
+// not set.
+const DefaultMaxKeys = 100000
+
+type Config struct {
+	// MaxFailures is the number of consecutive failures allowed before a key
+	// is locked out.
+	MaxFailures int
+	// BaseLockout is the first lockout, it doubles with every further
+	// failure up to MaxLockout.
+	BaseLockout time.Duration
+	MaxLockout  time.Duration
+	// Window is how long failures are remembered without a new failure.
+	Window time.Duration
+	// MaxKeys bounds the number of keys remembered at once. When it is
+	// reached, the key whose lockout ends first is forgotten.
+	MaxKeys int
+}
+
+// Limiter counts failures per key in process memory, so every server
+// instance keeps its own counts.
+//
+// Every attempt is announced with Attempt before the credentials are
+// checked and then settled with Fail, Refund or Reset. Attempts in flight
+// count as failures until they are settled, so concurrent attempts cannot
+// get past a lockout.
+type Limiter struct {
+	config    Config
+	mu        sync.Mutex
+	entries   map[string]*entry
+	lastPrune time.Time
+}
+
+type entry struct {
+	failures    int
+	pending     int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+func New(config Config) *Limiter {
+	if config.MaxKeys <= 0 {
+		config.MaxKeys = DefaultMaxKeys
+	}
+	return &Limiter{config: config, entries: make(map[string]*entry)}
+}
+
+// Attempt reserves an attempt for the key. It returns false and how long to
+// wait while the key is locked out, or while the attempts in flight may
+// lock it out. Otherwise it returns true and the lockout that a
+// failure of the attempt causes, zero if none.
+func (l *Limiter) Attempt(key string, now time.Time) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e := l.entry(key, now)
+	if e.lockedUntil.After(now) {
+		return e.lockedUntil.Sub(now), false
+	}
+	if e.pending > 0 && e.failures+e.pending >= l.config.MaxFailures {
+		return l.lockout(e.failures + e.pending), false
+	}
+	e.pending++
+	return l.lockout(e.failures + e.pending), true
+}
+
+// Fail records a reserved attempt as failed and returns the lockout it
+// caused, zero if the key may still try again right away.
+func (l *Limiter) Fail(key string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e := l.entry(key, now)
+	if e.pending > 0 {
+		e.pending--
+	}
+	e.failures++
+	e.lastFailure = now
+	lockout := l.lockout(e.failures)
+	if lockout > 0 {
+		e.lockedUntil = now.Add(lockout)
+	}
+	return lockout
+}
+
+// Refund takes back a reserved attempt that did not fail.
+func (l *Limiter) Refund(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e, ok := l.entries[key]
+	if !ok || e.pending == 0 {
+		return
+	}
+	e.pending--
+	if l.expired(e, time.Now()) {
+		delete(l.entries, key)
+	}
+}
+
+// Reset forgets the failures of the key after a successful login.
+func (l *Limiter) Reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.entries, key)
+}
+
+// lockout returns the lockout caused by the given number of consecutive
+// failures.
+func (l *Limiter) lockout(failures int) time.Duration {
+	if failures < l.config.MaxFailures {
+		return 0
+	}
+	lockout := l.config.BaseLockout
+	for i := l.config.MaxFailures; i < failures && lockout < l.config.MaxLockout; i++ {
+		lockout *= 2
+	}
+	if lockout > l.config.MaxLockout {
+		lockout = l.config.MaxLockout
+	}
+	return lockout
+}
+
+// entry returns the entry of the key, starting over when its failures are
+// no longer remembered. The caller must hold the lock.
+func (l *Limiter) entry(key string, now time.Time) *entry {
+	if now.Sub(l.lastPrune) > l.config.Window {
+		l.prune(now)
+	}
+	e, ok := l.entries[key]
+	if ok && l.expired(e, now) {
+		e.failures = 0
+	}
+	if !ok {
+		if len(l.entries) >= l.config.MaxKeys {
+			l.evict(now)
+		}
+		e = &entry{}
+		l.entries[key] = e
+	}
+	return e
+}
+
+// prune drops entries that are neither locked, remembered nor in use any
+// more. The caller must hold the lock.
+func (l *Limiter) prune(now time.Time) {
+	for key, e := range l.entries {
+		if l.expired(e, now) {
+			delete(l.entries, key)
+		}
+	}
+	l.lastPrune = now
+}
+
+// expired reports whether an entry holds nothing worth remembering.
+func (l *Limiter) expired(e *entry, now time.Time) bool {
+	return e.pending == 0 && now.Sub(e.lastFailure) > l.config.Window && !e.lockedUntil.After(now)
+}
+
+// evict makes room for a new key by pruning, or else by dropping the idle
+// entry whose lockout ends first. The caller must hold the lock.
+func (l *Limiter) evict(now time.Time) {
+	l.prune(now)
+	if len(l.entries) < l.config.MaxKeys {
+		return
+	}
+	var victim string
+	var first *entry
+	for key, e := range l.entries {
+		if e.pending > 0 {
+			continue
+		}
+		if first == nil || e.lockedUntil.Before(first.lockedUntil) ||
+			e.lockedUntil.Equal(first.lockedUntil) && e.lastFailure.Before(first.lastFailure) {
+			victim, first = key, e
+		}
+	}
+	if first != nil {
+		delete(l.entries, victim)
+	}
+}
@@ -0,0 +1,116 @@
+package loginlimit
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+var testConfig = Config{MaxFailures: 3, BaseLockout: time.Minute, MaxLockout: 4 * time.Minute, Window: time.Hour}
+
+func TestLockout(t *testing.T) {
+	l := New(testConfig)
+	now := time.Now()
+	want := []time.Duration{0, 0, time.Minute, 2 * time.Minute, 4 * time.Minute, 4 * time.Minute}
+	for i, lockout := range want {
+		if _, ok := l.Attempt("alice", now); !ok {
+			t.Fatalf("attempt %d refused", i+1)
+		}
+		if got := l.Fail("alice", now); got != lockout {
+			t.Errorf("failure %d: lockout %v, want %v", i+1, got, lockout)
+		}
+		if wait, ok := l.Attempt("alice", now); lockout > 0 && (ok || wait != lockout) {
+			t.Errorf("after failure %d: Attempt = %v, %v, want %v, false", i+1, wait, ok, lockout)
+		} else if ok {
+			l.Refund("alice")
+		}
+		now = now.Add(lockout)
+	}
+
+	l.Reset("alice")
+	if wait, ok := l.Attempt("alice", now); !ok || wait != 0 {
+		t.Errorf("after reset: Attempt = %v, %v, want 0, true", wait, ok)
+	}
+}
+
+func TestWindow(t *testing.T) {
+	l := New(testConfig)
+	now := time.Now()
+	for i := 0; i < 2; i++ {
+		l.Attempt("alice", now)
+		l.Fail("alice", now)
+	}
+	now = now.Add(testConfig.Window + time.Second)
+	l.Attempt("alice", now)
+	if lockout := l.Fail("alice", now); lockout != 0 {
+		t.Errorf("failures outside the window count, lockout %v", lockout)
+	}
+}
+
+func TestRefund(t *testing.T) {
+	l := New(testConfig)
+	now := time.Now()
+	for i := 0; i < 10; i++ {
+		if _, ok := l.Attempt("alice", now); !ok {
+			t.Fatalf("attempt %d refused although all were refunded", i+1)
+		}
+		l.Refund("alice")
+	}
+}
+
+// TestConcurrentAttempts checks that attempts in flight count, so that no
+// more than MaxFailures attempts get through before the key is locked.
+func TestConcurrentAttempts(t *testing.T) {
+	l := New(testConfig)
+	now := time.Now()
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	admitted := 0
+	start := make(chan struct{})
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			if _, ok := l.Attempt("alice", now); ok {
+				mu.Lock()
+				admitted++
+				mu.Unlock()
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+	if admitted != testConfig.MaxFailures {
+		t.Errorf("%d concurrent attempts admitted, want %d", admitted, testConfig.MaxFailures)
+	}
+	for i := 0; i < admitted; i++ {
+		l.Fail("alice", now)
+	}
+	if _, ok := l.Attempt("alice", now); ok {
+		t.Error("attempt admitted after the admitted attempts failed")
+	}
+}
+
+func TestMaxKeys(t *testing.T) {
+	config := testConfig
+	config.MaxKeys = 10
+	l := New(config)
+	now := time.Now()
+	for i := 0; i < 100; i++ {
+		key := strconv.Itoa(i)
+		l.Attempt(key, now)
+		l.Fail(key, now)
+	}
+	if len(l.entries) > config.MaxKeys {
+		t.Errorf("%d keys remembered, want at most %d", len(l.entries), config.MaxKeys)
+	}
+
+	now = now.Add(config.Window + time.Second)
+	l.Attempt("alice", now)
+	l.Refund("alice")
+	if len(l.entries) != 0 {
+		t.Errorf("%d keys remembered after the window, want 0", len(l.entries))
+	}
+}
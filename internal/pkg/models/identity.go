@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Identity links an account at an OpenID Connect provider, named by the
+// subject the provider assigned to it, to a user.
+type Identity struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"-"`
+	Provider  string    `json:"provider"`
+	Subject   string    `json:"subject"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// OIDCLogin is a login in progress at an identity provider, found again by
+// the hash of the state the provider sends back. LinkUserID is set when an
+// identity is linked to an existing user instead of logging in.
+type OIDCLogin struct {
+	StateHash  string
+	Provider   string
+	Nonce      string
+	Verifier   string
+	LinkUserID int
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// Invite allows one person to register while registration is invite only.
+// Only the hash of the code is stored.
+type Invite struct {
+	ID        int        `json:"id"`
+	CreatedBy int        `json:"-"`
+	CodeHash  string     `json:"-"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+}
@@ -5,9 +5,38 @@ import (
 )
 
 type Note struct {
+	ID         int  `json:"id"`
+	UserID     int  `json:"user_id"`
+	NotebookID *int `json:"notebook_id"`
+	// WorkspaceID is set for notes filed in a workspace, UserID is then
+	// the author of the note.
+	WorkspaceID *int     `json:"workspace_id"`
+	Title       string   `json:"title"`
+	Content     string   `json:"content"`
+	Tags        []string `json:"tags"`
+	// Version is incremented on every change of the note and is used as
+	// its ETag.
+	Version   int        `json:"version"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+type NoteSummary struct {
 	ID        int       `json:"id"`
-	UserID    int       `json:"user_id"`
 	Title     string    `json:"title"`
-	Content   string    `json:"content"`
+	Snippet   string    `json:"snippet"`
+	Tags      []string  `json:"tags"`
 	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type NoteSearchResult struct {
+	ID             int       `json:"id"`
+	Title          string    `json:"title"`
+	TitleHighlight string    `json:"title_highlight"`
+	Snippet        string    `json:"snippet"`
+	Rank           float64   `json:"rank"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
 }
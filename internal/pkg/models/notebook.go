@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+type Notebook struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"user_id"`
+	ParentID  *int      `json:"parent_id"`
+	Name      string    `json:"name"`
+	Path      string    `json:"path"`
+	NoteCount int       `json:"note_count"`
+	CreatedAt time.Time `json:"created_at"`
+}
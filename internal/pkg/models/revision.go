@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// NoteRevision is the state of a note before an update. EditorID is the
+// user who made the update.
+type NoteRevision struct {
+	ID        int       `json:"id"`
+	NoteID    int       `json:"note_id"`
+	EditorID  *int      `json:"editor_id"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// Permissions a note can be shared with. Only the owner of a note can
+// delete it or change its shares.
+const (
+	PermissionRead = "read"
+	PermissionEdit = "edit"
+)
+
+// NoteShare gives the user UserID access to a note of another user.
+type NoteShare struct {
+	NoteID     int       `json:"note_id"`
+	UserID     int       `json:"user_id"`
+	Username   string    `json:"username"`
+	Permission string    `json:"permission"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// SharedNote is a note shared with the user, with the name of its owner
+// and the permission the user has.
+type SharedNote struct {
+	Note
+	Owner      string `json:"owner"`
+	Permission string `json:"permission"`
+}
+
+// ShareLink makes a note readable without an account by anyone who knows
+// its token. Only the hashes of the token and the optional password are
+// stored.
+type ShareLink struct {
+	ID           int        `json:"id"`
+	NoteID       int        `json:"note_id"`
+	TokenHash    string     `json:"-"`
+	PasswordHash string     `json:"-"`
+	HasPassword  bool       `json:"has_password"`
+	Views        int64      `json:"views"`
+	CreatedAt    time.Time  `json:"created_at"`
+	ExpiresAt    *time.Time `json:"expires_at"`
+	LastViewedAt *time.Time `json:"last_viewed_at"`
+}
+
+// PublicNote is what a share link shows of a note.
+type PublicNote struct {
+	Title     string     `json:"title"`
+	Content   string     `json:"content"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
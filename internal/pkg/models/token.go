@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// RefreshToken is the server side record of a refresh token. Only the hash
+// of the token is stored. Tokens issued by rotating each other share a
+// FamilyID, AccessJTI is the id of the access token issued together with
+// the refresh token.
+type RefreshToken struct {
+	ID        int
+	UserID    int
+	FamilyID  string
+	TokenHash string
+	AccessJTI string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+}
+
+// PersonalToken is a long-lived access token created by a user for scripts
+// and integrations. Only the hash of the token is stored; Scopes limits
+// what it may be used for and a nil ExpiresAt means it never expires.
+type PersonalToken struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"-"`
+	Name       string     `json:"name"`
+	TokenHash  string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+// PasswordReset is a single-use token sent to the user to set a new
+// password without knowing the current one. Only its hash is stored.
+type PasswordReset struct {
+	ID        int
+	UserID    int
+	TokenHash string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
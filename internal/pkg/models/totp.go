@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// TOTP is the authenticator secret of a user. Enrollment is pending until
+// EnabledAt is set; LastStep is the time step of the last accepted code,
+// which cannot be used again.
+type TOTP struct {
+	UserID    int
+	Secret    string
+	CreatedAt time.Time
+	EnabledAt *time.Time
+	LastStep  int64
+	// RecoveryCodes is the number of unused recovery codes.
+	RecoveryCodes int
+}
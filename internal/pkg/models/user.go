@@ -1,7 +1,35 @@
 package models
 
+import "time"
+
+// Roles of users. Read-only users cannot change notes, admins may manage
+// other users.
+const (
+	RoleUser     = "user"
+	RoleAdmin    = "admin"
+	RoleReadOnly = "read-only"
+)
+
+var Roles = []string{RoleUser, RoleAdmin, RoleReadOnly}
+
 type User struct {
 	ID       int    `json:"id"`
 	Username string `json:"username"`
 	Password string `json:"-"`
+	// Email is optional and only used to deliver password reset tokens.
+	Email string `json:"email,omitempty"`
+	Role  string `json:"role"`
+	// DisabledAt is set while the account is disabled, its tokens are
+	// then rejected and it cannot log in.
+	DisabledAt *time.Time `json:"disabled_at,omitempty"`
+}
+
+// UserStats is a user with the amount of data they store, as shown to
+// administrators. StorageBytes counts the titles and contents of all notes,
+// including trashed notes and revisions.
+type UserStats struct {
+	User
+	NoteCount    int   `json:"note_count"`
+	TrashCount   int   `json:"trash_count"`
+	StorageBytes int64 `json:"storage_bytes"`
 }
@@ -0,0 +1,26 @@
+// Package notify delivers messages such as password reset tokens to users.
+package notify
+
+import (
+	"context"
+	l "noteserver/internal/pkg/logger"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Notifier interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// LogNotifier writes messages to the log instead of delivering them. It is
+// meant for local development, where reset tokens can be read from the log.
+type LogNotifier struct{}
+
+func (LogNotifier) Send(ctx context.Context, message Message) error {
+	l.Logger.Info("Notification to ", message.To, ": ", message.Subject, "\n", message.Body)
+	return nil
+}
@@ -0,0 +1,141 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSMTP is an SMTP server that accepts one message and records the
+// commands and the data it received.
+type fakeSMTP struct {
+	listener net.Listener
+	commands []string
+	data     string
+	done     chan struct{}
+}
+
+func newFakeSMTP(t *testing.T) *fakeSMTP {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &fakeSMTP{listener: listener, done: make(chan struct{})}
+	go server.serve()
+	t.Cleanup(func() { listener.Close() })
+	return server
+}
+
+func (s *fakeSMTP) serve() {
+	defer close(s.done)
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 localhost ESMTP")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.TrimRight(line, "\r\n")
+		s.commands = append(s.commands, command)
+		switch verb := strings.ToUpper(strings.Fields(command + " ")[0]); verb {
+		case "EHLO":
+			reply("250-localhost")
+			reply("250 AUTH PLAIN")
+		case "AUTH":
+			reply("235 Authenticated")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			s.data = data.String()
+			reply("250 Queued")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func TestSMTPNotifier(t *testing.T) {
+	server := newFakeSMTP(t)
+	notifier := &SMTPNotifier{
+		Addr:     server.listener.Addr().String(),
+		From:     "noteserver@example.com",
+		Username: "mailer",
+		Password: "secret",
+	}
+	err := notifier.Send(context.Background(), Message{
+		To:      "alice@example.com",
+		Subject: "Reset your NoteServer password",
+		Body:    "Hello alice,\n\nuse this token.\n",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-server.done
+
+	credentials := base64.StdEncoding.EncodeToString([]byte("\x00mailer\x00secret"))
+	want := []string{
+		"EHLO localhost",
+		"AUTH PLAIN " + credentials,
+		"MAIL FROM:<noteserver@example.com>",
+		"RCPT TO:<alice@example.com>",
+		"DATA",
+		"QUIT",
+	}
+	if got := strings.Join(server.commands, "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("commands:\n%s\nwant:\n%s", got, strings.Join(want, "\n"))
+	}
+
+	for _, header := range []string{
+		"From: noteserver@example.com\r\n",
+		"To: alice@example.com\r\n",
+		"Subject: Reset your NoteServer password\r\n",
+		"Content-Type: text/plain; charset=utf-8\r\n",
+	} {
+		if !strings.Contains(server.data, header) {
+			t.Errorf("message lacks %q:\n%s", header, server.data)
+		}
+	}
+	if !strings.HasSuffix(server.data, "\r\n\r\nHello alice,\r\n\r\nuse this token.\r\n") {
+		t.Errorf("message body not in CRLF lines:\n%q", server.data)
+	}
+}
+
+func TestSMTPNotifierRefused(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	notifier := &SMTPNotifier{Addr: addr, From: "noteserver@example.com"}
+	if err := notifier.Send(context.Background(), Message{To: "alice@example.com"}); err == nil {
+		t.Error("sending without a server succeeded")
+	}
+}
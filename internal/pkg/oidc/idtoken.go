@@ -0,0 +1,102 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"noteserver/internal/pkg/jwtkeys"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// clockSkew is how far the clocks of the provider and the server may
+// disagree.
+const clockSkew = time.Minute
+
+// verify checks the signature and the claims of an ID token (OpenID
+// Connect Core, section 3.1.3.7) and maps the claims to an identity.
+func (p *Provider) verify(ctx context.Context, rawToken, nonce string) (*Identity, error) {
+	keys, err := p.signingKeys(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	parser := jwt.Parser{SkipClaimsValidation: true}
+	token, err := parser.Parse(rawToken, keys.Keyfunc)
+	if err != nil && errors.Is(unwrapValidation(err), jwtkeys.ErrUnknownKey) {
+		// The provider may have rotated its keys.
+		if keys, err = p.signingKeys(ctx, true); err != nil {
+			return nil, err
+		}
+		token, err = parser.Parse(rawToken, keys.Keyfunc)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID token: %w", err)
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, errors.New("invalid ID token claims")
+	}
+
+	now := time.Now()
+	switch {
+	case !claims.VerifyIssuer(p.Issuer, true):
+		return nil, errors.New("ID token has the wrong issuer")
+	case !hasAudience(claims, p.ClientID):
+		return nil, errors.New("ID token is not meant for this client")
+	case !claims.VerifyExpiresAt(now.Add(-clockSkew).Unix(), true):
+		return nil, errors.New("ID token has expired")
+	case !claims.VerifyIssuedAt(now.Add(clockSkew).Unix(), false):
+		return nil, errors.New("ID token is issued in the future")
+	case stringClaim(claims, "nonce") != nonce:
+		return nil, errors.New("ID token has the wrong nonce")
+	}
+
+	identity := &Identity{
+		Subject:  stringClaim(claims, "sub"),
+		Username: stringClaim(claims, p.Claims.Username),
+	}
+	if identity.Subject == "" {
+		return nil, errors.New("ID token has no subject")
+	}
+	if verified, _ := claims[p.Claims.EmailVerified].(bool); verified {
+		identity.Email = stringClaim(claims, p.Claims.Email)
+	}
+	return identity, nil
+}
+
+// hasAudience reports whether the token is issued to the client. Tokens
+// for several audiences must name the client as authorized party.
+func hasAudience(claims jwt.MapClaims, clientID string) bool {
+	switch aud := claims["aud"].(type) {
+	case string:
+		return aud == clientID
+	case []interface{}:
+		found := false
+		for _, value := range aud {
+			if value == clientID {
+				found = true
+			}
+		}
+		if len(aud) > 1 {
+			return found && stringClaim(claims, "azp") == clientID
+		}
+		return found
+	}
+	return false
+}
+
+func stringClaim(claims jwt.MapClaims, name string) string {
+	value, _ := claims[name].(string)
+	return value
+}
+
+// unwrapValidation returns the error of the key function, which jwt-go
+// wraps in a ValidationError.
+func unwrapValidation(err error) error {
+	var validation *jwt.ValidationError
+	if errors.As(err, &validation) && validation.Inner != nil {
+		return validation.Inner
+	}
+	return err
+}
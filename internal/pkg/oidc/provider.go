@@ -0,0 +1,284 @@
+// Package oidc signs users in with an OpenID Connect identity provider
+// using the authorization code flow with PKCE (RFC 7636).
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"noteserver/internal/pkg/jwtkeys"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxResponseSize bounds what is read from the identity provider.
+	maxResponseSize = 1 << 20
+	// keyRefreshInterval limits how often the keys are fetched again for
+	// tokens signed with an unknown key.
+	keyRefreshInterval = time.Minute
+)
+
+var defaultScopes = []string{"openid", "profile", "email"}
+
+// Config describes an identity provider. Claims maps claims of the ID
+// token to user attributes. AutoProvision allows to create an account for
+// identities that are not linked to one yet.
+type Config struct {
+	Name          string       `json:"name"`
+	Issuer        string       `json:"issuer"`
+	ClientID      string       `json:"client_id"`
+	ClientSecret  string       `json:"client_secret"`
+	RedirectURL   string       `json:"redirect_url"`
+	Scopes        []string     `json:"scopes"`
+	AutoProvision bool         `json:"auto_provision"`
+	Claims        ClaimMapping `json:"claims"`
+}
+
+// ClaimMapping names the claims that hold the username, the email address
+// and whether the address has been verified. Empty names select the
+// standard claims.
+type ClaimMapping struct {
+	Username      string `json:"username"`
+	Email         string `json:"email"`
+	EmailVerified string `json:"email_verified"`
+}
+
+// Identity is a user as authenticated by the identity provider. Email is
+// only set when the provider has verified the address.
+type Identity struct {
+	Subject  string
+	Username string
+	Email    string
+}
+
+type metadata struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	JWKSURI               string   `json:"jwks_uri"`
+	TokenAuthMethods      []string `json:"token_endpoint_auth_methods_supported"`
+}
+
+// Provider talks to one identity provider. Its metadata is discovered on
+// first use and its keys are fetched again when they are rotated.
+type Provider struct {
+	Config
+	client *http.Client
+
+	mu          sync.Mutex
+	metadata    *metadata
+	keys        *jwtkeys.KeySet
+	keysFetched time.Time
+}
+
+func NewProvider(config Config, client *http.Client) (*Provider, error) {
+	switch {
+	case config.Name == "":
+		return nil, errors.New("provider name is required")
+	case config.Issuer == "":
+		return nil, fmt.Errorf("provider %q: issuer is required", config.Name)
+	case config.ClientID == "":
+		return nil, fmt.Errorf("provider %q: client_id is required", config.Name)
+	case config.RedirectURL == "":
+		return nil, fmt.Errorf("provider %q: redirect_url is required", config.Name)
+	}
+	if len(config.Scopes) == 0 {
+		config.Scopes = defaultScopes
+	}
+	if config.Claims.Username == "" {
+		config.Claims.Username = "preferred_username"
+	}
+	if config.Claims.Email == "" {
+		config.Claims.Email = "email"
+	}
+	if config.Claims.EmailVerified == "" {
+		config.Claims.EmailVerified = "email_verified"
+	}
+	return &Provider{Config: config, client: client}, nil
+}
+
+// AuthCodeURL returns the address of the provider's login page. The
+// provider sends the user back to the redirect URL with the state and a
+// code that only the holder of verifier can redeem.
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	md, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	challenge := sha256.Sum256([]byte(verifier))
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.ClientID},
+		"redirect_uri":          {p.RedirectURL},
+		"scope":                 {strings.Join(p.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	separator := "?"
+	if strings.Contains(md.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return md.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// Authenticate redeems an authorization code and verifies the ID token
+// that comes with it, which must carry the nonce of the login.
+func (p *Provider) Authenticate(ctx context.Context, code, verifier, nonce string) (*Identity, error) {
+	md, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.RedirectURL},
+		"code_verifier": {verifier},
+		"client_id":     {p.ClientID},
+	}
+	basicAuth := p.ClientSecret != "" && p.supportsBasicAuth(md)
+	if p.ClientSecret != "" && !basicAuth {
+		form.Set("client_secret", p.ClientSecret)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, md.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if basicAuth {
+		req.SetBasicAuth(url.QueryEscape(p.ClientID), url.QueryEscape(p.ClientSecret))
+	}
+
+	var response struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	status, err := p.do(req, &response)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK || response.Error != "" {
+		return nil, fmt.Errorf("token request failed with status %d: %s %s", status, response.Error, response.ErrorDescription)
+	}
+	if response.IDToken == "" {
+		return nil, errors.New("token response has no id_token")
+	}
+	return p.verify(ctx, response.IDToken, nonce)
+}
+
+// supportsBasicAuth reports whether the client authenticates with HTTP
+// basic authentication, the default of the specification, rather than
+// with the secret in the request body.
+func (p *Provider) supportsBasicAuth(md *metadata) bool {
+	if len(md.TokenAuthMethods) == 0 {
+		return true
+	}
+	for _, method := range md.TokenAuthMethods {
+		if method == "client_secret_basic" {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Provider) discover(ctx context.Context) (*metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(p.Issuer, "/")+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+	var md metadata
+	status, err := p.do(req, &md)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case status != http.StatusOK:
+		return nil, fmt.Errorf("discovery failed with status %d", status)
+	case md.Issuer != p.Issuer:
+		return nil, fmt.Errorf("discovery returned issuer %q instead of %q", md.Issuer, p.Issuer)
+	case md.AuthorizationEndpoint == "" || md.TokenEndpoint == "" || md.JWKSURI == "":
+		return nil, errors.New("discovery document lacks endpoints")
+	}
+	p.metadata = &md
+	return p.metadata, nil
+}
+
+// signingKeys returns the keys of the provider, fetched again if refresh is
+// set and they have not been fetched recently.
+func (p *Provider) signingKeys(ctx context.Context, refresh bool) (*jwtkeys.KeySet, error) {
+	md, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.keys != nil && (!refresh || time.Since(p.keysFetched) < keyRefreshInterval) {
+		return p.keys, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, md.JWKSURI, nil)
+	if err != nil {
+		return nil, err
+	}
+	var jwks jwtkeys.JWKS
+	status, err := p.do(req, &jwks)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("fetching keys failed with status %d", status)
+	}
+	keys, err := jwtkeys.ParseJWKS(jwks)
+	if err != nil {
+		return nil, err
+	}
+	p.keys, p.keysFetched = keys, time.Now()
+	return keys, nil
+}
+
+// do sends a request and decodes the JSON response into v. Error
+// responses are decoded as well, if they are JSON.
+func (p *Provider) do(req *http.Request, v interface{}) (int, error) {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return 0, err
+	}
+	if err := json.Unmarshal(body, v); err != nil && resp.StatusCode == http.StatusOK {
+		return 0, fmt.Errorf("invalid response from %s: %w", req.URL.Host, err)
+	}
+	return resp.StatusCode, nil
+}
+
+// RandomString returns a random URL-safe string, as used for states,
+// nonces and PKCE verifiers.
+func RandomString() (string, error) {
+	data := make([]byte, 32)
+	if _, err := rand.Read(data); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
@@ -0,0 +1,340 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"noteserver/internal/pkg/jwtkeys"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const (
+	testClientID     = "noteserver"
+	testClientSecret = "client-secret"
+)
+
+// mockIdP is an identity provider with discovery, a JWKS and a token
+// endpoint that issues ID tokens for the codes handed out by authorize.
+type mockIdP struct {
+	*httptest.Server
+	t *testing.T
+
+	mu        sync.Mutex
+	keys      *jwtkeys.KeySet
+	jwksCalls int
+	codes     map[string]pendingCode
+	// claims adjusts the claims of the next ID tokens.
+	claims func(jwt.MapClaims)
+}
+
+type pendingCode struct {
+	challenge string
+	nonce     string
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	t.Helper()
+	idp := &mockIdP{t: t, codes: make(map[string]pendingCode)}
+	idp.rotate("key-1")
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(metadata{
+			Issuer:                idp.URL,
+			AuthorizationEndpoint: idp.URL + "/authorize",
+			TokenEndpoint:         idp.URL + "/token",
+			JWKSURI:               idp.URL + "/jwks",
+			TokenAuthMethods:      []string{"client_secret_basic"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		idp.mu.Lock()
+		defer idp.mu.Unlock()
+		idp.jwksCalls++
+		json.NewEncoder(w).Encode(idp.keys.JWKS())
+	})
+	mux.HandleFunc("/token", idp.token)
+	idp.Server = httptest.NewServer(mux)
+	t.Cleanup(idp.Close)
+	return idp
+}
+
+// rotate replaces the signing key of the provider.
+func (idp *mockIdP) rotate(kid string) {
+	idp.t.Helper()
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		idp.t.Fatal(err)
+	}
+	der, err := x509.MarshalECPrivateKey(private)
+	if err != nil {
+		idp.t.Fatal(err)
+	}
+	key, err := jwtkeys.ParsePEM(kid, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}))
+	if err != nil {
+		idp.t.Fatal(err)
+	}
+	keys := jwtkeys.NewKeySet()
+	if err := keys.Add(key); err != nil {
+		idp.t.Fatal(err)
+	}
+	if err := keys.SetSigningKey(kid); err != nil {
+		idp.t.Fatal(err)
+	}
+	idp.mu.Lock()
+	idp.keys = keys
+	idp.mu.Unlock()
+}
+
+// authorize plays the login page: it hands out a code for the PKCE
+// challenge and the nonce of an authorization URL.
+func (idp *mockIdP) authorize(authURL string) string {
+	idp.t.Helper()
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		idp.t.Fatal(err)
+	}
+	query := parsed.Query()
+	if query.Get("code_challenge_method") != "S256" || query.Get("client_id") != testClientID {
+		idp.t.Fatalf("unexpected authorization request %s", authURL)
+	}
+	code := "code-" + query.Get("state")
+	idp.mu.Lock()
+	idp.codes[code] = pendingCode{challenge: query.Get("code_challenge"), nonce: query.Get("nonce")}
+	idp.mu.Unlock()
+	return code
+}
+
+func (idp *mockIdP) token(w http.ResponseWriter, r *http.Request) {
+	fail := func(status int, code string) {
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": code})
+	}
+	clientID, secret, ok := r.BasicAuth()
+	if !ok || clientID != testClientID || secret != testClientSecret {
+		fail(http.StatusUnauthorized, "invalid_client")
+		return
+	}
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	pending, ok := idp.codes[r.PostFormValue("code")]
+	delete(idp.codes, r.PostFormValue("code"))
+	verifier := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+	if !ok || r.PostFormValue("grant_type") != "authorization_code" ||
+		base64.RawURLEncoding.EncodeToString(verifier[:]) != pending.challenge {
+		fail(http.StatusBadRequest, "invalid_grant")
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":                idp.URL,
+		"aud":                testClientID,
+		"sub":                "subject-1",
+		"nonce":              pending.nonce,
+		"iat":                now.Unix(),
+		"exp":                now.Add(5 * time.Minute).Unix(),
+		"preferred_username": "alice",
+		"email":              "alice@example.com",
+		"email_verified":     true,
+	}
+	if idp.claims != nil {
+		idp.claims(claims)
+	}
+	idToken, err := idp.keys.Sign(claims)
+	if err != nil {
+		idp.t.Error(err)
+		fail(http.StatusInternalServerError, "server_error")
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"access_token": "access", "token_type": "Bearer", "id_token": idToken})
+}
+
+func newTestProvider(t *testing.T, idp *mockIdP) *Provider {
+	t.Helper()
+	provider, err := NewProvider(Config{
+		Name:         "mock",
+		Issuer:       idp.URL,
+		ClientID:     testClientID,
+		ClientSecret: testClientSecret,
+		RedirectURL:  "https://notes.example.com/v1/oidc/mock/callback",
+	}, idp.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return provider
+}
+
+// login runs the authorization code flow; verifier replaces the PKCE
+// verifier when the code is redeemed, if it is not empty.
+func login(t *testing.T, idp *mockIdP, provider *Provider, verifier string) (*Identity, error) {
+	t.Helper()
+	ctx := context.Background()
+	state, _ := RandomString()
+	nonce, _ := RandomString()
+	original, _ := RandomString()
+	authURL, err := provider.AuthCodeURL(ctx, state, nonce, original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := idp.authorize(authURL)
+	if verifier == "" {
+		verifier = original
+	}
+	return provider.Authenticate(ctx, code, verifier, nonce)
+}
+
+func TestAuthenticate(t *testing.T) {
+	idp := newMockIdP(t)
+	provider := newTestProvider(t, idp)
+
+	identity, err := login(t, idp, provider, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Identity{Subject: "subject-1", Username: "alice", Email: "alice@example.com"}
+	if *identity != want {
+		t.Errorf("identity %+v, want %+v", *identity, want)
+	}
+
+	idp.claims = func(claims jwt.MapClaims) { claims["email_verified"] = false }
+	identity, err = login(t, idp, provider, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity.Email != "" {
+		t.Errorf("unverified email %q accepted", identity.Email)
+	}
+}
+
+func TestAuthenticateRejects(t *testing.T) {
+	tests := []struct {
+		name     string
+		claims   func(jwt.MapClaims)
+		verifier string
+		err      string
+	}{
+		{name: "wrong issuer", claims: func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }, err: "wrong issuer"},
+		{name: "wrong audience", claims: func(c jwt.MapClaims) { c["aud"] = "other-client" }, err: "not meant for this client"},
+		{name: "audiences without azp", claims: func(c jwt.MapClaims) { c["aud"] = []string{testClientID, "other-client"} }, err: "not meant for this client"},
+		{name: "wrong azp", claims: func(c jwt.MapClaims) {
+			c["aud"] = []string{testClientID, "other-client"}
+			c["azp"] = "other-client"
+		}, err: "not meant for this client"},
+		{name: "wrong nonce", claims: func(c jwt.MapClaims) { c["nonce"] = "replayed" }, err: "wrong nonce"},
+		{name: "no nonce", claims: func(c jwt.MapClaims) { delete(c, "nonce") }, err: "wrong nonce"},
+		{name: "expired", claims: func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-2 * clockSkew).Unix() }, err: "expired"},
+		{name: "no expiry", claims: func(c jwt.MapClaims) { delete(c, "exp") }, err: "expired"},
+		{name: "issued in the future", claims: func(c jwt.MapClaims) { c["iat"] = time.Now().Add(2 * clockSkew).Unix() }, err: "in the future"},
+		{name: "no subject", claims: func(c jwt.MapClaims) { delete(c, "sub") }, err: "no subject"},
+		{name: "wrong PKCE verifier", verifier: "guessed-verifier", err: "invalid_grant"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			idp := newMockIdP(t)
+			idp.claims = test.claims
+			_, err := login(t, idp, newTestProvider(t, idp), test.verifier)
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("got error %v, want one containing %q", err, test.err)
+			}
+		})
+	}
+}
+
+func TestAuthenticateAudiences(t *testing.T) {
+	idp := newMockIdP(t)
+	provider := newTestProvider(t, idp)
+	for _, aud := range []interface{}{[]string{testClientID}, []string{"other-client", testClientID}} {
+		idp.claims = func(c jwt.MapClaims) {
+			c["aud"] = aud
+			c["azp"] = testClientID
+		}
+		if _, err := login(t, idp, provider, ""); err != nil {
+			t.Errorf("aud %v: %v", aud, err)
+		}
+	}
+}
+
+func TestAuthenticateKeyRotation(t *testing.T) {
+	idp := newMockIdP(t)
+	provider := newTestProvider(t, idp)
+	if _, err := login(t, idp, provider, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// Keys fetched within keyRefreshInterval are not fetched again, so
+	// tokens with unknown keys cannot make the server hammer the provider.
+	idp.rotate("key-2")
+	if _, err := login(t, idp, provider, ""); err == nil {
+		t.Error("token of an unknown key accepted")
+	}
+	if idp.jwksCalls != 1 {
+		t.Errorf("keys fetched %d times, want 1", idp.jwksCalls)
+	}
+
+	provider.mu.Lock()
+	provider.keysFetched = time.Now().Add(-keyRefreshInterval)
+	provider.mu.Unlock()
+	if _, err := login(t, idp, provider, ""); err != nil {
+		t.Fatalf("after rotation: %v", err)
+	}
+	if idp.jwksCalls != 2 {
+		t.Errorf("keys fetched %d times, want 2", idp.jwksCalls)
+	}
+	if _, err := login(t, idp, provider, ""); err != nil {
+		t.Fatal(err)
+	}
+	if idp.jwksCalls != 2 {
+		t.Errorf("keys fetched %d times for a known key, want 2", idp.jwksCalls)
+	}
+}
+
+func TestAuthenticateForgedSignature(t *testing.T) {
+	idp := newMockIdP(t)
+	provider := newTestProvider(t, idp)
+	if _, err := login(t, idp, provider, ""); err != nil {
+		t.Fatal(err)
+	}
+	// An attacker signs with a key of their own under the id of the
+	// provider's key.
+	idp.rotate("key-1")
+	provider.mu.Lock()
+	keys := provider.keys
+	provider.mu.Unlock()
+	if _, err := login(t, idp, provider, ""); err == nil {
+		t.Error("token with a forged signature accepted")
+	}
+	if provider.keys != keys {
+		t.Error("keys fetched again for a known key id")
+	}
+}
+
+func TestAuthenticateDiscovery(t *testing.T) {
+	idp := newMockIdP(t)
+	provider, err := NewProvider(Config{
+		Name:        "mock",
+		Issuer:      idp.URL + "/",
+		ClientID:    testClientID,
+		RedirectURL: "https://notes.example.com/v1/oidc/mock/callback",
+	}, idp.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = provider.AuthCodeURL(context.Background(), "state", "nonce", "verifier")
+	if err == nil || !strings.Contains(err.Error(), "instead of") {
+		t.Errorf("discovery of another issuer: %v", err)
+	}
+}
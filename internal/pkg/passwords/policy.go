@@ -0,0 +1,70 @@
+// Package passwords decides which passwords users may choose.
+package passwords
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxLength is the number of bytes bcrypt takes into account, longer
+// passwords would be silently truncated.
+const MaxLength = 72
+
+type Policy struct {
+	// MinLength is counted in characters.
+	MinLength int
+	// common holds lowercased passwords that are too easy to guess.
+	common map[string]bool
+}
+
+// NewPolicy returns a policy that rejects the passwords listed in the file
+// at commonPath, one per line. An empty path disables the list.
+func NewPolicy(minLength int, commonPath string) (*Policy, error) {
+	policy := &Policy{MinLength: minLength, common: make(map[string]bool)}
+	if commonPath == "" {
+		return policy, nil
+	}
+	file, err := os.Open(commonPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if password := strings.TrimSpace(scanner.Text()); password != "" {
+			policy.common[strings.ToLower(password)] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// Check returns why a password is not acceptable for the user, nil if it
+// is. An empty username skips the comparison with it.
+func (p *Policy) Check(password string, username string) error {
+	switch {
+	case password == "":
+		return errors.New("Password is required")
+	case utf8.RuneCountInString(password) < p.MinLength:
+		return errors.New("Password must be at least " + strconv.Itoa(p.MinLength) + " characters long")
+	case len(password) > MaxLength:
+		return errors.New("Password must be at most " + strconv.Itoa(MaxLength) + " bytes long")
+	case username != "" && strings.EqualFold(password, username):
+		return errors.New("Password must not be the username")
+	case p.common[strings.ToLower(password)]:
+		return errors.New("Password is too common")
+	}
+	return nil
+}
+
+// Common returns the number of passwords on the list of common passwords.
+func (p *Policy) Common() int {
+	return len(p.common)
+}
@@ -0,0 +1,35 @@
+package requests
+
+import "time"
+
+type NotePatch struct {
+	Title   *string `json:"title"`
+	Content *string `json:"content"`
+}
+
+type NotePut struct {
+	Title   string `json:"title"`
+	Content string `json:"content"`
+	// NotebookID and WorkspaceID are only applied when a note is created,
+	// PUT refuses other values than the current ones. Existing notes are
+	// refiled with the move endpoint.
+	NotebookID  *int `json:"notebook_id"`
+	WorkspaceID *int `json:"workspace_id"`
+}
+
+type NoteShare struct {
+	Username   string `json:"username"`
+	Permission string `json:"permission"`
+}
+
+// ShareLinkCreate creates a public link to a note, Password and ExpiresAt
+// are optional.
+type ShareLinkCreate struct {
+	Password  string     `json:"password"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// ShareLinkAccess unlocks a share link protected by a password.
+type ShareLinkAccess struct {
+	Password string `json:"password"`
+}
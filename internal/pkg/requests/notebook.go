@@ -0,0 +1,43 @@
+package requests
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// OptionalInt tells an omitted field apart from an explicit null, which
+// PATCH requests need to clear a value.
+type OptionalInt struct {
+	Set   bool
+	Value *int
+}
+
+func (o *OptionalInt) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	if bytes.Equal(data, []byte("null")) {
+		o.Value = nil
+		return nil
+	}
+	var value int
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	o.Value = &value
+	return nil
+}
+
+type NotebookCreate struct {
+	Name     string `json:"name"`
+	ParentID *int   `json:"parent_id"`
+}
+
+type NotebookPatch struct {
+	Name     *string     `json:"name"`
+	ParentID OptionalInt `json:"parent_id"`
+}
+
+// NoteMove files a note in a notebook, a null notebook_id leaves it
+// unfiled.
+type NoteMove struct {
+	NotebookID *int `json:"notebook_id"`
+}
@@ -0,0 +1,9 @@
+package requests
+
+type NoteTags struct {
+	Tags []string `json:"tags"`
+}
+
+type TagRename struct {
+	Name string `json:"name"`
+}
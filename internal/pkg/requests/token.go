@@ -0,0 +1,22 @@
+package requests
+
+import "time"
+
+type RefreshToken struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Logout revokes the access token of the request and, if given, the family
+// of the refresh token; All revokes every token of the user.
+type Logout struct {
+	RefreshToken string `json:"refresh_token"`
+	All          bool   `json:"all"`
+}
+
+// PersonalTokenCreate creates a personal access token, ExpiresAt is
+// optional.
+type PersonalTokenCreate struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
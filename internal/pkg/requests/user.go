@@ -0,0 +1,71 @@
+package requests
+
+type Login struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// Register creates an account, InviteCode is required when registration
+// is invite only.
+type Register struct {
+	Username   string `json:"username"`
+	Password   string `json:"password"`
+	Email      string `json:"email"`
+	InviteCode string `json:"invite_code"`
+}
+
+// AccountPatch changes the account, an empty Email removes the address.
+type AccountPatch struct {
+	Email *string `json:"email"`
+}
+
+type PasswordChange struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+type PasswordForgot struct {
+	Email string `json:"email"`
+}
+
+type PasswordReset struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+// LoginTwoFactor completes a login with the token returned by the first
+// step and either a TOTP code or a recovery code.
+type LoginTwoFactor struct {
+	MFAToken     string `json:"mfa_token"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+type TOTPSetup struct {
+	Password string `json:"password"`
+}
+
+type TOTPConfirm struct {
+	Code string `json:"code"`
+}
+
+// TwoFactorReauth confirms a sensitive change with the password and a TOTP
+// code or a recovery code.
+type TwoFactorReauth struct {
+	Password     string `json:"password"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// IdentityLink confirms linking an identity with the password of the user,
+// if the account has one.
+type IdentityLink struct {
+	Password string `json:"password"`
+}
+
+// AdminUserPatch changes the role of a user or disables and enables the
+// account. Fields that are not set are left alone.
+type AdminUserPatch struct {
+	Role     *string `json:"role"`
+	Disabled *bool   `json:"disabled"`
+}
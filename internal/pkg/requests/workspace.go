@@ -0,0 +1,12 @@
+package requests
+
+// Workspace creates or renames a workspace.
+type Workspace struct {
+	Name string `json:"name"`
+}
+
+// WorkspaceMember adds a member to a workspace or changes their role.
+type WorkspaceMember struct {
+	Username string `json:"username"`
+	Role     string `json:"role"`
+}
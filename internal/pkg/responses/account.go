@@ -0,0 +1,59 @@
+package responses
+
+import "noteserver/internal/pkg/models"
+
+type Account struct {
+	Status           string       `json:"status"`
+	Message          string       `json:"message"`
+	User             *models.User `json:"user"`
+	TwoFactorEnabled bool         `json:"two_factor_enabled"`
+}
+
+type Invites struct {
+	Status  string          `json:"status"`
+	Message string          `json:"message"`
+	Invites []models.Invite `json:"invites"`
+}
+
+// Invite carries the code only when the invite is created.
+type Invite struct {
+	Status  string         `json:"status"`
+	Message string         `json:"message"`
+	Code    string         `json:"code"`
+	Invite  *models.Invite `json:"invite"`
+}
+
+// TOTPSetup carries the secret of a pending enrollment. ProvisioningURI is
+// the otpauth URI to show as a QR code.
+type TOTPSetup struct {
+	Status          string `json:"status"`
+	Message         string `json:"message"`
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type RecoveryCodes struct {
+	Status        string   `json:"status"`
+	Message       string   `json:"message"`
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type Identities struct {
+	Status     string            `json:"status"`
+	Message    string            `json:"message"`
+	Identities []models.Identity `json:"identities"`
+}
+
+type Identity struct {
+	Status   string           `json:"status"`
+	Message  string           `json:"message"`
+	Identity *models.Identity `json:"identity"`
+}
+
+// AuthorizationURL is the login page of an identity provider to send the
+// user to.
+type AuthorizationURL struct {
+	Status           string `json:"status"`
+	Message          string `json:"message"`
+	AuthorizationURL string `json:"authorization_url"`
+}
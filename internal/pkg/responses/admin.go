@@ -0,0 +1,21 @@
+package responses
+
+import "noteserver/internal/pkg/models"
+
+type AdminUsers struct {
+	Status  string             `json:"status"`
+	Message string             `json:"message"`
+	Users   []models.UserStats `json:"users"`
+}
+
+type AdminUser struct {
+	Status  string            `json:"status"`
+	Message string            `json:"message"`
+	User    *models.UserStats `json:"user"`
+}
+
+type PurgedNotes struct {
+	Status       string `json:"status"`
+	Message      string `json:"message"`
+	DeletedNotes int64  `json:"deleted_notes"`
+}
@@ -3,7 +3,8 @@ package responses
 import "noteserver/internal/pkg/models"
 
 type AllNotes struct {
-	Status  string        `json:"status"`
-	Message string        `json:"message"`
-	Notes   []models.Note `json:"notes"`
+	Status     string        `json:"status"`
+	Message    string        `json:"message"`
+	Notes      []models.Note `json:"notes"`
+	NextCursor string        `json:"next_cursor,omitempty"`
 }
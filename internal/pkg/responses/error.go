@@ -0,0 +1,11 @@
+package responses
+
+type Error struct {
+	Status  string            `json:"status"`
+	Message string            `json:"message"`
+	Errors  map[string]string `json:"errors,omitempty"`
+}
+
+func NewError(message string) Error {
+	return Error{Status: "error", Message: message}
+}
@@ -0,0 +1,10 @@
+package responses
+
+import "noteserver/internal/pkg/models"
+
+type NoteSummaries struct {
+	Status     string               `json:"status"`
+	Message    string               `json:"message"`
+	Notes      []models.NoteSummary `json:"notes"`
+	NextCursor string               `json:"next_cursor,omitempty"`
+}
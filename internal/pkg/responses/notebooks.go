@@ -0,0 +1,17 @@
+package responses
+
+import "noteserver/internal/pkg/models"
+
+type Notebooks struct {
+	Status    string            `json:"status"`
+	Message   string            `json:"message"`
+	Notebooks []models.Notebook `json:"notebooks"`
+}
+
+type Notebook struct {
+	Status   string           `json:"status"`
+	Message  string           `json:"message"`
+	Notebook *models.Notebook `json:"notebook"`
+	// Descendants lists the notebooks nested in Notebook at any depth.
+	Descendants []models.Notebook `json:"descendants"`
+}
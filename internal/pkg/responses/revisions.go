@@ -0,0 +1,30 @@
+package responses
+
+import (
+	"noteserver/internal/pkg/diff"
+	"noteserver/internal/pkg/models"
+)
+
+type Revisions struct {
+	Status    string                `json:"status"`
+	Message   string                `json:"message"`
+	Revisions []models.NoteRevision `json:"revisions"`
+}
+
+type Revision struct {
+	Status   string               `json:"status"`
+	Message  string               `json:"message"`
+	Revision *models.NoteRevision `json:"revision"`
+}
+
+type RevisionDiff struct {
+	Status       string      `json:"status"`
+	Message      string      `json:"message"`
+	From         string      `json:"from"`
+	To           string      `json:"to"`
+	TitleChanged bool        `json:"title_changed"`
+	FromTitle    string      `json:"from_title"`
+	ToTitle      string      `json:"to_title"`
+	Lines        []diff.Line `json:"lines"`
+	Unified      string      `json:"unified"`
+}
@@ -0,0 +1,9 @@
+package responses
+
+import "noteserver/internal/pkg/models"
+
+type SearchNotes struct {
+	Status  string                    `json:"status"`
+	Message string                    `json:"message"`
+	Results []models.NoteSearchResult `json:"results"`
+}
@@ -0,0 +1,43 @@
+package responses
+
+import "noteserver/internal/pkg/models"
+
+type NoteShares struct {
+	Status  string             `json:"status"`
+	Message string             `json:"message"`
+	Shares  []models.NoteShare `json:"shares"`
+}
+
+type NoteShare struct {
+	Status  string            `json:"status"`
+	Message string            `json:"message"`
+	Share   *models.NoteShare `json:"share"`
+}
+
+type SharedNotes struct {
+	Status  string              `json:"status"`
+	Message string              `json:"message"`
+	Notes   []models.SharedNote `json:"notes"`
+}
+
+type ShareLinks struct {
+	Status  string             `json:"status"`
+	Message string             `json:"message"`
+	Links   []models.ShareLink `json:"links"`
+}
+
+// ShareLink carries the token and the address of the link only when it is
+// created, they cannot be read again later.
+type ShareLink struct {
+	Status  string            `json:"status"`
+	Message string            `json:"message"`
+	Token   string            `json:"token"`
+	URL     string            `json:"url"`
+	Link    *models.ShareLink `json:"link"`
+}
+
+type PublicNote struct {
+	Status  string             `json:"status"`
+	Message string             `json:"message"`
+	Note    *models.PublicNote `json:"note"`
+}
@@ -0,0 +1,11 @@
+package responses
+
+// Status reports the outcome of requests that return nothing else.
+type Status struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+func NewStatus(message string) Status {
+	return Status{Status: "success", Message: message}
+}
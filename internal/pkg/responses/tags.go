@@ -0,0 +1,9 @@
+package responses
+
+import "noteserver/internal/pkg/models"
+
+type Tags struct {
+	Status  string       `json:"status"`
+	Message string       `json:"message"`
+	Tags    []models.Tag `json:"tags"`
+}
@@ -0,0 +1,34 @@
+package responses
+
+import "noteserver/internal/pkg/models"
+
+type Token struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	// ExpiresIn is the lifetime of the access token in seconds.
+	ExpiresIn int `json:"expires_in"`
+}
+
+// MFAChallenge is returned by login instead of a Token when the user has
+// two-factor authentication enabled.
+type MFAChallenge struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+	// ExpiresIn is the lifetime of MFAToken in seconds.
+	ExpiresIn int `json:"expires_in"`
+}
+
+type PersonalTokens struct {
+	Status  string                 `json:"status"`
+	Message string                 `json:"message"`
+	Tokens  []models.PersonalToken `json:"tokens"`
+}
+
+// PersonalToken carries the token itself only when it is created, it cannot
+// be read again later.
+type PersonalToken struct {
+	Status        string                `json:"status"`
+	Message       string                `json:"message"`
+	Token         string                `json:"token"`
+	PersonalToken *models.PersonalToken `json:"personal_token"`
+}
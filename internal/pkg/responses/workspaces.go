@@ -0,0 +1,22 @@
+package responses
+
+import "noteserver/internal/pkg/models"
+
+type Workspaces struct {
+	Status     string             `json:"status"`
+	Message    string             `json:"message"`
+	Workspaces []models.Workspace `json:"workspaces"`
+}
+
+type Workspace struct {
+	Status    string                   `json:"status"`
+	Message   string                   `json:"message"`
+	Workspace *models.Workspace        `json:"workspace"`
+	Members   []models.WorkspaceMember `json:"members"`
+}
+
+type WorkspaceMember struct {
+	Status  string                  `json:"status"`
+	Message string                  `json:"message"`
+	Member  *models.WorkspaceMember `json:"member"`
+}
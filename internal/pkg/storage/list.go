@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"noteserver/internal/pkg/models"
+	"time"
+)
+
+const (
+	SortCreated = "created"
+	SortUpdated = "updated"
+	SortTitle   = "title"
+
+	// SnippetLength is the number of characters of content loaded for
+	// summary listings.
+	SnippetLength = 200
+)
+
+var ErrInvalidCursor = errors.New("Invalid cursor")
+
+type ListOptions struct {
+	// Limit caps the number of returned notes, zero means no limit.
+	Limit         int
+	Cursor        *Cursor
+	Sort          string
+	Descending    bool
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	UpdatedAfter  *time.Time
+	UpdatedBefore *time.Time
+	// Tags keeps notes having all of the tags, or any of them when
+	// AnyTag is set. Names must be lowercase and unique.
+	Tags   []string
+	AnyTag bool
+	// NotebookIDs keeps notes filed in any of the notebooks.
+	NotebookIDs []int
+	// WorkspaceID lists the notes of a workspace instead of the personal
+	// notes of the user.
+	WorkspaceID *int
+	// SummaryOnly loads at most SnippetLength+1 characters of content.
+	SummaryOnly bool
+}
+
+// Cursor points at the last note of a page. Value holds the sort key of
+// that note so the next page can continue after it (keyset pagination).
+type Cursor struct {
+	Sort       string `json:"s"`
+	Descending bool   `json:"d"`
+	Value      string `json:"v"`
+	ID         int    `json:"id"`
+}
+
+type NotePage struct {
+	Notes []models.Note
+	Next  *Cursor
+}
+
+func NewCursor(opts ListOptions, note models.Note) *Cursor {
+	cursor := &Cursor{Sort: opts.Sort, Descending: opts.Descending, ID: note.ID}
+	switch opts.Sort {
+	case SortUpdated:
+		cursor.Value = note.UpdatedAt.UTC().Format(time.RFC3339Nano)
+	case SortTitle:
+		cursor.Value = note.Title
+	default:
+		cursor.Value = note.CreatedAt.UTC().Format(time.RFC3339Nano)
+	}
+	return cursor
+}
+
+func (c *Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func (c *Cursor) Time() (time.Time, error) {
+	value, err := time.Parse(time.RFC3339Nano, c.Value)
+	if err != nil {
+		return time.Time{}, ErrInvalidCursor
+	}
+	return value, nil
+}
+
+// DecodeCursor parses a cursor and checks that it was issued for the same
+// sort order as the current request.
+func DecodeCursor(encoded string, opts ListOptions) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor Cursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if cursor.Sort != opts.Sort || cursor.Descending != opts.Descending {
+		return nil, ErrInvalidCursor
+	}
+	if cursor.Sort != SortTitle {
+		if _, err := cursor.Time(); err != nil {
+			return nil, err
+		}
+	}
+	return &cursor, nil
+}
@@ -0,0 +1,103 @@
+package memory
+
+import (
+	"context"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/storage"
+	"sort"
+	"strings"
+	"time"
+)
+
+func (s *Store) ListUsers(ctx context.Context, query storage.UserQuery) ([]models.UserStats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	search := strings.ToLower(query.Search)
+	users := []models.UserStats{}
+	for _, user := range s.users {
+		if search != "" && !strings.Contains(strings.ToLower(user.Username), search) &&
+			!strings.Contains(strings.ToLower(user.Email), search) {
+			continue
+		}
+		users = append(users, s.userStats(user))
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].ID < users[j].ID
+	})
+
+	if query.Offset >= len(users) {
+		return []models.UserStats{}, nil
+	}
+	users = users[query.Offset:]
+	if query.Limit > 0 && query.Limit < len(users) {
+		users = users[:query.Limit]
+	}
+	return users, nil
+}
+
+func (s *Store) GetUserStats(ctx context.Context, userID int) (*models.UserStats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, ok := s.users[userID]
+	if !ok {
+		return nil, nil
+	}
+	stats := s.userStats(user)
+	return &stats, nil
+}
+
+// userStats counts the notes of a user, the caller must hold the lock.
+func (s *Store) userStats(user models.User) models.UserStats {
+	stats := models.UserStats{User: user}
+	for _, note := range s.notes {
+		if note.UserID != user.ID {
+			continue
+		}
+		if note.DeletedAt == nil {
+			stats.NoteCount++
+		} else {
+			stats.TrashCount++
+		}
+		stats.StorageBytes += int64(len(note.Title) + len(note.Content))
+	}
+	for _, revision := range s.revisions {
+		if note, ok := s.notes[revision.NoteID]; ok && note.UserID == user.ID {
+			stats.StorageBytes += int64(len(revision.Title) + len(revision.Content))
+		}
+	}
+	return stats
+}
+
+func (s *Store) SetUserRole(ctx context.Context, userID int, role string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userID]
+	if !ok {
+		return storage.ErrUserNotFound
+	}
+	user.Role = role
+	s.users[userID] = user
+	return nil
+}
+
+func (s *Store) SetUserDisabled(ctx context.Context, userID int, disabled bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userID]
+	if !ok {
+		return storage.ErrUserNotFound
+	}
+	switch {
+	case !disabled:
+		user.DisabledAt = nil
+	case user.DisabledAt == nil:
+		now := time.Now()
+		user.DisabledAt = &now
+	}
+	s.users[userID] = user
+	return nil
+}
@@ -0,0 +1,119 @@
+package memory
+
+import (
+	"context"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/storage"
+	"sort"
+	"time"
+)
+
+func (s *Store) GetUserByIdentity(ctx context.Context, provider string, subject string) (*models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, identity := range s.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			user, ok := s.users[identity.UserID]
+			if !ok {
+				return nil, nil
+			}
+			return &user, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *Store) ListIdentities(ctx context.Context, userID int) ([]models.Identity, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	identities := []models.Identity{}
+	for _, identity := range s.identities {
+		if identity.UserID == userID {
+			identities = append(identities, identity)
+		}
+	}
+	sort.Slice(identities, func(i, j int) bool {
+		return identities[i].Provider < identities[j].Provider
+	})
+	return identities, nil
+}
+
+func (s *Store) LinkIdentity(ctx context.Context, identity *models.Identity) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.linkIdentity(identity)
+}
+
+func (s *Store) RegisterWithIdentity(ctx context.Context, user *models.User, identity *models.Identity) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.identityTaken(identity.Provider, identity.Subject, 0) {
+		return storage.ErrIdentityExists
+	}
+	if err := s.saveUser(user); err != nil {
+		return err
+	}
+	identity.UserID = user.ID
+	return s.linkIdentity(identity)
+}
+
+// linkIdentity adds an identity, the caller must hold the write lock.
+func (s *Store) linkIdentity(identity *models.Identity) error {
+	if s.identityTaken(identity.Provider, identity.Subject, identity.UserID) {
+		return storage.ErrIdentityExists
+	}
+	s.nextIdentityID++
+	identity.ID = s.nextIdentityID
+	identity.CreatedAt = time.Now()
+	s.identities[identity.ID] = *identity
+	return nil
+}
+
+// identityTaken reports whether the subject is linked to a user, or the
+// user to a subject of the provider, the caller must hold the lock.
+func (s *Store) identityTaken(provider string, subject string, userID int) bool {
+	for _, identity := range s.identities {
+		if identity.Provider == provider && (identity.Subject == subject || identity.UserID == userID) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Store) UnlinkIdentity(ctx context.Context, userID int, provider string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, identity := range s.identities {
+		if identity.UserID == userID && identity.Provider == provider {
+			delete(s.identities, id)
+			return nil
+		}
+	}
+	return storage.ErrIdentityNotFound
+}
+
+func (s *Store) CreateOIDCLogin(ctx context.Context, login *models.OIDCLogin) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	login.CreatedAt = time.Now()
+	s.oidcLogins[login.StateHash] = *login
+	return nil
+}
+
+func (s *Store) ConsumeOIDCLogin(ctx context.Context, stateHash string, now time.Time) (*models.OIDCLogin, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	login, ok := s.oidcLogins[stateHash]
+	if !ok || !login.ExpiresAt.After(now) {
+		return nil, storage.ErrOIDCLoginNotFound
+	}
+	delete(s.oidcLogins, stateHash)
+	return &login, nil
+}
@@ -0,0 +1,64 @@
+package memory
+
+import (
+	"context"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/storage"
+	"sort"
+	"time"
+)
+
+func (s *Store) CreateInvite(ctx context.Context, invite *models.Invite) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextInviteID++
+	invite.ID = s.nextInviteID
+	invite.CreatedAt = time.Now()
+	s.invites[invite.ID] = *invite
+	return nil
+}
+
+func (s *Store) ListInvites(ctx context.Context, userID int) ([]models.Invite, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	invites := []models.Invite{}
+	for _, invite := range s.invites {
+		if invite.CreatedBy == userID {
+			invites = append(invites, invite)
+		}
+	}
+	sort.Slice(invites, func(i, j int) bool { return invites[i].ID < invites[j].ID })
+	return invites, nil
+}
+
+func (s *Store) DeleteInvite(ctx context.Context, userID int, inviteID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	invite, ok := s.invites[inviteID]
+	if !ok || invite.CreatedBy != userID {
+		return storage.ErrInviteNotFound
+	}
+	delete(s.invites, inviteID)
+	return nil
+}
+
+func (s *Store) RegisterWithInvite(ctx context.Context, user models.User, codeHash string, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, invite := range s.invites {
+		if invite.CodeHash != codeHash || invite.UsedAt != nil || !invite.ExpiresAt.After(now) {
+			continue
+		}
+		if err := s.saveUser(&user); err != nil {
+			return err
+		}
+		invite.UsedAt = &now
+		s.invites[id] = invite
+		return nil
+	}
+	return storage.ErrInviteNotFound
+}
@@ -0,0 +1,232 @@
+package memory
+
+import (
+	"context"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/storage"
+	"strings"
+	"time"
+)
+
+func (s *Store) CreateNotebook(ctx context.Context, user *models.User, notebook *models.Notebook) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.ownsNotebook(user, notebook.ParentID) {
+		return 0, storage.ErrNotebookNotFound
+	}
+	if s.notebookNameTaken(user, notebook.ParentID, notebook.Name, 0) {
+		return 0, storage.ErrNotebookExists
+	}
+	s.nextBookID++
+	s.notebooks[s.nextBookID] = models.Notebook{
+		ID:        s.nextBookID,
+		UserID:    user.ID,
+		ParentID:  notebook.ParentID,
+		Name:      notebook.Name,
+		CreatedAt: time.Now(),
+	}
+	return s.nextBookID, nil
+}
+
+func (s *Store) ListNotebooks(ctx context.Context, user *models.User) ([]models.Notebook, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.userNotebooks(user), nil
+}
+
+func (s *Store) UpdateNotebook(ctx context.Context, user *models.User, notebook *models.Notebook) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.notebooks[notebook.ID]
+	if !ok || existing.UserID != user.ID {
+		return storage.ErrNotebookNotFound
+	}
+	if !s.ownsNotebook(user, notebook.ParentID) {
+		return storage.ErrNotebookNotFound
+	}
+	if notebook.ParentID != nil {
+		for _, id := range storage.NotebookSubtree(s.userNotebooks(user), notebook.ID) {
+			if id == *notebook.ParentID {
+				return storage.ErrNotebookCycle
+			}
+		}
+	}
+	if s.notebookNameTaken(user, notebook.ParentID, notebook.Name, notebook.ID) {
+		return storage.ErrNotebookExists
+	}
+	existing.Name = notebook.Name
+	existing.ParentID = notebook.ParentID
+	s.notebooks[notebook.ID] = existing
+	return nil
+}
+
+func (s *Store) DeleteNotebook(ctx context.Context, user *models.User, notebookID int, mode storage.NotebookDeleteMode) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deleted, ok := s.notebooks[notebookID]
+	if !ok || deleted.UserID != user.ID {
+		return storage.ErrNotebookNotFound
+	}
+	subtree := storage.NotebookSubtree(s.userNotebooks(user), notebookID)
+
+	switch mode {
+	case storage.DeleteCascade:
+		now := time.Now()
+		for id, note := range s.notes {
+			if _, ok := s.activeNote(user, id); ok && inNotebooks(note, subtree) {
+				note.DeletedAt = &now
+				s.notes[id] = note
+			}
+		}
+	case storage.DeleteReparent:
+		for _, notebook := range s.notebooks {
+			if notebook.ParentID != nil && *notebook.ParentID == notebookID &&
+				s.notebookNameTaken(user, deleted.ParentID, notebook.Name, notebook.ID) {
+				return storage.ErrNotebookExists
+			}
+		}
+		for id, notebook := range s.notebooks {
+			if notebook.ParentID != nil && *notebook.ParentID == notebookID {
+				notebook.ParentID = deleted.ParentID
+				s.notebooks[id] = notebook
+			}
+		}
+		for id, note := range s.notes {
+			if inNotebooks(note, []int{notebookID}) {
+				note.NotebookID = deleted.ParentID
+				note.Version++
+				note.UpdatedAt = time.Now()
+				s.notes[id] = note
+			}
+		}
+		subtree = []int{notebookID}
+	default:
+		if len(subtree) > 1 {
+			return storage.ErrNotebookNotEmpty
+		}
+		for _, note := range s.notes {
+			if note.DeletedAt == nil && inNotebooks(note, subtree) {
+				return storage.ErrNotebookNotEmpty
+			}
+		}
+	}
+
+	for id, note := range s.notes {
+		if inNotebooks(note, subtree) {
+			note.NotebookID = nil
+			s.notes[id] = note
+		}
+	}
+	for _, id := range subtree {
+		delete(s.notebooks, id)
+	}
+	return nil
+}
+
+func (s *Store) MoveNote(ctx context.Context, user *models.User, noteID int, notebookID *int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.ownsNotebook(user, notebookID) {
+		return storage.ErrNotebookNotFound
+	}
+	note, ok := s.activeNote(user, noteID)
+	if !ok {
+		return storage.ErrNoteNotFound
+	}
+	note.NotebookID = notebookID
+	note.Version++
+	note.UpdatedAt = time.Now()
+	s.notes[noteID] = note
+	return nil
+}
+
+func (s *Store) CopyNote(ctx context.Context, user *models.User, noteID int, notebookID *int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.ownsNotebook(user, notebookID) {
+		return 0, storage.ErrNotebookNotFound
+	}
+	note, ok := s.activeNote(user, noteID)
+	if !ok {
+		return 0, storage.ErrNoteNotFound
+	}
+	s.nextNoteID++
+	now := time.Now()
+	s.notes[s.nextNoteID] = models.Note{
+		ID:         s.nextNoteID,
+		UserID:     user.ID,
+		NotebookID: notebookID,
+		Title:      note.Title,
+		Content:    note.Content,
+		Version:    1,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if len(s.noteTags[noteID]) > 0 {
+		s.noteTags[s.nextNoteID] = make(map[int]bool)
+		for tagID := range s.noteTags[noteID] {
+			s.noteTags[s.nextNoteID][tagID] = true
+		}
+	}
+	return s.nextNoteID, nil
+}
+
+// userNotebooks returns the notebooks of a user with paths and note counts,
+// the caller must hold the lock.
+func (s *Store) userNotebooks(user *models.User) []models.Notebook {
+	notebooks := []models.Notebook{}
+	for _, notebook := range s.notebooks {
+		if notebook.UserID != user.ID {
+			continue
+		}
+		notebook.NoteCount = 0
+		for _, note := range s.notes {
+			if note.DeletedAt == nil && inNotebooks(note, []int{notebook.ID}) {
+				notebook.NoteCount++
+			}
+		}
+		notebooks = append(notebooks, notebook)
+	}
+	storage.SetNotebookPaths(notebooks)
+	return notebooks
+}
+
+// ownsNotebook reports whether notes can be filed in the notebook, nil
+// stands for no notebook.
+func (s *Store) ownsNotebook(user *models.User, notebookID *int) bool {
+	if notebookID == nil {
+		return true
+	}
+	notebook, ok := s.notebooks[*notebookID]
+	return ok && notebook.UserID == user.ID
+}
+
+func (s *Store) notebookNameTaken(user *models.User, parentID *int, name string, exceptID int) bool {
+	for _, notebook := range s.notebooks {
+		if notebook.UserID != user.ID || notebook.ID == exceptID || !strings.EqualFold(notebook.Name, name) {
+			continue
+		}
+		if (notebook.ParentID == nil) == (parentID == nil) && (parentID == nil || *notebook.ParentID == *parentID) {
+			return true
+		}
+	}
+	return false
+}
+
+func inNotebooks(note models.Note, notebookIDs []int) bool {
+	if note.NotebookID == nil {
+		return false
+	}
+	for _, id := range notebookIDs {
+		if *note.NotebookID == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,337 @@
+package memory
+
+import (
+	"context"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/storage"
+	"sort"
+	"strings"
+	"time"
+)
+
+func (s *Store) ReadNote(ctx context.Context, user *models.User, noteID int) (models.Note, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	note, _, ok := s.accessibleNote(user, noteID)
+	if !ok {
+		return models.Note{}, storage.ErrNoteNotFound
+	}
+	note.Tags = s.noteTagNames(noteID)
+	return note, nil
+}
+
+func (s *Store) DeleteNote(ctx context.Context, user *models.User, noteID int, version int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	note, _, ok := s.accessibleNote(user, noteID)
+	if !ok {
+		return storage.ErrNoteNotFound
+	}
+	if note.WorkspaceID == nil && note.UserID != user.ID || note.WorkspaceID != nil && !s.canWrite(note.WorkspaceID, user.ID) {
+		return storage.ErrNotNoteOwner
+	}
+	if version != 0 && version != note.Version {
+		return storage.ErrVersionMismatch
+	}
+	now := time.Now()
+	note.DeletedAt = &now
+	s.notes[noteID] = note
+	return nil
+}
+
+func (s *Store) UpdateNote(ctx context.Context, user *models.User, note *models.Note) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, permission, ok := s.accessibleNote(user, note.ID)
+	if !ok {
+		return storage.ErrNoteNotFound
+	}
+	if permission != models.PermissionEdit {
+		return storage.ErrNoteReadOnly
+	}
+	if note.Version != 0 && note.Version != existing.Version {
+		return storage.ErrVersionMismatch
+	}
+	now := time.Now()
+	s.nextRevID++
+	editorID := user.ID
+	s.revisions[s.nextRevID] = models.NoteRevision{
+		ID:        s.nextRevID,
+		NoteID:    existing.ID,
+		EditorID:  &editorID,
+		Title:     existing.Title,
+		Content:   existing.Content,
+		CreatedAt: now,
+	}
+
+	existing.Title = note.Title
+	existing.Content = note.Content
+	existing.UpdatedAt = now
+	existing.Version++
+	s.notes[note.ID] = existing
+	note.Version, note.UpdatedAt = existing.Version, now
+	return nil
+}
+
+func (s *Store) CreateNote(ctx context.Context, user *models.User, note *models.Note) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if note.WorkspaceID != nil {
+		if _, ok := s.members[*note.WorkspaceID][user.ID]; !ok {
+			return 0, storage.ErrWorkspaceNotFound
+		}
+		if !s.canWrite(note.WorkspaceID, user.ID) {
+			return 0, storage.ErrWorkspaceReadOnly
+		}
+	}
+	if !s.ownsNotebook(user, note.NotebookID) {
+		return 0, storage.ErrNotebookNotFound
+	}
+	s.nextNoteID++
+	now := time.Now()
+	s.notes[s.nextNoteID] = models.Note{
+		ID:          s.nextNoteID,
+		UserID:      user.ID,
+		NotebookID:  note.NotebookID,
+		WorkspaceID: note.WorkspaceID,
+		Title:       note.Title,
+		Content:     note.Content,
+		Version:     1,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	note.Version, note.CreatedAt, note.UpdatedAt = 1, now, now
+	return s.nextNoteID, nil
+}
+
+func (s *Store) ListNotes(ctx context.Context, user *models.User, opts storage.ListOptions) (storage.NotePage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if opts.WorkspaceID != nil {
+		if _, ok := s.members[*opts.WorkspaceID][user.ID]; !ok {
+			return storage.NotePage{}, storage.ErrWorkspaceNotFound
+		}
+	}
+	var cursorTime time.Time
+	if opts.Cursor != nil && opts.Sort != storage.SortTitle {
+		var err error
+		cursorTime, err = opts.Cursor.Time()
+		if err != nil {
+			return storage.NotePage{}, err
+		}
+	}
+
+	// compare orders notes the same way the PostgreSQL store does: by the
+	// sort key first and by id to break ties.
+	compare := func(a models.Note, key string, keyTime time.Time, id int) int {
+		var result int
+		switch opts.Sort {
+		case storage.SortTitle:
+			result = strings.Compare(a.Title, key)
+		case storage.SortUpdated:
+			result = compareTime(a.UpdatedAt, keyTime)
+		default:
+			result = compareTime(a.CreatedAt, keyTime)
+		}
+		if result == 0 {
+			result = a.ID - id
+		}
+		if opts.Descending {
+			result = -result
+		}
+		return result
+	}
+
+	var notes []models.Note
+	for _, note := range s.notes {
+		if !inScope(user, note, opts.WorkspaceID) {
+			continue
+		}
+		if opts.CreatedAfter != nil && note.CreatedAt.Before(*opts.CreatedAfter) {
+			continue
+		}
+		if opts.CreatedBefore != nil && !note.CreatedAt.Before(*opts.CreatedBefore) {
+			continue
+		}
+		if opts.UpdatedAfter != nil && note.UpdatedAt.Before(*opts.UpdatedAfter) {
+			continue
+		}
+		if opts.UpdatedBefore != nil && !note.UpdatedAt.Before(*opts.UpdatedBefore) {
+			continue
+		}
+		if len(opts.Tags) > 0 && !s.hasTags(note.ID, opts.Tags, opts.AnyTag) {
+			continue
+		}
+		if len(opts.NotebookIDs) > 0 && !inNotebooks(note, opts.NotebookIDs) {
+			continue
+		}
+		if opts.Cursor != nil && compare(note, opts.Cursor.Value, cursorTime, opts.Cursor.ID) <= 0 {
+			continue
+		}
+		notes = append(notes, note)
+	}
+	sort.Slice(notes, func(i, j int) bool {
+		b := notes[j]
+		return compare(notes[i], b.Title, sortTime(b, opts.Sort), b.ID) < 0
+	})
+
+	var page storage.NotePage
+	if opts.Limit > 0 && len(notes) > opts.Limit {
+		notes = notes[:opts.Limit]
+		page.Next = storage.NewCursor(opts, notes[opts.Limit-1])
+	}
+	for i := range notes {
+		notes[i].Tags = s.noteTagNames(notes[i].ID)
+		if opts.SummaryOnly {
+			notes[i].Content = truncate(notes[i].Content, storage.SnippetLength+1)
+		}
+	}
+	page.Notes = notes
+	return page, nil
+}
+
+func sortTime(note models.Note, sortBy string) time.Time {
+	if sortBy == storage.SortUpdated {
+		return note.UpdatedAt
+	}
+	return note.CreatedAt
+}
+
+func compareTime(a, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	}
+	return 0
+}
+
+func truncate(value string, length int) string {
+	runes := []rune(value)
+	if len(runes) <= length {
+		return value
+	}
+	return string(runes[:length])
+}
+
+func (s *Store) DeleteAllNotes(ctx context.Context, user *models.User) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.deleteUserData(user.ID), nil
+}
+
+// deleteUserData removes the notes, tags and notebooks of a user and
+// returns the number of notes, the caller must hold the write lock. Notes
+// of workspaces are kept, without tags and notebook.
+func (s *Store) deleteUserData(userID int) int64 {
+	var deleted int64
+	for id, note := range s.notes {
+		if note.UserID != userID {
+			continue
+		}
+		if note.WorkspaceID != nil {
+			note.NotebookID = nil
+			s.notes[id] = note
+			continue
+		}
+		s.deleteNote(id)
+		deleted++
+	}
+	for id, t := range s.tags {
+		if t.userID == userID {
+			s.deleteTag(id)
+		}
+	}
+	for id, notebook := range s.notebooks {
+		if notebook.UserID == userID {
+			delete(s.notebooks, id)
+		}
+	}
+	return deleted
+}
+
+// activeNote returns a note of the user unless it is in the trash or in a
+// workspace in which the user is neither owner nor editor anymore.
+func (s *Store) activeNote(user *models.User, noteID int) (models.Note, bool) {
+	note, ok := s.notes[noteID]
+	if !ok || note.UserID != user.ID || note.DeletedAt != nil {
+		return models.Note{}, false
+	}
+	if note.WorkspaceID != nil && !s.canWrite(note.WorkspaceID, user.ID) {
+		return models.Note{}, false
+	}
+	return note, true
+}
+
+// accessibleNote returns a note of the user, shared with them or in one of
+// their workspaces unless it is in the trash, together with the permission
+// of the user. Owners of personal notes, and owners and editors of the
+// workspace, have the edit permission.
+func (s *Store) accessibleNote(user *models.User, noteID int) (models.Note, string, bool) {
+	if note, ok := s.activeNote(user, noteID); ok {
+		return note, models.PermissionEdit, true
+	}
+	note, ok := s.notes[noteID]
+	if !ok || note.DeletedAt != nil {
+		return models.Note{}, "", false
+	}
+	if s.canWrite(note.WorkspaceID, user.ID) {
+		return note, models.PermissionEdit, true
+	}
+	if share, shared := s.shares[noteID][user.ID]; shared {
+		return note, share.Permission, true
+	}
+	if note.WorkspaceID != nil {
+		if _, member := s.members[*note.WorkspaceID][user.ID]; member {
+			return note, models.PermissionRead, true
+		}
+	}
+	return models.Note{}, "", false
+}
+
+// inScope reports whether a note appears in listings of the workspace, or
+// of the personal notes of the user when workspaceID is nil. Membership of
+// the workspace is checked by the caller.
+func inScope(user *models.User, note models.Note, workspaceID *int) bool {
+	if note.DeletedAt != nil {
+		return false
+	}
+	if workspaceID == nil {
+		return note.UserID == user.ID && note.WorkspaceID == nil
+	}
+	return note.WorkspaceID != nil && *note.WorkspaceID == *workspaceID
+}
+
+// touchNote marks a note as modified after its tags changed, the caller must
+// hold the write lock.
+func (s *Store) touchNote(noteID int) {
+	note := s.notes[noteID]
+	note.Version++
+	note.UpdatedAt = time.Now()
+	s.notes[noteID] = note
+}
+
+// deleteNote removes a note with its dependent records, the caller must
+// hold the write lock.
+func (s *Store) deleteNote(noteID int) {
+	delete(s.notes, noteID)
+	delete(s.noteTags, noteID)
+	delete(s.shares, noteID)
+	for id, link := range s.shareLinks {
+		if link.NoteID == noteID {
+			delete(s.shareLinks, id)
+		}
+	}
+	for id, revision := range s.revisions {
+		if revision.NoteID == noteID {
+			delete(s.revisions, id)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package memory
+
+import (
+	"context"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/storage"
+	"time"
+)
+
+func (s *Store) CreatePasswordReset(ctx context.Context, reset *models.PasswordReset) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextResetID++
+	reset.ID = s.nextResetID
+	reset.CreatedAt = time.Now()
+	s.passwordResets[reset.TokenHash] = *reset
+	return nil
+}
+
+func (s *Store) ResetPassword(ctx context.Context, tokenHash string, passwordHash string, now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reset, ok := s.passwordResets[tokenHash]
+	if !ok || !reset.ExpiresAt.After(now) {
+		return 0, storage.ErrResetTokenNotFound
+	}
+	for hash, other := range s.passwordResets {
+		if other.UserID == reset.UserID {
+			delete(s.passwordResets, hash)
+		}
+	}
+	if user, ok := s.users[reset.UserID]; ok {
+		user.Password = passwordHash
+		s.users[reset.UserID] = user
+	}
+	return reset.UserID, nil
+}
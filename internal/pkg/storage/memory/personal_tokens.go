@@ -0,0 +1,83 @@
+package memory
+
+import (
+	"context"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/storage"
+	"sort"
+	"strings"
+	"time"
+)
+
+func (s *Store) CreatePersonalToken(ctx context.Context, token *models.PersonalToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.personalTokens {
+		if existing.UserID == token.UserID && strings.EqualFold(existing.Name, token.Name) {
+			return storage.ErrPersonalTokenExists
+		}
+	}
+	s.nextPATID++
+	token.ID = s.nextPATID
+	token.CreatedAt = time.Now()
+	s.personalTokens[token.ID] = *token
+	return nil
+}
+
+func (s *Store) ListPersonalTokens(ctx context.Context, userID int) ([]models.PersonalToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tokens := []models.PersonalToken{}
+	for _, token := range s.personalTokens {
+		if token.UserID == userID {
+			tokens = append(tokens, token)
+		}
+	}
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].ID < tokens[j].ID })
+	return tokens, nil
+}
+
+func (s *Store) UsePersonalToken(ctx context.Context, tokenHash string, now time.Time) (*models.PersonalToken, *models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, token := range s.personalTokens {
+		if token.TokenHash != tokenHash {
+			continue
+		}
+		user, ok := s.users[token.UserID]
+		if !ok || (token.ExpiresAt != nil && !token.ExpiresAt.After(now)) {
+			return nil, nil, nil
+		}
+		token.LastUsedAt = &now
+		s.personalTokens[id] = token
+		return &token, &user, nil
+	}
+	return nil, nil, nil
+}
+
+func (s *Store) DeletePersonalToken(ctx context.Context, userID int, tokenID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, ok := s.personalTokens[tokenID]
+	if !ok || token.UserID != userID {
+		return storage.ErrPersonalTokenNotFound
+	}
+	delete(s.personalTokens, tokenID)
+	return nil
+}
+
+func (s *Store) DeleteUserPersonalTokens(ctx context.Context, userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, token := range s.personalTokens {
+		if token.UserID == userID {
+			delete(s.personalTokens, id)
+		}
+	}
+	return nil
+}
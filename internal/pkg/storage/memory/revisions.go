@@ -0,0 +1,39 @@
+package memory
+
+import (
+	"context"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/storage"
+	"sort"
+)
+
+func (s *Store) ListRevisions(ctx context.Context, user *models.User, noteID int) ([]models.NoteRevision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, _, ok := s.accessibleNote(user, noteID); !ok {
+		return nil, storage.ErrNoteNotFound
+	}
+	revisions := []models.NoteRevision{}
+	for _, revision := range s.revisions {
+		if revision.NoteID == noteID {
+			revisions = append(revisions, revision)
+		}
+	}
+	sort.Slice(revisions, func(i, j int) bool { return revisions[i].ID > revisions[j].ID })
+	return revisions, nil
+}
+
+func (s *Store) ReadRevision(ctx context.Context, user *models.User, noteID int, revisionID int) (models.NoteRevision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	revision, ok := s.revisions[revisionID]
+	if !ok || revision.NoteID != noteID {
+		return models.NoteRevision{}, storage.ErrRevisionNotFound
+	}
+	if _, _, ok := s.accessibleNote(user, noteID); !ok {
+		return models.NoteRevision{}, storage.ErrRevisionNotFound
+	}
+	return revision, nil
+}
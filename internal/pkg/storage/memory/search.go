@@ -0,0 +1,183 @@
+package memory
+
+import (
+	"context"
+	"html"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/storage"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const snippetWords = 30
+
+// token is a lowercased word and its byte range in the original text.
+type token struct {
+	word       string
+	start, end int
+}
+
+func tokenize(text string) []token {
+	var tokens []token
+	start := -1
+	for i, r := range text {
+		wordRune := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if wordRune && start < 0 {
+			start = i
+		} else if !wordRune && start >= 0 {
+			tokens = append(tokens, token{strings.ToLower(text[start:i]), start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, token{strings.ToLower(text[start:]), start, len(text)})
+	}
+	return tokens
+}
+
+// matchTerm returns the token ranges [first, last] where the term matches.
+func matchTerm(tokens []token, term storage.SearchTerm) [][2]int {
+	var matches [][2]int
+	for i := 0; i+len(term.Words) <= len(tokens); i++ {
+		matched := true
+		for j, word := range term.Words {
+			candidate := tokens[i+j].word
+			if term.Prefix && j == len(term.Words)-1 {
+				matched = strings.HasPrefix(candidate, word)
+			} else {
+				matched = candidate == word
+			}
+			if !matched {
+				break
+			}
+		}
+		if matched {
+			matches = append(matches, [2]int{i, i + len(term.Words) - 1})
+		}
+	}
+	return matches
+}
+
+func (s *Store) SearchNotes(ctx context.Context, user *models.User, query storage.SearchQuery, limit, offset int) ([]models.NoteSearchResult, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if query.WorkspaceID != nil {
+		if _, ok := s.members[*query.WorkspaceID][user.ID]; !ok {
+			return nil, storage.ErrWorkspaceNotFound
+		}
+	}
+	results := []models.NoteSearchResult{}
+	for _, note := range s.notes {
+		if !inScope(user, note, query.WorkspaceID) {
+			continue
+		}
+		titleTokens := tokenize(note.Title)
+		contentTokens := tokenize(note.Content)
+
+		var titleMatches, contentMatches [][2]int
+		rank, found := 0.0, true
+		for _, term := range query.Terms {
+			inTitle := matchTerm(titleTokens, term)
+			inContent := matchTerm(contentTokens, term)
+			if len(inTitle) == 0 && len(inContent) == 0 {
+				found = false
+				break
+			}
+			// Title matches weigh more, like the 'A' weight of the title
+			// in the PostgreSQL search vector.
+			rank += float64(len(inTitle)) + 0.4*float64(len(inContent))
+			titleMatches = append(titleMatches, inTitle...)
+			contentMatches = append(contentMatches, inContent...)
+		}
+		if !found {
+			continue
+		}
+
+		results = append(results, models.NoteSearchResult{
+			ID:             note.ID,
+			Title:          note.Title,
+			TitleHighlight: highlight(note.Title, titleTokens, titleMatches, 0, len(titleTokens)),
+			Snippet:        contentSnippet(note.Content, contentTokens, contentMatches),
+			Rank:           rank / float64(1+len(titleTokens)+len(contentTokens)),
+			CreatedAt:      note.CreatedAt,
+			UpdatedAt:      note.UpdatedAt,
+		})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Rank != results[j].Rank {
+			return results[i].Rank > results[j].Rank
+		}
+		return results[i].ID > results[j].ID
+	})
+	if offset >= len(results) {
+		return []models.NoteSearchResult{}, nil
+	}
+	results = results[offset:]
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// contentSnippet returns a window of words around the first match with all
+// matches in it highlighted.
+func contentSnippet(text string, tokens []token, matches [][2]int) string {
+	if len(tokens) == 0 {
+		return ""
+	}
+	first := len(tokens)
+	for _, match := range matches {
+		if match[0] < first {
+			first = match[0]
+		}
+	}
+	if first == len(tokens) {
+		first = 0
+	}
+	from := first - snippetWords/3
+	if from < 0 {
+		from = 0
+	}
+	to := from + snippetWords
+	if to > len(tokens) {
+		to = len(tokens)
+	}
+	return highlight(text, tokens, matches, from, to)
+}
+
+// highlight renders the text of tokens[from:to] HTML-escaped, wrapping
+// matched token ranges in highlight tags.
+func highlight(text string, tokens []token, matches [][2]int, from, to int) string {
+	if from >= to {
+		return ""
+	}
+	marked := make([]bool, len(tokens))
+	for _, match := range matches {
+		for i := match[0]; i <= match[1]; i++ {
+			marked[i] = true
+		}
+	}
+
+	var builder strings.Builder
+	position := tokens[from].start
+	if from == 0 {
+		position = 0
+	}
+	for i := from; i < to; i++ {
+		builder.WriteString(html.EscapeString(text[position:tokens[i].start]))
+		word := html.EscapeString(text[tokens[i].start:tokens[i].end])
+		if marked[i] {
+			builder.WriteString(storage.HighlightStart + word + storage.HighlightStop)
+		} else {
+			builder.WriteString(word)
+		}
+		position = tokens[i].end
+	}
+	if to == len(tokens) {
+		builder.WriteString(html.EscapeString(text[position:]))
+	}
+	return builder.String()
+}
@@ -0,0 +1,36 @@
+package memory
+
+import (
+	"context"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/storage"
+	"testing"
+)
+
+func TestSearchNotesEscapesHighlights(t *testing.T) {
+	ctx := context.Background()
+	store := New()
+	user := &models.User{ID: 1}
+	note := models.Note{Title: `<b>Plan</b> & "more"`, Content: `<script>alert('plan')</script>`}
+	if _, err := store.CreateNote(ctx, user, &note); err != nil {
+		t.Fatal(err)
+	}
+
+	query, err := storage.ParseSearchQuery("plan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := store.SearchNotes(ctx, user, query, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("got %d results, want 1", len(results))
+	}
+	if want := `&lt;b&gt;<mark>Plan</mark>&lt;/b&gt; &amp; &#34;more&#34;`; results[0].TitleHighlight != want {
+		t.Errorf("title highlight = %q, want %q", results[0].TitleHighlight, want)
+	}
+	if want := `&lt;script&gt;alert(&#39;<mark>plan</mark>&#39;)&lt;/script&gt;`; results[0].Snippet != want {
+		t.Errorf("snippet = %q, want %q", results[0].Snippet, want)
+	}
+}
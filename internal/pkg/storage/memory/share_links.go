@@ -0,0 +1,92 @@
+package memory
+
+import (
+	"context"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/storage"
+	"sort"
+	"time"
+)
+
+func (s *Store) CreateShareLink(ctx context.Context, owner *models.User, link *models.ShareLink) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.activeNote(owner, link.NoteID); !ok {
+		return storage.ErrNoteNotFound
+	}
+	s.nextLinkID++
+	link.ID = s.nextLinkID
+	link.CreatedAt = time.Now()
+	link.HasPassword = link.PasswordHash != ""
+	s.shareLinks[link.ID] = *link
+	return nil
+}
+
+func (s *Store) ListShareLinks(ctx context.Context, owner *models.User, noteID int) ([]models.ShareLink, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.activeNote(owner, noteID); !ok {
+		return nil, storage.ErrNoteNotFound
+	}
+	links := []models.ShareLink{}
+	for _, link := range s.shareLinks {
+		if link.NoteID == noteID {
+			links = append(links, link)
+		}
+	}
+	sort.Slice(links, func(i, j int) bool { return links[i].ID < links[j].ID })
+	return links, nil
+}
+
+func (s *Store) DeleteShareLink(ctx context.Context, owner *models.User, noteID int, linkID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.activeNote(owner, noteID); !ok {
+		return storage.ErrNoteNotFound
+	}
+	link, ok := s.shareLinks[linkID]
+	if !ok || link.NoteID != noteID {
+		return storage.ErrShareLinkNotFound
+	}
+	delete(s.shareLinks, linkID)
+	return nil
+}
+
+func (s *Store) GetShareLink(ctx context.Context, tokenHash string, now time.Time) (*models.ShareLink, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, link := range s.shareLinks {
+		if link.TokenHash != tokenHash {
+			continue
+		}
+		note, ok := s.notes[link.NoteID]
+		if (link.ExpiresAt != nil && !link.ExpiresAt.After(now)) || !ok || note.DeletedAt != nil {
+			return nil, nil
+		}
+		return &link, nil
+	}
+	return nil, nil
+}
+
+func (s *Store) ViewShareLink(ctx context.Context, linkID int, now time.Time) (models.Note, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	link, ok := s.shareLinks[linkID]
+	if !ok {
+		return models.Note{}, storage.ErrNoteNotFound
+	}
+	note, ok := s.notes[link.NoteID]
+	if !ok || note.DeletedAt != nil {
+		return models.Note{}, storage.ErrNoteNotFound
+	}
+	link.Views++
+	link.LastViewedAt = &now
+	s.shareLinks[linkID] = link
+	note.Tags = s.noteTagNames(note.ID)
+	return note, nil
+}
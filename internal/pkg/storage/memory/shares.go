@@ -0,0 +1,100 @@
+package memory
+
+import (
+	"context"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/storage"
+	"sort"
+	"strings"
+	"time"
+)
+
+func (s *Store) ShareNote(ctx context.Context, owner *models.User, share *models.NoteShare) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.activeNote(owner, share.NoteID); !ok {
+		return storage.ErrNoteNotFound
+	}
+	if existing, ok := s.shares[share.NoteID][share.UserID]; ok {
+		share.CreatedAt = existing.CreatedAt
+	} else {
+		share.CreatedAt = time.Now()
+	}
+	if s.shares[share.NoteID] == nil {
+		s.shares[share.NoteID] = make(map[int]models.NoteShare)
+	}
+	s.shares[share.NoteID][share.UserID] = models.NoteShare{
+		NoteID:     share.NoteID,
+		UserID:     share.UserID,
+		Permission: share.Permission,
+		CreatedAt:  share.CreatedAt,
+	}
+	return nil
+}
+
+func (s *Store) ListNoteShares(ctx context.Context, owner *models.User, noteID int) ([]models.NoteShare, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.activeNote(owner, noteID); !ok {
+		return nil, storage.ErrNoteNotFound
+	}
+	shares := []models.NoteShare{}
+	for userID, share := range s.shares[noteID] {
+		share.Username = s.users[userID].Username
+		shares = append(shares, share)
+	}
+	sort.Slice(shares, func(i, j int) bool {
+		return strings.ToLower(shares[i].Username) < strings.ToLower(shares[j].Username)
+	})
+	return shares, nil
+}
+
+func (s *Store) RevokeShare(ctx context.Context, owner *models.User, noteID int, userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.activeNote(owner, noteID); !ok {
+		return storage.ErrNoteNotFound
+	}
+	if _, ok := s.shares[noteID][userID]; !ok {
+		return storage.ErrShareNotFound
+	}
+	delete(s.shares[noteID], userID)
+	return nil
+}
+
+func (s *Store) ListSharedNotes(ctx context.Context, user *models.User, limit, offset int) ([]models.SharedNote, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	notes := []models.SharedNote{}
+	for noteID, shares := range s.shares {
+		share, ok := shares[user.ID]
+		note, exists := s.notes[noteID]
+		if !ok || !exists || note.DeletedAt != nil {
+			continue
+		}
+		note.Tags = s.noteTagNames(noteID)
+		notes = append(notes, models.SharedNote{
+			Note:       note,
+			Owner:      s.users[note.UserID].Username,
+			Permission: share.Permission,
+		})
+	}
+	sort.Slice(notes, func(i, j int) bool {
+		if !notes[i].UpdatedAt.Equal(notes[j].UpdatedAt) {
+			return notes[i].UpdatedAt.After(notes[j].UpdatedAt)
+		}
+		return notes[i].ID > notes[j].ID
+	})
+	if offset >= len(notes) {
+		return []models.SharedNote{}, nil
+	}
+	notes = notes[offset:]
+	if limit > 0 && len(notes) > limit {
+		notes = notes[:limit]
+	}
+	return notes, nil
+}
@@ -0,0 +1,80 @@
+package memory
+
+import (
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/storage"
+	"sync"
+	"time"
+)
+
+// Store keeps users and notes in process memory. It behaves like the
+// PostgreSQL store and is meant for tests and local development.
+type Store struct {
+	mu        sync.RWMutex
+	users     map[int]models.User
+	notes     map[int]models.Note
+	revisions map[int]models.NoteRevision
+	tags      map[int]tag
+	noteTags  map[int]map[int]bool
+	notebooks map[int]models.Notebook
+	// shares maps note ids to the shares of the note by user id.
+	shares     map[int]map[int]models.NoteShare
+	shareLinks map[int]models.ShareLink
+	workspaces map[int]models.Workspace
+	// members maps workspace ids to their members by user id.
+	members map[int]map[int]models.WorkspaceMember
+	// refreshTokens is keyed by token hash, revokedTokens maps revoked
+	// access token ids to their expiry.
+	refreshTokens map[string]models.RefreshToken
+	revokedTokens map[string]time.Time
+	// personalTokens is keyed by token id.
+	personalTokens map[int]models.PersonalToken
+	// passwordResets is keyed by token hash.
+	passwordResets map[string]models.PasswordReset
+	invites        map[int]models.Invite
+	// totps is keyed by user id, recoveryCodes maps user ids to the hashes
+	// of their unused recovery codes.
+	totps         map[int]models.TOTP
+	recoveryCodes map[int]map[string]bool
+	identities    map[int]models.Identity
+	// oidcLogins is keyed by state hash.
+	oidcLogins     map[string]models.OIDCLogin
+	nextUserID     int
+	nextNoteID     int
+	nextRevID      int
+	nextTagID      int
+	nextBookID     int
+	nextTokenID    int
+	nextPATID      int
+	nextResetID    int
+	nextInviteID   int
+	nextIdentityID int
+	nextLinkID     int
+	nextSpaceID    int
+}
+
+var _ storage.Store = (*Store)(nil)
+
+func New() *Store {
+	return &Store{
+		users:          make(map[int]models.User),
+		notes:          make(map[int]models.Note),
+		revisions:      make(map[int]models.NoteRevision),
+		tags:           make(map[int]tag),
+		noteTags:       make(map[int]map[int]bool),
+		notebooks:      make(map[int]models.Notebook),
+		shares:         make(map[int]map[int]models.NoteShare),
+		shareLinks:     make(map[int]models.ShareLink),
+		workspaces:     make(map[int]models.Workspace),
+		members:        make(map[int]map[int]models.WorkspaceMember),
+		refreshTokens:  make(map[string]models.RefreshToken),
+		revokedTokens:  make(map[string]time.Time),
+		personalTokens: make(map[int]models.PersonalToken),
+		passwordResets: make(map[string]models.PasswordReset),
+		invites:        make(map[int]models.Invite),
+		totps:          make(map[int]models.TOTP),
+		recoveryCodes:  make(map[int]map[string]bool),
+		identities:     make(map[int]models.Identity),
+		oidcLogins:     make(map[string]models.OIDCLogin),
+	}
+}
@@ -0,0 +1,156 @@
+package memory
+
+import (
+	"context"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/storage"
+	"sort"
+	"strings"
+)
+
+type tag struct {
+	id     int
+	userID int
+	name   string
+}
+
+func (s *Store) ListTags(ctx context.Context, user *models.User) ([]models.Tag, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tags := []models.Tag{}
+	for _, t := range s.tags {
+		if t.userID != user.ID {
+			continue
+		}
+		count := 0
+		for noteID, tagIDs := range s.noteTags {
+			if tagIDs[t.id] && s.notes[noteID].DeletedAt == nil {
+				count++
+			}
+		}
+		tags = append(tags, models.Tag{Name: t.name, Count: count})
+	}
+	sort.Slice(tags, func(i, j int) bool { return strings.ToLower(tags[i].Name) < strings.ToLower(tags[j].Name) })
+	return tags, nil
+}
+
+func (s *Store) AddNoteTags(ctx context.Context, user *models.User, noteID int, names []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.activeNote(user, noteID); !ok {
+		return storage.ErrNoteNotFound
+	}
+	for _, name := range names {
+		t, ok := s.findTag(user, name)
+		if !ok {
+			s.nextTagID++
+			t = tag{id: s.nextTagID, userID: user.ID, name: name}
+			s.tags[t.id] = t
+		}
+		if s.noteTags[noteID] == nil {
+			s.noteTags[noteID] = make(map[int]bool)
+		}
+		s.noteTags[noteID][t.id] = true
+	}
+	s.touchNote(noteID)
+	return nil
+}
+
+func (s *Store) RemoveNoteTag(ctx context.Context, user *models.User, noteID int, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.activeNote(user, noteID); !ok {
+		return storage.ErrTagNotFound
+	}
+	t, ok := s.findTag(user, name)
+	if !ok || !s.noteTags[noteID][t.id] {
+		return storage.ErrTagNotFound
+	}
+	delete(s.noteTags[noteID], t.id)
+	s.touchNote(noteID)
+	return nil
+}
+
+func (s *Store) RenameTag(ctx context.Context, user *models.User, name string, newName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.findTag(user, name)
+	if !ok {
+		return storage.ErrTagNotFound
+	}
+	if existing, ok := s.findTag(user, newName); ok && existing.id != t.id {
+		return storage.ErrTagExists
+	}
+	s.touchTaggedNotes(t.id)
+	t.name = newName
+	s.tags[t.id] = t
+	return nil
+}
+
+func (s *Store) DeleteTag(ctx context.Context, user *models.User, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.findTag(user, name)
+	if !ok {
+		return storage.ErrTagNotFound
+	}
+	s.touchTaggedNotes(t.id)
+	s.deleteTag(t.id)
+	return nil
+}
+
+func (s *Store) findTag(user *models.User, name string) (tag, bool) {
+	for _, t := range s.tags {
+		if t.userID == user.ID && strings.EqualFold(t.name, name) {
+			return t, true
+		}
+	}
+	return tag{}, false
+}
+
+func (s *Store) deleteTag(tagID int) {
+	delete(s.tags, tagID)
+	for _, tagIDs := range s.noteTags {
+		delete(tagIDs, tagID)
+	}
+}
+
+func (s *Store) touchTaggedNotes(tagID int) {
+	for noteID, tagIDs := range s.noteTags {
+		if tagIDs[tagID] {
+			s.touchNote(noteID)
+		}
+	}
+}
+
+// noteTagNames returns the sorted tag names of a note.
+func (s *Store) noteTagNames(noteID int) []string {
+	names := []string{}
+	for tagID := range s.noteTags[noteID] {
+		names = append(names, s.tags[tagID].name)
+	}
+	sort.Slice(names, func(i, j int) bool { return strings.ToLower(names[i]) < strings.ToLower(names[j]) })
+	return names
+}
+
+// hasTags reports whether a note matches a tag filter of lowercase names.
+func (s *Store) hasTags(noteID int, names []string, any bool) bool {
+	tagged := make(map[string]bool)
+	for tagID := range s.noteTags[noteID] {
+		tagged[strings.ToLower(s.tags[tagID].name)] = true
+	}
+	for _, name := range names {
+		if tagged[name] && any {
+			return true
+		}
+		if !tagged[name] && !any {
+			return false
+		}
+	}
+	return !any
+}
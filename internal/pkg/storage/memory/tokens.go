@@ -0,0 +1,166 @@
+package memory
+
+import (
+	"context"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/storage"
+	"time"
+)
+
+func (s *Store) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.insertRefreshToken(token)
+	return nil
+}
+
+func (s *Store) GetRefreshToken(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	token, ok := s.refreshTokens[tokenHash]
+	if !ok {
+		return nil, nil
+	}
+	return &token, nil
+}
+
+func (s *Store) RotateRefreshToken(ctx context.Context, tokenHash string, next *models.RefreshToken) (models.RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.refreshTokens[tokenHash]
+	if !ok {
+		return models.RefreshToken{}, storage.ErrTokenNotFound
+	}
+	if current.UsedAt != nil || current.RevokedAt != nil {
+		return current, storage.ErrTokenReused
+	}
+	now := time.Now()
+	if !current.ExpiresAt.After(now) {
+		return current, storage.ErrTokenNotFound
+	}
+
+	current.UsedAt = &now
+	s.refreshTokens[tokenHash] = current
+	next.UserID = current.UserID
+	next.FamilyID = current.FamilyID
+	s.insertRefreshToken(next)
+	return current, nil
+}
+
+func (s *Store) RevokeTokenFamily(ctx context.Context, familyID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.revokeRefreshTokens(func(token models.RefreshToken) bool { return token.FamilyID == familyID })
+	return nil
+}
+
+func (s *Store) RevokeUserTokens(ctx context.Context, userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.revokeRefreshTokens(func(token models.RefreshToken) bool { return token.UserID == userID })
+	return nil
+}
+
+func (s *Store) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.revokedTokens[jti]; !ok {
+		s.revokedTokens[jti] = expiresAt
+	}
+	return nil
+}
+
+func (s *Store) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, revoked := s.revokedTokens[jti]
+	return revoked, nil
+}
+
+func (s *Store) GetTokenUser(ctx context.Context, jti string, userID int) (*models.User, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, ok := s.users[userID]
+	if !ok {
+		return nil, false, nil
+	}
+	_, revoked := s.revokedTokens[jti]
+	return &user, revoked, nil
+}
+
+func (s *Store) PurgeExpiredTokens(ctx context.Context, now time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var purged int64
+	for hash, token := range s.refreshTokens {
+		if token.ExpiresAt.Before(now) {
+			delete(s.refreshTokens, hash)
+			purged++
+		}
+	}
+	for jti, expiresAt := range s.revokedTokens {
+		if expiresAt.Before(now) {
+			delete(s.revokedTokens, jti)
+			purged++
+		}
+	}
+	for hash, reset := range s.passwordResets {
+		if reset.ExpiresAt.Before(now) {
+			delete(s.passwordResets, hash)
+			purged++
+		}
+	}
+	for hash, login := range s.oidcLogins {
+		if login.ExpiresAt.Before(now) {
+			delete(s.oidcLogins, hash)
+			purged++
+		}
+	}
+	for id, token := range s.personalTokens {
+		if token.ExpiresAt != nil && token.ExpiresAt.Before(now) {
+			delete(s.personalTokens, id)
+			purged++
+		}
+	}
+	for id, link := range s.shareLinks {
+		if link.ExpiresAt != nil && link.ExpiresAt.Before(now) {
+			delete(s.shareLinks, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// revokeRefreshTokens revokes the matching refresh tokens together with the
+// access tokens issued with them, the caller must hold the write lock.
+func (s *Store) revokeRefreshTokens(match func(models.RefreshToken) bool) {
+	now := time.Now()
+	for hash, token := range s.refreshTokens {
+		if !match(token) {
+			continue
+		}
+		if _, ok := s.revokedTokens[token.AccessJTI]; !ok {
+			s.revokedTokens[token.AccessJTI] = token.ExpiresAt
+		}
+		if token.RevokedAt == nil {
+			token.RevokedAt = &now
+			s.refreshTokens[hash] = token
+		}
+	}
+}
+
+func (s *Store) insertRefreshToken(token *models.RefreshToken) {
+	s.nextTokenID++
+	token.ID = s.nextTokenID
+	token.CreatedAt = time.Now()
+	s.refreshTokens[token.TokenHash] = *token
+}
@@ -0,0 +1,101 @@
+package memory
+
+import (
+	"context"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/storage"
+	"time"
+)
+
+func (s *Store) GetTOTP(ctx context.Context, userID int) (*models.TOTP, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	totp, ok := s.totps[userID]
+	if !ok {
+		return nil, nil
+	}
+	totp.RecoveryCodes = len(s.recoveryCodes[userID])
+	return &totp, nil
+}
+
+func (s *Store) SaveTOTPSecret(ctx context.Context, userID int, secret string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if totp, ok := s.totps[userID]; ok && totp.EnabledAt != nil {
+		return storage.ErrTOTPEnabled
+	}
+	s.totps[userID] = models.TOTP{UserID: userID, Secret: secret, CreatedAt: time.Now()}
+	return nil
+}
+
+func (s *Store) EnableTOTP(ctx context.Context, userID int, step int64, codeHashes []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	totp, ok := s.totps[userID]
+	if !ok {
+		return storage.ErrTOTPNotFound
+	}
+	if totp.EnabledAt != nil {
+		return storage.ErrTOTPEnabled
+	}
+	now := time.Now()
+	totp.EnabledAt = &now
+	totp.LastStep = step
+	s.totps[userID] = totp
+	s.replaceRecoveryCodes(userID, codeHashes)
+	return nil
+}
+
+func (s *Store) UseTOTPStep(ctx context.Context, userID int, step int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	totp, ok := s.totps[userID]
+	if !ok || totp.EnabledAt == nil || totp.LastStep >= step {
+		return false, nil
+	}
+	totp.LastStep = step
+	s.totps[userID] = totp
+	return true, nil
+}
+
+func (s *Store) UseRecoveryCode(ctx context.Context, userID int, codeHash string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.recoveryCodes[userID][codeHash] {
+		return false, nil
+	}
+	delete(s.recoveryCodes[userID], codeHash)
+	return true, nil
+}
+
+func (s *Store) ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.replaceRecoveryCodes(userID, codeHashes)
+	return nil
+}
+
+func (s *Store) DeleteTOTP(ctx context.Context, userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.totps, userID)
+	delete(s.recoveryCodes, userID)
+	return nil
+}
+
+// replaceRecoveryCodes swaps the recovery codes of a user, the caller must
+// hold the write lock.
+func (s *Store) replaceRecoveryCodes(userID int, codeHashes []string) {
+	codes := make(map[string]bool, len(codeHashes))
+	for _, hash := range codeHashes {
+		codes[hash] = true
+	}
+	s.recoveryCodes[userID] = codes
+}
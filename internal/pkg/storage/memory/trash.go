@@ -0,0 +1,89 @@
+package memory
+
+import (
+	"context"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/storage"
+	"sort"
+	"time"
+)
+
+func (s *Store) ListTrash(ctx context.Context, user *models.User) ([]models.Note, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	notes := []models.Note{}
+	for _, note := range s.notes {
+		if note.UserID == user.ID && note.DeletedAt != nil {
+			note.Tags = s.noteTagNames(note.ID)
+			notes = append(notes, note)
+		}
+	}
+	sort.Slice(notes, func(i, j int) bool {
+		if !notes[i].DeletedAt.Equal(*notes[j].DeletedAt) {
+			return notes[i].DeletedAt.After(*notes[j].DeletedAt)
+		}
+		return notes[i].ID > notes[j].ID
+	})
+	return notes, nil
+}
+
+func (s *Store) RestoreNote(ctx context.Context, user *models.User, noteID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	note, ok := s.trashedNote(user, noteID)
+	if !ok {
+		return storage.ErrNoteNotFound
+	}
+	note.DeletedAt = nil
+	s.notes[noteID] = note
+	return nil
+}
+
+func (s *Store) PurgeNote(ctx context.Context, user *models.User, noteID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.trashedNote(user, noteID); !ok {
+		return storage.ErrNoteNotFound
+	}
+	s.deleteNote(noteID)
+	return nil
+}
+
+func (s *Store) EmptyTrash(ctx context.Context, user *models.User) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var purged int64
+	for id, note := range s.notes {
+		if note.UserID == user.ID && note.DeletedAt != nil {
+			s.deleteNote(id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+func (s *Store) PurgeTrash(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var purged int64
+	for id, note := range s.notes {
+		if note.DeletedAt != nil && note.DeletedAt.Before(deletedBefore) {
+			s.deleteNote(id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+func (s *Store) trashedNote(user *models.User, noteID int) (models.Note, bool) {
+	note, ok := s.notes[noteID]
+	if !ok || note.UserID != user.ID || note.DeletedAt == nil {
+		return models.Note{}, false
+	}
+	return note, true
+}
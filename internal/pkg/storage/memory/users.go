@@ -0,0 +1,159 @@
+package memory
+
+import (
+	"context"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/storage"
+	"strings"
+)
+
+func (s *Store) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, user := range s.users {
+		if strings.EqualFold(user.Username, username) {
+			return &user, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *Store) GetUserByID(ctx context.Context, userID int) (*models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, ok := s.users[userID]
+	if !ok {
+		return nil, nil
+	}
+	return &user, nil
+}
+
+func (s *Store) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, user := range s.users {
+		if user.Email != "" && strings.EqualFold(user.Email, email) {
+			return &user, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *Store) SaveUser(ctx context.Context, user models.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.saveUser(&user)
+}
+
+// saveUser adds a user, the caller must hold the write lock.
+func (s *Store) saveUser(user *models.User) error {
+	for _, existing := range s.users {
+		if strings.EqualFold(existing.Username, user.Username) {
+			return storage.ErrUserExists
+		}
+	}
+	if s.emailTaken(user.Email, 0) {
+		return storage.ErrEmailExists
+	}
+	if user.Role == "" {
+		user.Role = models.RoleUser
+	}
+	s.nextUserID++
+	user.ID = s.nextUserID
+	s.users[user.ID] = *user
+	return nil
+}
+
+func (s *Store) UpdatePassword(ctx context.Context, userID int, passwordHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if user, ok := s.users[userID]; ok {
+		user.Password = passwordHash
+		s.users[userID] = user
+	}
+	return nil
+}
+
+func (s *Store) UpdateEmail(ctx context.Context, userID int, email string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.emailTaken(email, userID) {
+		return storage.ErrEmailExists
+	}
+	if user, ok := s.users[userID]; ok {
+		user.Email = email
+		s.users[userID] = user
+	}
+	return nil
+}
+
+func (s *Store) DeleteUser(ctx context.Context, user models.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.leaveWorkspaces(user.ID)
+	s.deleteUserData(user.ID)
+	for _, shares := range s.shares {
+		delete(shares, user.ID)
+	}
+	for id, revision := range s.revisions {
+		if revision.EditorID != nil && *revision.EditorID == user.ID {
+			revision.EditorID = nil
+			s.revisions[id] = revision
+		}
+	}
+	for hash, token := range s.refreshTokens {
+		if token.UserID == user.ID {
+			delete(s.refreshTokens, hash)
+		}
+	}
+	for id, token := range s.personalTokens {
+		if token.UserID == user.ID {
+			delete(s.personalTokens, id)
+		}
+	}
+	for hash, reset := range s.passwordResets {
+		if reset.UserID == user.ID {
+			delete(s.passwordResets, hash)
+		}
+	}
+	for id, invite := range s.invites {
+		if invite.CreatedBy == user.ID {
+			delete(s.invites, id)
+		}
+	}
+	for id, identity := range s.identities {
+		if identity.UserID == user.ID {
+			delete(s.identities, id)
+		}
+	}
+	for hash, login := range s.oidcLogins {
+		if login.LinkUserID == user.ID {
+			delete(s.oidcLogins, hash)
+		}
+	}
+	delete(s.totps, user.ID)
+	delete(s.recoveryCodes, user.ID)
+	delete(s.users, user.ID)
+	return nil
+}
+
+// emailTaken reports whether a user other than userID has the email
+// address, the caller must hold the lock.
+func (s *Store) emailTaken(email string, userID int) bool {
+	if email == "" {
+		return false
+	}
+	for _, user := range s.users {
+		if user.ID != userID && strings.EqualFold(user.Email, email) {
+			return true
+		}
+	}
+	return false
+}
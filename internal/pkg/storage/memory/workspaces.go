@@ -0,0 +1,259 @@
+package memory
+
+import (
+	"context"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/storage"
+	"sort"
+	"strings"
+	"time"
+)
+
+func (s *Store) CreateWorkspace(ctx context.Context, user *models.User, workspace *models.Workspace) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextSpaceID++
+	workspace.ID = s.nextSpaceID
+	workspace.Role = models.WorkspaceRoleOwner
+	workspace.CreatedAt = time.Now()
+	s.workspaces[workspace.ID] = models.Workspace{ID: workspace.ID, Name: workspace.Name, CreatedAt: workspace.CreatedAt}
+	s.members[workspace.ID] = map[int]models.WorkspaceMember{
+		user.ID: {
+			WorkspaceID: workspace.ID,
+			UserID:      user.ID,
+			Role:        models.WorkspaceRoleOwner,
+			CreatedAt:   workspace.CreatedAt,
+		},
+	}
+	return nil
+}
+
+func (s *Store) ListWorkspaces(ctx context.Context, user *models.User) ([]models.Workspace, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	workspaces := []models.Workspace{}
+	for id, workspace := range s.workspaces {
+		if member, ok := s.members[id][user.ID]; ok {
+			workspace.Role = member.Role
+			workspaces = append(workspaces, workspace)
+		}
+	}
+	sort.Slice(workspaces, func(i, j int) bool {
+		a, b := strings.ToLower(workspaces[i].Name), strings.ToLower(workspaces[j].Name)
+		if a != b {
+			return a < b
+		}
+		return workspaces[i].ID < workspaces[j].ID
+	})
+	return workspaces, nil
+}
+
+func (s *Store) GetWorkspace(ctx context.Context, user *models.User, workspaceID int) (models.Workspace, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	member, ok := s.members[workspaceID][user.ID]
+	if !ok {
+		return models.Workspace{}, storage.ErrWorkspaceNotFound
+	}
+	workspace := s.workspaces[workspaceID]
+	workspace.Role = member.Role
+	return workspace, nil
+}
+
+func (s *Store) RenameWorkspace(ctx context.Context, user *models.User, workspaceID int, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkOwner(workspaceID, user.ID); err != nil {
+		return err
+	}
+	workspace := s.workspaces[workspaceID]
+	workspace.Name = name
+	s.workspaces[workspaceID] = workspace
+	return nil
+}
+
+func (s *Store) DeleteWorkspace(ctx context.Context, user *models.User, workspaceID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkOwner(workspaceID, user.ID); err != nil {
+		return err
+	}
+	s.deleteWorkspace(workspaceID)
+	return nil
+}
+
+func (s *Store) ListWorkspaceMembers(ctx context.Context, user *models.User, workspaceID int) ([]models.WorkspaceMember, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.members[workspaceID][user.ID]; !ok {
+		return nil, storage.ErrWorkspaceNotFound
+	}
+	members := []models.WorkspaceMember{}
+	for userID, member := range s.members[workspaceID] {
+		member.Username = s.users[userID].Username
+		members = append(members, member)
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return strings.ToLower(members[i].Username) < strings.ToLower(members[j].Username)
+	})
+	return members, nil
+}
+
+func (s *Store) SetWorkspaceMember(ctx context.Context, user *models.User, member *models.WorkspaceMember) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkOwner(member.WorkspaceID, user.ID); err != nil {
+		return err
+	}
+	if member.Role != models.WorkspaceRoleOwner && s.lastOwner(member.WorkspaceID, member.UserID) {
+		return storage.ErrLastWorkspaceOwner
+	}
+	if existing, ok := s.members[member.WorkspaceID][member.UserID]; ok {
+		member.CreatedAt = existing.CreatedAt
+	} else {
+		member.CreatedAt = time.Now()
+	}
+	s.members[member.WorkspaceID][member.UserID] = models.WorkspaceMember{
+		WorkspaceID: member.WorkspaceID,
+		UserID:      member.UserID,
+		Role:        member.Role,
+		CreatedAt:   member.CreatedAt,
+	}
+	return nil
+}
+
+func (s *Store) RemoveWorkspaceMember(ctx context.Context, user *models.User, workspaceID int, userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	actor, ok := s.members[workspaceID][user.ID]
+	if !ok {
+		return storage.ErrWorkspaceNotFound
+	}
+	if userID != user.ID && actor.Role != models.WorkspaceRoleOwner {
+		return storage.ErrNotWorkspaceOwner
+	}
+	if s.lastOwner(workspaceID, userID) {
+		return storage.ErrLastWorkspaceOwner
+	}
+	if _, ok := s.members[workspaceID][userID]; !ok {
+		return storage.ErrMemberNotFound
+	}
+	delete(s.members[workspaceID], userID)
+	s.handOverNotes(workspaceID, userID)
+	return nil
+}
+
+// checkOwner fails unless the user is an owner of the workspace, the
+// caller must hold the lock.
+func (s *Store) checkOwner(workspaceID int, userID int) error {
+	member, ok := s.members[workspaceID][userID]
+	if !ok {
+		return storage.ErrWorkspaceNotFound
+	}
+	if member.Role != models.WorkspaceRoleOwner {
+		return storage.ErrNotWorkspaceOwner
+	}
+	return nil
+}
+
+// canWrite reports whether the user may change the notes of a workspace,
+// nil stands for no workspace. The caller must hold the lock.
+func (s *Store) canWrite(workspaceID *int, userID int) bool {
+	if workspaceID == nil {
+		return false
+	}
+	role := s.members[*workspaceID][userID].Role
+	return role == models.WorkspaceRoleOwner || role == models.WorkspaceRoleEditor
+}
+
+// lastOwner reports whether userID is the only owner of the workspace, the
+// caller must hold the lock.
+func (s *Store) lastOwner(workspaceID int, userID int) bool {
+	if s.members[workspaceID][userID].Role != models.WorkspaceRoleOwner {
+		return false
+	}
+	for id, member := range s.members[workspaceID] {
+		if id != userID && member.Role == models.WorkspaceRoleOwner {
+			return false
+		}
+	}
+	return true
+}
+
+// earliestMember returns the member with the given role who joined first,
+// or zero if there is none. The caller must hold the lock.
+func (s *Store) earliestMember(workspaceID int, role string) int {
+	var earliest models.WorkspaceMember
+	for _, member := range s.members[workspaceID] {
+		if member.Role != role {
+			continue
+		}
+		if earliest.UserID == 0 || member.CreatedAt.Before(earliest.CreatedAt) ||
+			(member.CreatedAt.Equal(earliest.CreatedAt) && member.UserID < earliest.UserID) {
+			earliest = member
+		}
+	}
+	return earliest.UserID
+}
+
+// handOverNotes gives the notes a former member wrote in the workspace to
+// its earliest owner, without the tags and notebook of the former member.
+// The caller must hold the write lock.
+func (s *Store) handOverNotes(workspaceID int, userID int) {
+	ownerID := s.earliestMember(workspaceID, models.WorkspaceRoleOwner)
+	for id, note := range s.notes {
+		if note.UserID != userID || note.WorkspaceID == nil || *note.WorkspaceID != workspaceID {
+			continue
+		}
+		note.UserID = ownerID
+		note.NotebookID = nil
+		s.notes[id] = note
+		delete(s.noteTags, id)
+	}
+}
+
+// deleteWorkspace removes a workspace, its notes become personal notes of
+// their authors. The caller must hold the write lock.
+func (s *Store) deleteWorkspace(workspaceID int) {
+	for id, note := range s.notes {
+		if note.WorkspaceID != nil && *note.WorkspaceID == workspaceID {
+			note.WorkspaceID = nil
+			s.notes[id] = note
+		}
+	}
+	delete(s.members, workspaceID)
+	delete(s.workspaces, workspaceID)
+}
+
+// leaveWorkspaces prepares the deletion of a user like its PostgreSQL
+// counterpart, the caller must hold the write lock.
+func (s *Store) leaveWorkspaces(userID int) {
+	for workspaceID, members := range s.members {
+		if _, ok := members[userID]; !ok {
+			continue
+		}
+		if len(members) == 1 {
+			s.deleteWorkspace(workspaceID)
+			continue
+		}
+		if s.lastOwner(workspaceID, userID) {
+			successorID := s.earliestMember(workspaceID, models.WorkspaceRoleEditor)
+			if successorID == 0 {
+				successorID = s.earliestMember(workspaceID, models.WorkspaceRoleViewer)
+			}
+			successor := members[successorID]
+			successor.Role = models.WorkspaceRoleOwner
+			members[successorID] = successor
+		}
+		delete(members, userID)
+		s.handOverNotes(workspaceID, userID)
+	}
+}
@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"errors"
+	"noteserver/internal/pkg/models"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrNotebookNotFound = errors.New("No matching notebooks found")
+	ErrNotebookExists   = errors.New("A notebook with this name already exists here")
+	ErrNotebookNotEmpty = errors.New("Notebook is not empty")
+	ErrNotebookCycle    = errors.New("A notebook cannot be moved into itself or its descendants")
+)
+
+// NotebookDeleteMode decides what happens to the contents of a deleted
+// notebook.
+type NotebookDeleteMode string
+
+const (
+	// DeleteRestrict refuses to delete notebooks that contain notes or
+	// other notebooks.
+	DeleteRestrict NotebookDeleteMode = "restrict"
+	// DeleteCascade deletes nested notebooks and moves their notes to the
+	// trash. Workspace notes the user can no longer edit become unfiled.
+	DeleteCascade NotebookDeleteMode = "cascade"
+	// DeleteReparent moves notes and nested notebooks to the parent of the
+	// deleted notebook.
+	DeleteReparent NotebookDeleteMode = "reparent"
+)
+
+const NotebookPathSeparator = "/"
+
+// SetNotebookPaths fills Path of every notebook from the names of its
+// ancestors, e.g. "Projects/Backend/Design", and sorts notebooks by path.
+func SetNotebookPaths(notebooks []models.Notebook) {
+	byID := make(map[int]*models.Notebook, len(notebooks))
+	for i := range notebooks {
+		byID[notebooks[i].ID] = &notebooks[i]
+	}
+	for i := range notebooks {
+		var names []string
+		visited := make(map[int]bool)
+		for current := &notebooks[i]; current != nil && !visited[current.ID]; {
+			visited[current.ID] = true
+			names = append([]string{current.Name}, names...)
+			if current.ParentID == nil {
+				break
+			}
+			current = byID[*current.ParentID]
+		}
+		notebooks[i].Path = strings.Join(names, NotebookPathSeparator)
+	}
+	sort.Slice(notebooks, func(i, j int) bool {
+		return strings.ToLower(notebooks[i].Path) < strings.ToLower(notebooks[j].Path)
+	})
+}
+
+// NotebookSubtree returns the ids of a notebook and all notebooks nested in
+// it, in breadth-first order.
+func NotebookSubtree(notebooks []models.Notebook, rootID int) []int {
+	children := make(map[int][]int)
+	for _, notebook := range notebooks {
+		if notebook.ParentID != nil {
+			children[*notebook.ParentID] = append(children[*notebook.ParentID], notebook.ID)
+		}
+	}
+	subtree := []int{rootID}
+	for i := 0; i < len(subtree); i++ {
+		subtree = append(subtree, children[subtree[i]]...)
+	}
+	return subtree
+}
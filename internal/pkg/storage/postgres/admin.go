@@ -0,0 +1,110 @@
+package postgres
+
+import (
+	"context"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/storage"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+// userStatsQuery selects users with the size of their notes, trashed notes
+// and revisions included.
+const userStatsQuery = `
+	SELECT ` + userColumns + `, stats.note_count, stats.trash_count, stats.storage_bytes
+	FROM users
+	LEFT JOIN LATERAL (
+		SELECT
+			COUNT(*) FILTER (WHERE n.deleted_at IS NULL) AS note_count,
+			COUNT(*) FILTER (WHERE n.deleted_at IS NOT NULL) AS trash_count,
+			COALESCE(SUM(
+				OCTET_LENGTH(n.title) + COALESCE(OCTET_LENGTH(n.content), 0) +
+				(SELECT COALESCE(SUM(OCTET_LENGTH(r.title) + COALESCE(OCTET_LENGTH(r.content), 0)), 0)
+				 FROM note_revisions r WHERE r.note_id = n.note_id)
+			), 0)::BIGINT AS storage_bytes
+		FROM notes n WHERE n.user_id = users.user_id
+	) stats ON TRUE`
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func (s *Store) ListUsers(ctx context.Context, query storage.UserQuery) ([]models.UserStats, error) {
+	sql := userStatsQuery
+	var args []interface{}
+	if query.Search != "" {
+		args = append(args, "%"+likeEscaper.Replace(query.Search)+"%")
+		sql += " WHERE username ILIKE $1 OR email ILIKE $1"
+	}
+	sql += " ORDER BY user_id"
+	if query.Limit > 0 {
+		args = append(args, query.Limit)
+		sql += " LIMIT $" + strconv.Itoa(len(args))
+	}
+	if query.Offset > 0 {
+		args = append(args, query.Offset)
+		sql += " OFFSET $" + strconv.Itoa(len(args))
+	}
+
+	rows, err := s.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []models.UserStats{}
+	for rows.Next() {
+		var stats models.UserStats
+		if err := rows.Scan(userStatsFields(&stats)...); err != nil {
+			return nil, err
+		}
+		users = append(users, stats)
+	}
+	return users, rows.Err()
+}
+
+func (s *Store) GetUserStats(ctx context.Context, userID int) (*models.UserStats, error) {
+	var stats models.UserStats
+	err := s.db.QueryRow(ctx, userStatsQuery+" WHERE user_id = $1", userID).Scan(userStatsFields(&stats)...)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+func userStatsFields(stats *models.UserStats) []interface{} {
+	return append(userFields(&stats.User), &stats.NoteCount, &stats.TrashCount, &stats.StorageBytes)
+}
+
+func (s *Store) SetUserRole(ctx context.Context, userID int, role string) error {
+	result, err := s.db.Exec(ctx, "UPDATE users SET role = $1 WHERE user_id = $2", role, userID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return storage.ErrUserNotFound
+	}
+	return nil
+}
+
+func (s *Store) SetUserDisabled(ctx context.Context, userID int, disabled bool) error {
+	var result pgconn.CommandTag
+	var err error
+	if disabled {
+		result, err = s.db.Exec(ctx, "UPDATE users SET disabled_at = COALESCE(disabled_at, $1) WHERE user_id = $2", time.Now(), userID)
+	} else {
+		result, err = s.db.Exec(ctx, "UPDATE users SET disabled_at = NULL WHERE user_id = $1", userID)
+	}
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return storage.ErrUserNotFound
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package postgres
+
+import (
+	"context"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/storage"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+func (s *Store) GetUserByIdentity(ctx context.Context, provider string, subject string) (*models.User, error) {
+	return s.getUser(ctx, "user_id = (SELECT user_id FROM identities WHERE provider = $1 AND subject = $2)", provider, subject)
+}
+
+func (s *Store) ListIdentities(ctx context.Context, userID int) ([]models.Identity, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT identity_id, user_id, provider, subject, created_at
+		FROM identities WHERE user_id = $1 ORDER BY provider`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	identities := []models.Identity{}
+	for rows.Next() {
+		var identity models.Identity
+		err := rows.Scan(&identity.ID, &identity.UserID, &identity.Provider, &identity.Subject, &identity.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		identities = append(identities, identity)
+	}
+	return identities, rows.Err()
+}
+
+func (s *Store) LinkIdentity(ctx context.Context, identity *models.Identity) error {
+	return insertIdentity(ctx, s.db, identity)
+}
+
+func (s *Store) RegisterWithIdentity(ctx context.Context, user *models.User, identity *models.Identity) error {
+	return s.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if err := insertUser(ctx, tx, user); err != nil {
+			return err
+		}
+		identity.UserID = user.ID
+		return insertIdentity(ctx, tx, identity)
+	})
+}
+
+func insertIdentity(ctx context.Context, db queryer, identity *models.Identity) error {
+	identity.CreatedAt = time.Now()
+	err := db.QueryRow(ctx, `
+		INSERT INTO identities (user_id, provider, subject, created_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING identity_id`,
+		identity.UserID, identity.Provider, identity.Subject, identity.CreatedAt,
+	).Scan(&identity.ID)
+	if isUniqueViolation(err) {
+		return storage.ErrIdentityExists
+	}
+	return err
+}
+
+func (s *Store) UnlinkIdentity(ctx context.Context, userID int, provider string) error {
+	result, err := s.db.Exec(ctx, "DELETE FROM identities WHERE user_id = $1 AND provider = $2", userID, provider)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return storage.ErrIdentityNotFound
+	}
+	return nil
+}
+
+func (s *Store) CreateOIDCLogin(ctx context.Context, login *models.OIDCLogin) error {
+	login.CreatedAt = time.Now()
+	_, err := s.db.Exec(ctx, `
+		INSERT INTO oidc_logins (state_hash, provider, nonce, verifier, link_user_id, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, 0), $6, $7)`,
+		login.StateHash, login.Provider, login.Nonce, login.Verifier, login.LinkUserID, login.CreatedAt, login.ExpiresAt)
+	return err
+}
+
+func (s *Store) ConsumeOIDCLogin(ctx context.Context, stateHash string, now time.Time) (*models.OIDCLogin, error) {
+	var login models.OIDCLogin
+	err := s.db.QueryRow(ctx, `
+		DELETE FROM oidc_logins WHERE state_hash = $1 AND expires_at > $2
+		RETURNING state_hash, provider, nonce, verifier, COALESCE(link_user_id, 0), created_at, expires_at`,
+		stateHash, now,
+	).Scan(&login.StateHash, &login.Provider, &login.Nonce, &login.Verifier, &login.LinkUserID, &login.CreatedAt, &login.ExpiresAt)
+	if err == pgx.ErrNoRows {
+		return nil, storage.ErrOIDCLoginNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &login, nil
+}
@@ -0,0 +1,74 @@
+package postgres
+
+import (
+	"context"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/storage"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+func (s *Store) CreateInvite(ctx context.Context, invite *models.Invite) error {
+	invite.CreatedAt = time.Now()
+	return s.db.QueryRow(ctx, `
+		INSERT INTO invites (created_by, code_hash, created_at, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING invite_id`,
+		invite.CreatedBy, invite.CodeHash, invite.CreatedAt, invite.ExpiresAt,
+	).Scan(&invite.ID)
+}
+
+func (s *Store) ListInvites(ctx context.Context, userID int) ([]models.Invite, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT invite_id, created_by, code_hash, created_at, expires_at, used_at
+		FROM invites WHERE created_by = $1 ORDER BY invite_id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invites := []models.Invite{}
+	for rows.Next() {
+		var invite models.Invite
+		err := rows.Scan(&invite.ID, &invite.CreatedBy, &invite.CodeHash, &invite.CreatedAt, &invite.ExpiresAt, &invite.UsedAt)
+		if err != nil {
+			return nil, err
+		}
+		invites = append(invites, invite)
+	}
+	return invites, rows.Err()
+}
+
+func (s *Store) DeleteInvite(ctx context.Context, userID int, inviteID int) error {
+	result, err := s.db.Exec(ctx, "DELETE FROM invites WHERE invite_id = $1 AND created_by = $2", inviteID, userID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return storage.ErrInviteNotFound
+	}
+	return nil
+}
+
+func (s *Store) RegisterWithInvite(ctx context.Context, user models.User, codeHash string, now time.Time) error {
+	return s.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		var inviteID int
+		err := tx.QueryRow(ctx, `
+			UPDATE invites SET used_at = $2
+			WHERE code_hash = $1 AND used_at IS NULL AND expires_at > $2
+			RETURNING invite_id`,
+			codeHash, now).Scan(&inviteID)
+		if err == pgx.ErrNoRows {
+			return storage.ErrInviteNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if err := insertUser(ctx, tx, &user); err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, "UPDATE invites SET used_by = $1 WHERE invite_id = $2", user.ID, inviteID)
+		return err
+	})
+}
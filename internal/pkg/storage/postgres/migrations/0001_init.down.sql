@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS Notes;
+DROP TABLE IF EXISTS Users;
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS Users (
+  user_id SERIAL PRIMARY KEY,
+  username VARCHAR(50) NOT NULL,
+  password_hash VARCHAR(100) NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS Notes (
+  note_id SERIAL PRIMARY KEY,
+  user_id INT REFERENCES Users(user_id),
+  title VARCHAR(100) NOT NULL,
+  content TEXT,
+  created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS notes_user_id_idx ON Notes (user_id);
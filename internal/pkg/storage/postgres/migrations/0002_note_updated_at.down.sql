@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS notes_user_title_idx;
+DROP INDEX IF EXISTS notes_user_updated_idx;
+DROP INDEX IF EXISTS notes_user_created_idx;
+
+ALTER TABLE Notes DROP COLUMN updated_at;
+ALTER TABLE Notes ALTER COLUMN created_at DROP NOT NULL;
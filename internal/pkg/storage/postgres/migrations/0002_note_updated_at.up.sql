@@ -0,0 +1,11 @@
+UPDATE Notes SET created_at = NOW() WHERE created_at IS NULL;
+ALTER TABLE Notes ALTER COLUMN created_at SET NOT NULL;
+
+ALTER TABLE Notes ADD COLUMN updated_at TIMESTAMP;
+UPDATE Notes SET updated_at = created_at;
+ALTER TABLE Notes ALTER COLUMN updated_at SET NOT NULL;
+ALTER TABLE Notes ALTER COLUMN updated_at SET DEFAULT NOW();
+
+CREATE INDEX notes_user_created_idx ON Notes (user_id, created_at, note_id);
+CREATE INDEX notes_user_updated_idx ON Notes (user_id, updated_at, note_id);
+CREATE INDEX notes_user_title_idx ON Notes (user_id, title, note_id);
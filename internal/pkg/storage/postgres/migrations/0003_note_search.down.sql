@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS notes_search_idx;
+ALTER TABLE Notes DROP COLUMN search_vector;
@@ -0,0 +1,6 @@
+ALTER TABLE Notes ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+  setweight(to_tsvector('simple', COALESCE(title, '')), 'A') ||
+  setweight(to_tsvector('simple', COALESCE(content, '')), 'B')
+) STORED;
+
+CREATE INDEX notes_search_idx ON Notes USING GIN (search_vector);
@@ -0,0 +1,10 @@
+CREATE TABLE note_revisions (
+  revision_id SERIAL PRIMARY KEY,
+  note_id INT NOT NULL REFERENCES Notes(note_id) ON DELETE CASCADE,
+  editor_id INT REFERENCES Users(user_id) ON DELETE SET NULL,
+  title VARCHAR(100) NOT NULL,
+  content TEXT,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX note_revisions_note_idx ON note_revisions (note_id, revision_id);
@@ -0,0 +1,3 @@
+DELETE FROM Notes WHERE deleted_at IS NOT NULL;
+DROP INDEX IF EXISTS notes_deleted_at_idx;
+ALTER TABLE Notes DROP COLUMN deleted_at;
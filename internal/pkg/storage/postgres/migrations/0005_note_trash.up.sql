@@ -0,0 +1,3 @@
+ALTER TABLE Notes ADD COLUMN deleted_at TIMESTAMP;
+
+CREATE INDEX notes_deleted_at_idx ON Notes (deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,15 @@
+CREATE TABLE tags (
+  tag_id SERIAL PRIMARY KEY,
+  user_id INT NOT NULL REFERENCES Users(user_id) ON DELETE CASCADE,
+  name VARCHAR(50) NOT NULL
+);
+
+CREATE UNIQUE INDEX tags_user_name_idx ON tags (user_id, LOWER(name));
+
+CREATE TABLE note_tags (
+  note_id INT NOT NULL REFERENCES Notes(note_id) ON DELETE CASCADE,
+  tag_id INT NOT NULL REFERENCES tags(tag_id) ON DELETE CASCADE,
+  PRIMARY KEY (note_id, tag_id)
+);
+
+CREATE INDEX note_tags_tag_idx ON note_tags (tag_id);
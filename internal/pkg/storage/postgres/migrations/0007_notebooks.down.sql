@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS notes_notebook_idx;
+ALTER TABLE Notes DROP COLUMN notebook_id;
+DROP TABLE IF EXISTS notebooks;
@@ -0,0 +1,14 @@
+CREATE TABLE notebooks (
+  notebook_id SERIAL PRIMARY KEY,
+  user_id INT NOT NULL REFERENCES Users(user_id) ON DELETE CASCADE,
+  parent_id INT REFERENCES notebooks(notebook_id) ON DELETE CASCADE,
+  name VARCHAR(100) NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX notebooks_sibling_name_idx ON notebooks (user_id, COALESCE(parent_id, 0), LOWER(name));
+CREATE INDEX notebooks_parent_idx ON notebooks (parent_id);
+
+ALTER TABLE Notes ADD COLUMN notebook_id INT REFERENCES notebooks(notebook_id) ON DELETE SET NULL;
+
+CREATE INDEX notes_notebook_idx ON Notes (notebook_id);
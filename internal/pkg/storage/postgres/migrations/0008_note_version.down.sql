@@ -0,0 +1 @@
+ALTER TABLE Notes DROP COLUMN version;
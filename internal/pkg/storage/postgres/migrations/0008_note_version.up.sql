@@ -0,0 +1 @@
+ALTER TABLE Notes ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,13 @@
+CREATE TABLE personal_tokens (
+  token_id SERIAL PRIMARY KEY,
+  user_id INT NOT NULL REFERENCES Users(user_id) ON DELETE CASCADE,
+  name VARCHAR(100) NOT NULL,
+  token_hash VARCHAR(64) NOT NULL UNIQUE,
+  scopes TEXT[] NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  expires_at TIMESTAMP,
+  last_used_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX personal_tokens_name_idx ON personal_tokens (user_id, LOWER(name));
+CREATE INDEX personal_tokens_expires_idx ON personal_tokens (expires_at);
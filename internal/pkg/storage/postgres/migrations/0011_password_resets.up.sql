@@ -0,0 +1,14 @@
+ALTER TABLE Users ADD COLUMN email VARCHAR(254);
+
+CREATE UNIQUE INDEX users_email_idx ON Users (LOWER(email));
+
+CREATE TABLE password_resets (
+  reset_id SERIAL PRIMARY KEY,
+  user_id INT NOT NULL REFERENCES Users(user_id) ON DELETE CASCADE,
+  token_hash VARCHAR(64) NOT NULL UNIQUE,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX password_resets_user_idx ON password_resets (user_id);
+CREATE INDEX password_resets_expires_idx ON password_resets (expires_at);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS invites;
+DROP INDEX IF EXISTS users_username_idx;
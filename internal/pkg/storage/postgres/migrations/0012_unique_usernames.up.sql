@@ -0,0 +1,29 @@
+-- Usernames that differ only in case cannot be told apart any more, so the
+-- migration stops and names them instead of failing on the index.
+DO $$
+DECLARE
+  duplicates TEXT;
+BEGIN
+  SELECT string_agg(names, '; ') INTO duplicates FROM (
+    SELECT string_agg(username || ' (id ' || user_id || ')', ', ' ORDER BY user_id) AS names
+    FROM Users GROUP BY LOWER(username) HAVING COUNT(*) > 1
+  ) conflicts;
+  IF duplicates IS NOT NULL THEN
+    RAISE EXCEPTION 'usernames must be unique regardless of case, rename all but one of each of these accounts and migrate again: %', duplicates;
+  END IF;
+END
+$$;
+
+CREATE UNIQUE INDEX users_username_idx ON Users (LOWER(username));
+
+CREATE TABLE invites (
+  invite_id SERIAL PRIMARY KEY,
+  created_by INT NOT NULL REFERENCES Users(user_id) ON DELETE CASCADE,
+  code_hash VARCHAR(64) NOT NULL UNIQUE,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  expires_at TIMESTAMP NOT NULL,
+  used_at TIMESTAMP,
+  used_by INT REFERENCES Users(user_id) ON DELETE SET NULL
+);
+
+CREATE INDEX invites_created_by_idx ON invites (created_by);
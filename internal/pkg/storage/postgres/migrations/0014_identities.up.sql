@@ -0,0 +1,20 @@
+CREATE TABLE identities (
+  identity_id SERIAL PRIMARY KEY,
+  user_id INT NOT NULL REFERENCES Users(user_id) ON DELETE CASCADE,
+  provider VARCHAR(50) NOT NULL,
+  subject VARCHAR(255) NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX identities_subject_idx ON identities (provider, subject);
+CREATE UNIQUE INDEX identities_user_idx ON identities (user_id, provider);
+
+CREATE TABLE oidc_logins (
+  state_hash VARCHAR(64) PRIMARY KEY,
+  provider VARCHAR(50) NOT NULL,
+  nonce VARCHAR(64) NOT NULL,
+  verifier VARCHAR(128) NOT NULL,
+  link_user_id INT REFERENCES Users(user_id) ON DELETE CASCADE,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  expires_at TIMESTAMP NOT NULL
+);
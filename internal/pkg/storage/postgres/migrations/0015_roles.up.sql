@@ -0,0 +1,2 @@
+ALTER TABLE Users ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'user';
+ALTER TABLE Users ADD COLUMN disabled_at TIMESTAMP;
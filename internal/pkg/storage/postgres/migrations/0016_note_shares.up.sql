@@ -0,0 +1,9 @@
+CREATE TABLE note_shares (
+  note_id INT NOT NULL REFERENCES Notes(note_id) ON DELETE CASCADE,
+  user_id INT NOT NULL REFERENCES Users(user_id) ON DELETE CASCADE,
+  permission VARCHAR(10) NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (note_id, user_id)
+);
+
+CREATE INDEX note_shares_user_idx ON note_shares (user_id);
@@ -0,0 +1,12 @@
+CREATE TABLE share_links (
+  link_id SERIAL PRIMARY KEY,
+  note_id INT NOT NULL REFERENCES Notes(note_id) ON DELETE CASCADE,
+  token_hash VARCHAR(64) NOT NULL UNIQUE,
+  password_hash VARCHAR(255),
+  views BIGINT NOT NULL DEFAULT 0,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  expires_at TIMESTAMP,
+  last_viewed_at TIMESTAMP
+);
+
+CREATE INDEX share_links_note_idx ON share_links (note_id);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS notes_workspace_idx;
+ALTER TABLE Notes DROP COLUMN workspace_id;
+DROP TABLE IF EXISTS workspace_members;
+DROP TABLE IF EXISTS workspaces;
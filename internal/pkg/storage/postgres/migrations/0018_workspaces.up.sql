@@ -0,0 +1,19 @@
+CREATE TABLE workspaces (
+  workspace_id SERIAL PRIMARY KEY,
+  name VARCHAR(100) NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE workspace_members (
+  workspace_id INT NOT NULL REFERENCES workspaces(workspace_id) ON DELETE CASCADE,
+  user_id INT NOT NULL REFERENCES Users(user_id) ON DELETE CASCADE,
+  role VARCHAR(10) NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (workspace_id, user_id)
+);
+
+CREATE INDEX workspace_members_user_idx ON workspace_members (user_id);
+
+ALTER TABLE Notes ADD COLUMN workspace_id INT REFERENCES workspaces(workspace_id) ON DELETE SET NULL;
+
+CREATE INDEX notes_workspace_idx ON Notes (workspace_id);
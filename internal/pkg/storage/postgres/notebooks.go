@@ -0,0 +1,213 @@
+package postgres
+
+import (
+	"context"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/storage"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+func (s *Store) CreateNotebook(ctx context.Context, user *models.User, notebook *models.Notebook) (int, error) {
+	var notebookID int
+	err := s.db.QueryRow(ctx, `
+		INSERT INTO notebooks (user_id, parent_id, name, created_at)
+		SELECT $1, $2, $3, $4
+		WHERE $2::int IS NULL OR EXISTS (SELECT 1 FROM notebooks WHERE notebook_id = $2 AND user_id = $1)
+		RETURNING notebook_id`,
+		user.ID, notebook.ParentID, notebook.Name, time.Now()).Scan(&notebookID)
+	if err == pgx.ErrNoRows {
+		return 0, storage.ErrNotebookNotFound
+	}
+	if isUniqueViolation(err) {
+		return 0, storage.ErrNotebookExists
+	}
+	if err != nil {
+		return 0, err
+	}
+	return notebookID, nil
+}
+
+func (s *Store) ListNotebooks(ctx context.Context, user *models.User) ([]models.Notebook, error) {
+	return listNotebooks(ctx, s.db, user, "")
+}
+
+func (s *Store) UpdateNotebook(ctx context.Context, user *models.User, notebook *models.Notebook) error {
+	return s.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		notebooks, err := listNotebooks(ctx, tx, user, "FOR UPDATE OF nb")
+		if err != nil {
+			return err
+		}
+		if !containsNotebook(notebooks, notebook.ID) {
+			return storage.ErrNotebookNotFound
+		}
+		if notebook.ParentID != nil {
+			if !containsNotebook(notebooks, *notebook.ParentID) {
+				return storage.ErrNotebookNotFound
+			}
+			for _, id := range storage.NotebookSubtree(notebooks, notebook.ID) {
+				if id == *notebook.ParentID {
+					return storage.ErrNotebookCycle
+				}
+			}
+		}
+
+		_, err = tx.Exec(ctx,
+			"UPDATE notebooks SET name = $1, parent_id = $2 WHERE notebook_id = $3",
+			notebook.Name, notebook.ParentID, notebook.ID)
+		if isUniqueViolation(err) {
+			return storage.ErrNotebookExists
+		}
+		return err
+	})
+}
+
+func (s *Store) DeleteNotebook(ctx context.Context, user *models.User, notebookID int, mode storage.NotebookDeleteMode) error {
+	return s.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		notebooks, err := listNotebooks(ctx, tx, user, "FOR UPDATE OF nb")
+		if err != nil {
+			return err
+		}
+		var deleted *models.Notebook
+		for i := range notebooks {
+			if notebooks[i].ID == notebookID {
+				deleted = &notebooks[i]
+			}
+		}
+		if deleted == nil {
+			return storage.ErrNotebookNotFound
+		}
+
+		switch mode {
+		case storage.DeleteCascade:
+			_, err = tx.Exec(ctx,
+				"UPDATE Notes SET deleted_at = $1 WHERE notebook_id = ANY($2) AND "+authoredNote("$3")+" AND deleted_at IS NULL",
+				time.Now(), storage.NotebookSubtree(notebooks, notebookID), user.ID)
+			if err != nil {
+				return err
+			}
+		case storage.DeleteReparent:
+			_, err = tx.Exec(ctx, "UPDATE notebooks SET parent_id = $1 WHERE parent_id = $2", deleted.ParentID, notebookID)
+			if isUniqueViolation(err) {
+				return storage.ErrNotebookExists
+			}
+			if err != nil {
+				return err
+			}
+			_, err = tx.Exec(ctx, "UPDATE Notes SET notebook_id = $1, version = version + 1, updated_at = $3 WHERE notebook_id = $2",
+				deleted.ParentID, notebookID, time.Now())
+			if err != nil {
+				return err
+			}
+		default:
+			if len(storage.NotebookSubtree(notebooks, notebookID)) > 1 || deleted.NoteCount > 0 {
+				return storage.ErrNotebookNotEmpty
+			}
+		}
+
+		// Nested notebooks are removed by the foreign key cascade, notes
+		// left in them become unfiled.
+		_, err = tx.Exec(ctx, "DELETE FROM notebooks WHERE notebook_id = $1", notebookID)
+		return err
+	})
+}
+
+func (s *Store) MoveNote(ctx context.Context, user *models.User, noteID int, notebookID *int) error {
+	return s.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if err := checkNotebook(ctx, tx, user, notebookID); err != nil {
+			return err
+		}
+		result, err := tx.Exec(ctx,
+			"UPDATE Notes SET notebook_id = $1, version = version + 1, updated_at = $4 WHERE note_id = $2 AND "+authoredNote("$3")+" AND deleted_at IS NULL",
+			notebookID, noteID, user.ID, time.Now())
+		if err != nil {
+			return err
+		}
+		if result.RowsAffected() == 0 {
+			return storage.ErrNoteNotFound
+		}
+		return nil
+	})
+}
+
+func (s *Store) CopyNote(ctx context.Context, user *models.User, noteID int, notebookID *int) (int, error) {
+	var copyID int
+	err := s.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if err := checkNotebook(ctx, tx, user, notebookID); err != nil {
+			return err
+		}
+		now := time.Now()
+		err := tx.QueryRow(ctx, `
+			INSERT INTO Notes (user_id, notebook_id, title, content, created_at, updated_at)
+			SELECT user_id, $1, title, content, $2, $2 FROM Notes
+			WHERE note_id = $3 AND `+authoredNote("$4")+` AND deleted_at IS NULL
+			RETURNING note_id`,
+			notebookID, now, noteID, user.ID).Scan(&copyID)
+		if err == pgx.ErrNoRows {
+			return storage.ErrNoteNotFound
+		}
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx,
+			"INSERT INTO note_tags (note_id, tag_id) SELECT $1, tag_id FROM note_tags WHERE note_id = $2",
+			copyID, noteID)
+		return err
+	})
+	return copyID, err
+}
+
+func listNotebooks(ctx context.Context, db queryer, user *models.User, lock string) ([]models.Notebook, error) {
+	rows, err := db.Query(ctx, `
+		SELECT nb.notebook_id, nb.user_id, nb.parent_id, nb.name, nb.created_at,
+			(SELECT COUNT(*) FROM Notes n WHERE n.notebook_id = nb.notebook_id AND n.deleted_at IS NULL)
+		FROM notebooks nb
+		WHERE nb.user_id = $1 `+lock,
+		user.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notebooks := []models.Notebook{}
+	for rows.Next() {
+		var notebook models.Notebook
+		err := rows.Scan(&notebook.ID, &notebook.UserID, &notebook.ParentID, &notebook.Name, &notebook.CreatedAt, &notebook.NoteCount)
+		if err != nil {
+			return nil, err
+		}
+		notebooks = append(notebooks, notebook)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	storage.SetNotebookPaths(notebooks)
+	return notebooks, nil
+}
+
+func checkNotebook(ctx context.Context, db queryer, user *models.User, notebookID *int) error {
+	if notebookID == nil {
+		return nil
+	}
+	var exists bool
+	err := db.QueryRow(ctx,
+		"SELECT EXISTS (SELECT 1 FROM notebooks WHERE notebook_id = $1 AND user_id = $2)",
+		*notebookID, user.ID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return storage.ErrNotebookNotFound
+	}
+	return nil
+}
+
+func containsNotebook(notebooks []models.Notebook, notebookID int) bool {
+	for _, notebook := range notebooks {
+		if notebook.ID == notebookID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,309 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/storage"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	noteTagsColumn = "ARRAY(SELECT t.name FROM note_tags nt JOIN tags t ON t.tag_id = nt.tag_id " +
+		"WHERE nt.note_id = Notes.note_id ORDER BY LOWER(t.name))"
+	noteColumns = "note_id, user_id, notebook_id, workspace_id, title, COALESCE(content, ''), " + noteTagsColumn + ", version, created_at, updated_at, deleted_at"
+	// accessibleNote matches notes of the user $2, notes shared with them
+	// and notes of their workspaces.
+	accessibleNote = "(user_id = $2 OR note_id IN (SELECT note_id FROM note_shares WHERE user_id = $2) " +
+		"OR workspace_id IN (SELECT workspace_id FROM workspace_members WHERE user_id = $2))"
+)
+
+// authoredNote matches the notes that the user in param wrote and may still
+// change: their personal notes, and notes of workspaces in which they are
+// an owner or editor.
+func authoredNote(param string) string {
+	return "(user_id = " + param + " AND (workspace_id IS NULL OR workspace_id IN (SELECT workspace_id FROM workspace_members " +
+		"WHERE user_id = " + param + " AND role IN ('" + models.WorkspaceRoleOwner + "', '" + models.WorkspaceRoleEditor + "'))))"
+}
+
+func scanNote(row pgx.Row, note *models.Note) error {
+	return row.Scan(&note.ID, &note.UserID, &note.NotebookID, &note.WorkspaceID, &note.Title, &note.Content, &note.Tags,
+		&note.Version, &note.CreatedAt, &note.UpdatedAt, &note.DeletedAt)
+}
+
+func (s *Store) ReadNote(ctx context.Context, user *models.User, noteID int) (models.Note, error) {
+	var readnote models.Note
+	err := scanNote(s.db.QueryRow(
+		ctx,
+		"SELECT "+noteColumns+" FROM Notes WHERE note_id = $1 AND "+accessibleNote+" AND deleted_at IS NULL",
+		noteID, user.ID,
+	), &readnote)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return models.Note{}, storage.ErrNoteNotFound
+		}
+		return models.Note{}, err
+	}
+
+	return readnote, nil
+}
+
+func (s *Store) DeleteNote(ctx context.Context, user *models.User, noteID int, version int) error {
+	return s.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		var current, ownerID int
+		var workspaceID *int
+		err := tx.QueryRow(
+			ctx,
+			"SELECT version, user_id, workspace_id FROM Notes WHERE note_id = $1 AND "+accessibleNote+" AND deleted_at IS NULL FOR UPDATE",
+			noteID, user.ID,
+		).Scan(&current, &ownerID, &workspaceID)
+		if err == pgx.ErrNoRows {
+			return storage.ErrNoteNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if ownerID != user.ID || workspaceID != nil {
+			if workspaceID == nil {
+				return storage.ErrNotNoteOwner
+			}
+			role, err := workspaceRole(ctx, tx, *workspaceID, user.ID)
+			if err != nil {
+				return err
+			}
+			if role != models.WorkspaceRoleOwner && role != models.WorkspaceRoleEditor {
+				return storage.ErrNotNoteOwner
+			}
+		}
+		if version != 0 && version != current {
+			return storage.ErrVersionMismatch
+		}
+
+		_, err = tx.Exec(ctx, "UPDATE Notes SET deleted_at = $1 WHERE note_id = $2", time.Now(), noteID)
+		return err
+	})
+}
+
+func (s *Store) UpdateNote(ctx context.Context, user *models.User, note *models.Note) error {
+	return s.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		var previous models.Note
+		err := scanNote(tx.QueryRow(
+			ctx,
+			"SELECT "+noteColumns+" FROM Notes WHERE note_id = $1 AND "+accessibleNote+" AND deleted_at IS NULL FOR UPDATE",
+			note.ID, user.ID,
+		), &previous)
+		if err == pgx.ErrNoRows {
+			return storage.ErrNoteNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if previous.UserID != user.ID || previous.WorkspaceID != nil {
+			var editable bool
+			err := tx.QueryRow(ctx, `
+				SELECT EXISTS (SELECT 1 FROM note_shares WHERE note_id = $1 AND user_id = $2 AND permission = $3)
+					OR EXISTS (SELECT 1 FROM workspace_members WHERE workspace_id = $4 AND user_id = $2 AND role IN ($5, $6))`,
+				note.ID, user.ID, models.PermissionEdit, previous.WorkspaceID, models.WorkspaceRoleOwner, models.WorkspaceRoleEditor,
+			).Scan(&editable)
+			if err != nil {
+				return err
+			}
+			if !editable {
+				return storage.ErrNoteReadOnly
+			}
+		}
+		if note.Version != 0 && note.Version != previous.Version {
+			return storage.ErrVersionMismatch
+		}
+
+		now := time.Now()
+		_, err = tx.Exec(
+			ctx,
+			"INSERT INTO note_revisions (note_id, editor_id, title, content, created_at) VALUES ($1, $2, $3, $4, $5)",
+			previous.ID, user.ID, previous.Title, previous.Content, now,
+		)
+		if err != nil {
+			return err
+		}
+
+		return tx.QueryRow(
+			ctx,
+			"UPDATE Notes SET title = $1, content = $2, updated_at = $3, version = version + 1 WHERE note_id = $4 RETURNING version, updated_at",
+			note.Title, note.Content, now, note.ID,
+		).Scan(&note.Version, &note.UpdatedAt)
+	})
+}
+
+func (s *Store) CreateNote(ctx context.Context, user *models.User, note *models.Note) (int, error) {
+	if note.WorkspaceID != nil {
+		role, err := workspaceRole(ctx, s.db, *note.WorkspaceID, user.ID)
+		if err != nil {
+			return 0, err
+		}
+		if role == "" {
+			return 0, storage.ErrWorkspaceNotFound
+		}
+		if role == models.WorkspaceRoleViewer {
+			return 0, storage.ErrWorkspaceReadOnly
+		}
+	}
+
+	var noteID int
+	now := time.Now()
+	err := s.db.QueryRow(ctx, `
+		INSERT INTO Notes(user_id, notebook_id, workspace_id, title, content, created_at, updated_at)
+		SELECT $1, $2, $6, $3, $4, $5, $5
+		WHERE $2::int IS NULL OR EXISTS (SELECT 1 FROM notebooks WHERE notebook_id = $2 AND user_id = $1)
+		RETURNING note_id, version, created_at, updated_at`,
+		user.ID, note.NotebookID, note.Title, note.Content, now, note.WorkspaceID).Scan(&noteID, &note.Version, &note.CreatedAt, &note.UpdatedAt)
+	if err == pgx.ErrNoRows {
+		return 0, storage.ErrNotebookNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	return noteID, nil
+}
+
+func (s *Store) ListNotes(ctx context.Context, user *models.User, opts storage.ListOptions) (storage.NotePage, error) {
+	columns := noteColumns
+	if opts.SummaryOnly {
+		columns = fmt.Sprintf("note_id, user_id, notebook_id, workspace_id, title, LEFT(COALESCE(content, ''), %d), %s, version, created_at, updated_at, deleted_at",
+			storage.SnippetLength+1, noteTagsColumn)
+	}
+
+	sortColumn := "created_at"
+	switch opts.Sort {
+	case storage.SortUpdated:
+		sortColumn = "updated_at"
+	case storage.SortTitle:
+		sortColumn = "title"
+	}
+	direction, comparison := "ASC", ">"
+	if opts.Descending {
+		direction, comparison = "DESC", "<"
+	}
+
+	conditions := []string{"user_id = $1", "workspace_id IS NULL", "deleted_at IS NULL"}
+	args := []interface{}{user.ID}
+	addCondition := func(condition string, values ...interface{}) {
+		placeholders := make([]interface{}, len(values))
+		for i, value := range values {
+			args = append(args, value)
+			placeholders[i] = len(args)
+		}
+		conditions = append(conditions, fmt.Sprintf(condition, placeholders...))
+	}
+	if opts.WorkspaceID != nil {
+		if err := checkMember(ctx, s.db, *opts.WorkspaceID, user.ID); err != nil {
+			return storage.NotePage{}, err
+		}
+		conditions = []string{"deleted_at IS NULL"}
+		addCondition("workspace_id = $%d", *opts.WorkspaceID)
+		conditions = append(conditions, "workspace_id IN (SELECT workspace_id FROM workspace_members WHERE user_id = $1)")
+	}
+	if opts.CreatedAfter != nil {
+		addCondition("created_at >= $%d", *opts.CreatedAfter)
+	}
+	if opts.CreatedBefore != nil {
+		addCondition("created_at < $%d", *opts.CreatedBefore)
+	}
+	if opts.UpdatedAfter != nil {
+		addCondition("updated_at >= $%d", *opts.UpdatedAfter)
+	}
+	if opts.UpdatedBefore != nil {
+		addCondition("updated_at < $%d", *opts.UpdatedBefore)
+	}
+	if len(opts.Tags) > 0 {
+		tagged := "SELECT nt.note_id FROM note_tags nt JOIN tags t ON t.tag_id = nt.tag_id " +
+			"WHERE t.user_id = $1 AND LOWER(t.name) = ANY($%d)"
+		if opts.AnyTag {
+			addCondition("note_id IN ("+tagged+")", opts.Tags)
+		} else {
+			addCondition("note_id IN ("+tagged+" GROUP BY nt.note_id HAVING COUNT(*) = $%d)", opts.Tags, len(opts.Tags))
+		}
+	}
+	if len(opts.NotebookIDs) > 0 {
+		addCondition("notebook_id = ANY($%d)", opts.NotebookIDs)
+	}
+	if opts.Cursor != nil {
+		var value interface{} = opts.Cursor.Value
+		if sortColumn != "title" {
+			cursorTime, err := opts.Cursor.Time()
+			if err != nil {
+				return storage.NotePage{}, err
+			}
+			value = cursorTime
+		}
+		addCondition("("+sortColumn+", note_id) "+comparison+" ($%d, $%d)", value, opts.Cursor.ID)
+	}
+
+	query := "SELECT " + columns + " FROM Notes WHERE " + strings.Join(conditions, " AND ") +
+		" ORDER BY " + sortColumn + " " + direction + ", note_id " + direction
+	if opts.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", opts.Limit+1)
+	}
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return storage.NotePage{}, err
+	}
+	defer rows.Close()
+
+	var page storage.NotePage
+	for rows.Next() {
+		var note models.Note
+		if err := scanNote(rows, &note); err != nil {
+			return storage.NotePage{}, err
+		}
+		page.Notes = append(page.Notes, note)
+	}
+	if err := rows.Err(); err != nil {
+		return storage.NotePage{}, err
+	}
+
+	if opts.Limit > 0 && len(page.Notes) > opts.Limit {
+		page.Notes = page.Notes[:opts.Limit]
+		page.Next = storage.NewCursor(opts, page.Notes[opts.Limit-1])
+	}
+	return page, nil
+}
+
+// touchNote marks a note as modified after its tags changed.
+func touchNote(ctx context.Context, db queryer, noteID int) error {
+	_, err := db.Exec(ctx, "UPDATE Notes SET version = version + 1, updated_at = $1 WHERE note_id = $2", time.Now(), noteID)
+	return err
+}
+
+// touchTaggedNotes marks the notes carrying a tag as modified before the tag
+// is renamed or deleted.
+func touchTaggedNotes(ctx context.Context, db queryer, user *models.User, name string) error {
+	_, err := db.Exec(ctx, `
+		UPDATE Notes SET version = version + 1, updated_at = $3
+		WHERE note_id IN (
+			SELECT nt.note_id FROM note_tags nt JOIN tags t ON t.tag_id = nt.tag_id
+			WHERE t.user_id = $1 AND LOWER(t.name) = LOWER($2)
+		)`,
+		user.ID, name, time.Now())
+	return err
+}
+
+func (s *Store) DeleteAllNotes(ctx context.Context, user *models.User) (int64, error) {
+	var deleted int64
+	err := s.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, "DELETE FROM notes WHERE user_id = $1 AND workspace_id IS NULL", user.ID)
+		if err != nil {
+			return err
+		}
+		deleted = result.RowsAffected()
+		if _, err := tx.Exec(ctx, "DELETE FROM tags WHERE user_id = $1", user.ID); err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, "DELETE FROM notebooks WHERE user_id = $1", user.ID)
+		return err
+	})
+	return deleted, err
+}
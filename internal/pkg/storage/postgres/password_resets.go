@@ -0,0 +1,42 @@
+package postgres
+
+import (
+	"context"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/storage"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+func (s *Store) CreatePasswordReset(ctx context.Context, reset *models.PasswordReset) error {
+	reset.CreatedAt = time.Now()
+	return s.db.QueryRow(ctx, `
+		INSERT INTO password_resets (user_id, token_hash, created_at, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING reset_id`,
+		reset.UserID, reset.TokenHash, reset.CreatedAt, reset.ExpiresAt,
+	).Scan(&reset.ID)
+}
+
+func (s *Store) ResetPassword(ctx context.Context, tokenHash string, passwordHash string, now time.Time) (int, error) {
+	var userID int
+	err := s.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx,
+			"DELETE FROM password_resets WHERE token_hash = $1 AND expires_at > $2 RETURNING user_id",
+			tokenHash, now).Scan(&userID)
+		if err == pgx.ErrNoRows {
+			return storage.ErrResetTokenNotFound
+		}
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, "DELETE FROM password_resets WHERE user_id = $1", userID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, "UPDATE users SET password_hash = $1 WHERE user_id = $2", passwordHash, userID)
+		return err
+	})
+	return userID, err
+}
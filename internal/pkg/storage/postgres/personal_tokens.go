@@ -0,0 +1,93 @@
+package postgres
+
+import (
+	"context"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/storage"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+const personalTokenColumns = "token_id, user_id, name, token_hash, scopes, created_at, expires_at, last_used_at"
+
+func scanPersonalToken(row pgx.Row, token *models.PersonalToken) error {
+	return row.Scan(personalTokenFields(token)...)
+}
+
+// personalTokenFields are the scan destinations of personalTokenColumns.
+func personalTokenFields(token *models.PersonalToken) []interface{} {
+	return []interface{}{&token.ID, &token.UserID, &token.Name, &token.TokenHash, &token.Scopes,
+		&token.CreatedAt, &token.ExpiresAt, &token.LastUsedAt}
+}
+
+func (s *Store) CreatePersonalToken(ctx context.Context, token *models.PersonalToken) error {
+	token.CreatedAt = time.Now()
+	err := s.db.QueryRow(ctx, `
+		INSERT INTO personal_tokens (user_id, name, token_hash, scopes, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING token_id`,
+		token.UserID, token.Name, token.TokenHash, token.Scopes, token.CreatedAt, token.ExpiresAt,
+	).Scan(&token.ID)
+	if isUniqueViolation(err) {
+		return storage.ErrPersonalTokenExists
+	}
+	return err
+}
+
+func (s *Store) ListPersonalTokens(ctx context.Context, userID int) ([]models.PersonalToken, error) {
+	rows, err := s.db.Query(ctx,
+		"SELECT "+personalTokenColumns+" FROM personal_tokens WHERE user_id = $1 ORDER BY token_id", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []models.PersonalToken{}
+	for rows.Next() {
+		var token models.PersonalToken
+		if err := scanPersonalToken(rows, &token); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+func (s *Store) UsePersonalToken(ctx context.Context, tokenHash string, now time.Time) (*models.PersonalToken, *models.User, error) {
+	var token models.PersonalToken
+	var user models.User
+	err := s.db.QueryRow(ctx, `
+		WITH used AS (
+			UPDATE personal_tokens SET last_used_at = $2
+			WHERE token_hash = $1 AND (expires_at IS NULL OR expires_at > $2)
+			RETURNING `+personalTokenColumns+`
+		), owner AS (
+			SELECT `+userColumns+` FROM users WHERE user_id = (SELECT user_id FROM used)
+		)
+		SELECT used.*, owner.* FROM used, owner`,
+		tokenHash, now).Scan(append(personalTokenFields(&token), userFields(&user)...)...)
+	if err == pgx.ErrNoRows {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return &token, &user, nil
+}
+
+func (s *Store) DeletePersonalToken(ctx context.Context, userID int, tokenID int) error {
+	result, err := s.db.Exec(ctx, "DELETE FROM personal_tokens WHERE token_id = $1 AND user_id = $2", tokenID, userID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return storage.ErrPersonalTokenNotFound
+	}
+	return nil
+}
+
+func (s *Store) DeleteUserPersonalTokens(ctx context.Context, userID int) error {
+	_, err := s.db.Exec(ctx, "DELETE FROM personal_tokens WHERE user_id = $1", userID)
+	return err
+}
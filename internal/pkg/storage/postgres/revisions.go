@@ -0,0 +1,60 @@
+package postgres
+
+import (
+	"context"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/storage"
+
+	"github.com/jackc/pgx/v4"
+)
+
+const revisionColumns = "r.revision_id, r.note_id, r.editor_id, r.title, COALESCE(r.content, ''), r.created_at"
+
+func scanRevision(row pgx.Row, revision *models.NoteRevision) error {
+	return row.Scan(&revision.ID, &revision.NoteID, &revision.EditorID, &revision.Title, &revision.Content, &revision.CreatedAt)
+}
+
+func (s *Store) ListRevisions(ctx context.Context, user *models.User, noteID int) ([]models.NoteRevision, error) {
+	if _, err := s.ReadNote(ctx, user, noteID); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(
+		ctx,
+		"SELECT "+revisionColumns+" FROM note_revisions r WHERE r.note_id = $1 ORDER BY r.revision_id DESC",
+		noteID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []models.NoteRevision{}
+	for rows.Next() {
+		var revision models.NoteRevision
+		if err := scanRevision(rows, &revision); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+	return revisions, rows.Err()
+}
+
+func (s *Store) ReadRevision(ctx context.Context, user *models.User, noteID int, revisionID int) (models.NoteRevision, error) {
+	var revision models.NoteRevision
+	err := scanRevision(s.db.QueryRow(
+		ctx,
+		"SELECT "+revisionColumns+" FROM note_revisions r JOIN Notes n ON n.note_id = r.note_id "+
+			"WHERE r.revision_id = $1 AND r.note_id = $2 AND n.deleted_at IS NULL "+
+			"AND (n.user_id = $3 OR n.note_id IN (SELECT note_id FROM note_shares WHERE user_id = $3) "+
+			"OR n.workspace_id IN (SELECT workspace_id FROM workspace_members WHERE user_id = $3))",
+		revisionID, noteID, user.ID,
+	), &revision)
+	if err == pgx.ErrNoRows {
+		return models.NoteRevision{}, storage.ErrRevisionNotFound
+	}
+	if err != nil {
+		return models.NoteRevision{}, err
+	}
+	return revision, nil
+}
@@ -0,0 +1,88 @@
+package postgres
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"html"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/storage"
+	"strings"
+)
+
+// highlightMarkers returns the ts_headline options wrapping matches in
+// markers, and a replacer turning the markers into the highlight tags.
+// Headlines are made from the raw text and escaped before the markers are
+// replaced; the markers are random, so that notes cannot contain them.
+func highlightMarkers() (string, *strings.Replacer, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", nil, err
+	}
+	start := "hlstart" + hex.EncodeToString(nonce)
+	stop := "hlstop" + hex.EncodeToString(nonce)
+	options := "StartSel=" + start + ", StopSel=" + stop
+	return options, strings.NewReplacer(start, storage.HighlightStart, stop, storage.HighlightStop), nil
+}
+
+func (s *Store) SearchNotes(ctx context.Context, user *models.User, query storage.SearchQuery, limit, offset int) ([]models.NoteSearchResult, error) {
+	options, markup, err := highlightMarkers()
+	if err != nil {
+		return nil, err
+	}
+	scope := "user_id = $1 AND workspace_id IS NULL"
+	args := []interface{}{user.ID, tsquery(query), limit, offset, options}
+	if query.WorkspaceID != nil {
+		if err := checkMember(ctx, s.db, *query.WorkspaceID, user.ID); err != nil {
+			return nil, err
+		}
+		scope = "workspace_id = $6 AND workspace_id IN (SELECT workspace_id FROM workspace_members WHERE user_id = $1)"
+		args = append(args, *query.WorkspaceID)
+	}
+
+	rows, err := s.db.Query(ctx, `
+		SELECT note_id, title, created_at, updated_at,
+			ts_rank(search_vector, query),
+			ts_headline('simple', title, query, $5 || ', HighlightAll=true'),
+			ts_headline('simple', COALESCE(content, ''), query, $5 || ', MaxWords=35, MinWords=15, MaxFragments=2')
+		FROM Notes, to_tsquery('simple', $2) query
+		WHERE `+scope+` AND deleted_at IS NULL AND search_vector @@ query
+		ORDER BY 5 DESC, note_id DESC
+		LIMIT $3 OFFSET $4`,
+		args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []models.NoteSearchResult{}
+	for rows.Next() {
+		var result models.NoteSearchResult
+		var rank float32
+		err := rows.Scan(&result.ID, &result.Title, &result.CreatedAt, &result.UpdatedAt,
+			&rank, &result.TitleHighlight, &result.Snippet)
+		if err != nil {
+			return nil, err
+		}
+		result.Rank = float64(rank)
+		result.TitleHighlight = markup.Replace(html.EscapeString(result.TitleHighlight))
+		result.Snippet = markup.Replace(html.EscapeString(result.Snippet))
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
+
+// tsquery renders a parsed query in to_tsquery syntax. Words only contain
+// letters and digits, so they need no quoting.
+func tsquery(query storage.SearchQuery) string {
+	terms := make([]string, 0, len(query.Terms))
+	for _, term := range query.Terms {
+		words := make([]string, len(term.Words))
+		copy(words, term.Words)
+		if term.Prefix {
+			words[len(words)-1] += ":*"
+		}
+		terms = append(terms, "("+strings.Join(words, " <-> ")+")")
+	}
+	return strings.Join(terms, " & ")
+}
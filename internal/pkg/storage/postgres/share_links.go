@@ -0,0 +1,113 @@
+package postgres
+
+import (
+	"context"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/storage"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+const shareLinkColumns = "link_id, note_id, token_hash, COALESCE(password_hash, ''), views, created_at, expires_at, last_viewed_at"
+
+func scanShareLink(row pgx.Row, link *models.ShareLink) error {
+	err := row.Scan(&link.ID, &link.NoteID, &link.TokenHash, &link.PasswordHash, &link.Views,
+		&link.CreatedAt, &link.ExpiresAt, &link.LastViewedAt)
+	if err != nil {
+		return err
+	}
+	link.HasPassword = link.PasswordHash != ""
+	return nil
+}
+
+func (s *Store) CreateShareLink(ctx context.Context, owner *models.User, link *models.ShareLink) error {
+	link.CreatedAt = time.Now()
+	link.HasPassword = link.PasswordHash != ""
+	err := s.db.QueryRow(ctx, `
+		INSERT INTO share_links (note_id, token_hash, password_hash, created_at, expires_at)
+		SELECT note_id, $2, NULLIF($3, ''), $4, $5 FROM Notes WHERE note_id = $1 AND `+authoredNote("$6")+` AND deleted_at IS NULL
+		RETURNING link_id`,
+		link.NoteID, link.TokenHash, link.PasswordHash, link.CreatedAt, link.ExpiresAt, owner.ID,
+	).Scan(&link.ID)
+	if err == pgx.ErrNoRows {
+		return storage.ErrNoteNotFound
+	}
+	return err
+}
+
+func (s *Store) ListShareLinks(ctx context.Context, owner *models.User, noteID int) ([]models.ShareLink, error) {
+	if err := ownNote(ctx, s.db, owner, noteID); err != nil {
+		return nil, err
+	}
+	rows, err := s.db.Query(ctx, "SELECT "+shareLinkColumns+" FROM share_links WHERE note_id = $1 ORDER BY link_id", noteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := []models.ShareLink{}
+	for rows.Next() {
+		var link models.ShareLink
+		if err := scanShareLink(rows, &link); err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+	return links, rows.Err()
+}
+
+func (s *Store) DeleteShareLink(ctx context.Context, owner *models.User, noteID int, linkID int) error {
+	if err := ownNote(ctx, s.db, owner, noteID); err != nil {
+		return err
+	}
+	result, err := s.db.Exec(ctx, "DELETE FROM share_links WHERE link_id = $1 AND note_id = $2", linkID, noteID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return storage.ErrShareLinkNotFound
+	}
+	return nil
+}
+
+func (s *Store) GetShareLink(ctx context.Context, tokenHash string, now time.Time) (*models.ShareLink, error) {
+	var link models.ShareLink
+	err := scanShareLink(s.db.QueryRow(ctx, `
+		SELECT `+shareLinkColumns+` FROM share_links
+		WHERE token_hash = $1 AND (expires_at IS NULL OR expires_at > $2)
+		AND note_id IN (SELECT note_id FROM Notes WHERE deleted_at IS NULL)`,
+		tokenHash, now,
+	), &link)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+func (s *Store) ViewShareLink(ctx context.Context, linkID int, now time.Time) (models.Note, error) {
+	var note models.Note
+	err := s.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx,
+			"UPDATE share_links SET views = views + 1, last_viewed_at = $2 WHERE link_id = $1",
+			linkID, now)
+		if err != nil {
+			return err
+		}
+		if result.RowsAffected() == 0 {
+			return storage.ErrNoteNotFound
+		}
+		err = scanNote(tx.QueryRow(ctx,
+			"SELECT "+noteColumns+" FROM Notes WHERE note_id = (SELECT note_id FROM share_links WHERE link_id = $1) AND deleted_at IS NULL",
+			linkID,
+		), &note)
+		if err == pgx.ErrNoRows {
+			return storage.ErrNoteNotFound
+		}
+		return err
+	})
+	return note, err
+}
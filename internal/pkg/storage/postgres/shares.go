@@ -0,0 +1,107 @@
+package postgres
+
+import (
+	"context"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/storage"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+func (s *Store) ShareNote(ctx context.Context, owner *models.User, share *models.NoteShare) error {
+	err := s.db.QueryRow(ctx, `
+		INSERT INTO note_shares (note_id, user_id, permission, created_at)
+		SELECT note_id, $2, $3, $4 FROM Notes WHERE note_id = $1 AND `+authoredNote("$5")+` AND deleted_at IS NULL
+		ON CONFLICT (note_id, user_id) DO UPDATE SET permission = EXCLUDED.permission
+		RETURNING created_at`,
+		share.NoteID, share.UserID, share.Permission, time.Now(), owner.ID,
+	).Scan(&share.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return storage.ErrNoteNotFound
+	}
+	return err
+}
+
+func (s *Store) ListNoteShares(ctx context.Context, owner *models.User, noteID int) ([]models.NoteShare, error) {
+	if err := ownNote(ctx, s.db, owner, noteID); err != nil {
+		return nil, err
+	}
+	rows, err := s.db.Query(ctx, `
+		SELECT s.note_id, s.user_id, u.username, s.permission, s.created_at
+		FROM note_shares s JOIN Users u ON u.user_id = s.user_id
+		WHERE s.note_id = $1 ORDER BY LOWER(u.username)`, noteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shares := []models.NoteShare{}
+	for rows.Next() {
+		var share models.NoteShare
+		if err := rows.Scan(&share.NoteID, &share.UserID, &share.Username, &share.Permission, &share.CreatedAt); err != nil {
+			return nil, err
+		}
+		shares = append(shares, share)
+	}
+	return shares, rows.Err()
+}
+
+func (s *Store) RevokeShare(ctx context.Context, owner *models.User, noteID int, userID int) error {
+	if err := ownNote(ctx, s.db, owner, noteID); err != nil {
+		return err
+	}
+	result, err := s.db.Exec(ctx, "DELETE FROM note_shares WHERE note_id = $1 AND user_id = $2", noteID, userID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return storage.ErrShareNotFound
+	}
+	return nil
+}
+
+func (s *Store) ListSharedNotes(ctx context.Context, user *models.User, limit, offset int) ([]models.SharedNote, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT `+noteColumns+`,
+			(SELECT username FROM Users WHERE Users.user_id = Notes.user_id),
+			(SELECT permission FROM note_shares WHERE note_id = Notes.note_id AND user_id = $1)
+		FROM Notes
+		WHERE note_id IN (SELECT note_id FROM note_shares WHERE user_id = $1) AND deleted_at IS NULL
+		ORDER BY updated_at DESC, note_id DESC
+		LIMIT $2 OFFSET $3`,
+		user.ID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notes := []models.SharedNote{}
+	for rows.Next() {
+		var note models.SharedNote
+		err := rows.Scan(&note.ID, &note.UserID, &note.NotebookID, &note.WorkspaceID, &note.Title, &note.Content, &note.Tags,
+			&note.Version, &note.CreatedAt, &note.UpdatedAt, &note.DeletedAt, &note.Owner, &note.Permission)
+		if err != nil {
+			return nil, err
+		}
+		notes = append(notes, note)
+	}
+	return notes, rows.Err()
+}
+
+// ownNote fails with ErrNoteNotFound unless the user wrote the note, may
+// still change it and it is not in the trash.
+func ownNote(ctx context.Context, db queryer, user *models.User, noteID int) error {
+	var exists bool
+	err := db.QueryRow(ctx,
+		"SELECT EXISTS (SELECT 1 FROM Notes WHERE note_id = $1 AND "+authoredNote("$2")+" AND deleted_at IS NULL)",
+		noteID, user.ID,
+	).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return storage.ErrNoteNotFound
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package postgres
+
+import (
+	"context"
+	"noteserver/internal/pkg/storage"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type Store struct {
+	db *pgxpool.Pool
+}
+
+var _ storage.Store = (*Store)(nil)
+
+func New(db *pgxpool.Pool) *Store {
+	return &Store{db: db}
+}
+
+// queryer is implemented by both the pool and transactions.
+type queryer interface {
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
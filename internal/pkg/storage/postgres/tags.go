@@ -0,0 +1,139 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/storage"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+const uniqueViolation = "23505"
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation
+}
+
+func (s *Store) ListTags(ctx context.Context, user *models.User) ([]models.Tag, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT t.name, COUNT(n.note_id)
+		FROM tags t
+		LEFT JOIN note_tags nt ON nt.tag_id = t.tag_id
+		LEFT JOIN Notes n ON n.note_id = nt.note_id AND n.deleted_at IS NULL
+		WHERE t.user_id = $1
+		GROUP BY t.tag_id, t.name
+		ORDER BY LOWER(t.name)`,
+		user.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []models.Tag{}
+	for rows.Next() {
+		var tag models.Tag
+		if err := rows.Scan(&tag.Name, &tag.Count); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+func (s *Store) AddNoteTags(ctx context.Context, user *models.User, noteID int, names []string) error {
+	return s.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		var exists bool
+		err := tx.QueryRow(ctx,
+			"SELECT EXISTS (SELECT 1 FROM Notes WHERE note_id = $1 AND "+authoredNote("$2")+" AND deleted_at IS NULL)",
+			noteID, user.ID).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return storage.ErrNoteNotFound
+		}
+
+		if err := touchNote(ctx, tx, noteID); err != nil {
+			return err
+		}
+		for _, name := range names {
+			_, err := tx.Exec(ctx,
+				"INSERT INTO tags (user_id, name) VALUES ($1, $2) ON CONFLICT (user_id, LOWER(name)) DO NOTHING",
+				user.ID, name)
+			if err != nil {
+				return err
+			}
+			_, err = tx.Exec(ctx, `
+				INSERT INTO note_tags (note_id, tag_id)
+				SELECT $1, tag_id FROM tags WHERE user_id = $2 AND LOWER(name) = LOWER($3)
+				ON CONFLICT DO NOTHING`,
+				noteID, user.ID, name)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *Store) RemoveNoteTag(ctx context.Context, user *models.User, noteID int, name string) error {
+	return s.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		result, err := tx.Exec(ctx, `
+			DELETE FROM note_tags nt
+			USING tags t, Notes n
+			WHERE nt.tag_id = t.tag_id AND nt.note_id = n.note_id
+				AND n.note_id = $1 AND n.deleted_at IS NULL
+				AND n.note_id IN (SELECT note_id FROM Notes WHERE `+authoredNote("$2")+`)
+				AND t.user_id = $2 AND LOWER(t.name) = LOWER($3)`,
+			noteID, user.ID, name)
+		if err != nil {
+			return err
+		}
+		if result.RowsAffected() == 0 {
+			return storage.ErrTagNotFound
+		}
+		return touchNote(ctx, tx, noteID)
+	})
+}
+
+func (s *Store) RenameTag(ctx context.Context, user *models.User, name string, newName string) error {
+	return s.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if err := touchTaggedNotes(ctx, tx, user, name); err != nil {
+			return err
+		}
+		result, err := tx.Exec(ctx,
+			"UPDATE tags SET name = $3 WHERE user_id = $1 AND LOWER(name) = LOWER($2)",
+			user.ID, name, newName)
+		if isUniqueViolation(err) {
+			return storage.ErrTagExists
+		}
+		if err != nil {
+			return err
+		}
+		if result.RowsAffected() == 0 {
+			return storage.ErrTagNotFound
+		}
+		return nil
+	})
+}
+
+func (s *Store) DeleteTag(ctx context.Context, user *models.User, name string) error {
+	return s.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if err := touchTaggedNotes(ctx, tx, user, name); err != nil {
+			return err
+		}
+		result, err := tx.Exec(ctx,
+			"DELETE FROM tags WHERE user_id = $1 AND LOWER(name) = LOWER($2)",
+			user.ID, name)
+		if err != nil {
+			return err
+		}
+		if result.RowsAffected() == 0 {
+			return storage.ErrTagNotFound
+		}
+		return nil
+	})
+}
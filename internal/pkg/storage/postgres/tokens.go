@@ -0,0 +1,144 @@
+package postgres
+
+import (
+	"context"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/storage"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+const refreshTokenColumns = "token_id, user_id, family_id, token_hash, access_jti, created_at, expires_at, used_at, revoked_at"
+
+func scanRefreshToken(row pgx.Row, token *models.RefreshToken) error {
+	return row.Scan(&token.ID, &token.UserID, &token.FamilyID, &token.TokenHash, &token.AccessJTI,
+		&token.CreatedAt, &token.ExpiresAt, &token.UsedAt, &token.RevokedAt)
+}
+
+func (s *Store) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	return insertRefreshToken(ctx, s.db, token)
+}
+
+func (s *Store) GetRefreshToken(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	err := scanRefreshToken(s.db.QueryRow(ctx,
+		"SELECT "+refreshTokenColumns+" FROM refresh_tokens WHERE token_hash = $1", tokenHash), &token)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (s *Store) RotateRefreshToken(ctx context.Context, tokenHash string, next *models.RefreshToken) (models.RefreshToken, error) {
+	var current models.RefreshToken
+	err := s.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		err := scanRefreshToken(tx.QueryRow(ctx,
+			"SELECT "+refreshTokenColumns+" FROM refresh_tokens WHERE token_hash = $1 FOR UPDATE", tokenHash), &current)
+		if err == pgx.ErrNoRows {
+			return storage.ErrTokenNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if current.UsedAt != nil || current.RevokedAt != nil {
+			return storage.ErrTokenReused
+		}
+		now := time.Now()
+		if !current.ExpiresAt.After(now) {
+			return storage.ErrTokenNotFound
+		}
+
+		_, err = tx.Exec(ctx, "UPDATE refresh_tokens SET used_at = $1 WHERE token_id = $2", now, current.ID)
+		if err != nil {
+			return err
+		}
+		next.UserID = current.UserID
+		next.FamilyID = current.FamilyID
+		return insertRefreshToken(ctx, tx, next)
+	})
+	return current, err
+}
+
+func (s *Store) RevokeTokenFamily(ctx context.Context, familyID string) error {
+	return s.revokeRefreshTokens(ctx, "family_id = $1", familyID)
+}
+
+func (s *Store) RevokeUserTokens(ctx context.Context, userID int) error {
+	return s.revokeRefreshTokens(ctx, "user_id = $1", userID)
+}
+
+// revokeRefreshTokens revokes the matching refresh tokens together with the
+// access tokens issued with them.
+func (s *Store) revokeRefreshTokens(ctx context.Context, condition string, value interface{}) error {
+	return s.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO revoked_tokens (jti, expires_at)
+			SELECT access_jti, expires_at FROM refresh_tokens WHERE `+condition+`
+			ON CONFLICT DO NOTHING`,
+			value)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx,
+			"UPDATE refresh_tokens SET revoked_at = $2 WHERE "+condition+" AND revoked_at IS NULL",
+			value, time.Now())
+		return err
+	})
+}
+
+func (s *Store) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	_, err := s.db.Exec(ctx,
+		"INSERT INTO revoked_tokens (jti, expires_at) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+		jti, expiresAt)
+	return err
+}
+
+func (s *Store) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	var revoked bool
+	err := s.db.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = $1)", jti).Scan(&revoked)
+	return revoked, err
+}
+
+func (s *Store) GetTokenUser(ctx context.Context, jti string, userID int) (*models.User, bool, error) {
+	var user models.User
+	var revoked bool
+	err := s.db.QueryRow(ctx, `
+		SELECT `+userColumns+`, EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = $1)
+		FROM users WHERE user_id = $2`,
+		jti, userID).Scan(append(userFields(&user), &revoked)...)
+	if err == pgx.ErrNoRows {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+	return &user, revoked, nil
+}
+
+func (s *Store) PurgeExpiredTokens(ctx context.Context, now time.Time) (int64, error) {
+	var purged int64
+	err := s.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		for _, table := range []string{"refresh_tokens", "revoked_tokens", "personal_tokens", "password_resets", "oidc_logins", "share_links"} {
+			result, err := tx.Exec(ctx, "DELETE FROM "+table+" WHERE expires_at < $1", now)
+			if err != nil {
+				return err
+			}
+			purged += result.RowsAffected()
+		}
+		return nil
+	})
+	return purged, err
+}
+
+func insertRefreshToken(ctx context.Context, db queryer, token *models.RefreshToken) error {
+	token.CreatedAt = time.Now()
+	return db.QueryRow(ctx, `
+		INSERT INTO refresh_tokens (user_id, family_id, token_hash, access_jti, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING token_id`,
+		token.UserID, token.FamilyID, token.TokenHash, token.AccessJTI, token.CreatedAt, token.ExpiresAt,
+	).Scan(&token.ID)
+}
@@ -0,0 +1,117 @@
+package postgres
+
+import (
+	"context"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/storage"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+func (s *Store) GetTOTP(ctx context.Context, userID int) (*models.TOTP, error) {
+	var totp models.TOTP
+	err := s.db.QueryRow(ctx, `
+		SELECT t.user_id, t.secret, t.created_at, t.enabled_at, t.last_step,
+			(SELECT COUNT(*) FROM recovery_codes c WHERE c.user_id = t.user_id AND c.used_at IS NULL)
+		FROM user_totp t WHERE t.user_id = $1`, userID,
+	).Scan(&totp.UserID, &totp.Secret, &totp.CreatedAt, &totp.EnabledAt, &totp.LastStep, &totp.RecoveryCodes)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &totp, nil
+}
+
+func (s *Store) SaveTOTPSecret(ctx context.Context, userID int, secret string) error {
+	result, err := s.db.Exec(ctx, `
+		INSERT INTO user_totp (user_id, secret, created_at) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, created_at = EXCLUDED.created_at
+		WHERE user_totp.enabled_at IS NULL`,
+		userID, secret, time.Now())
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return storage.ErrTOTPEnabled
+	}
+	return nil
+}
+
+func (s *Store) EnableTOTP(ctx context.Context, userID int, step int64, codeHashes []string) error {
+	return s.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		var enabledAt *time.Time
+		err := tx.QueryRow(ctx, "SELECT enabled_at FROM user_totp WHERE user_id = $1 FOR UPDATE", userID).Scan(&enabledAt)
+		if err == pgx.ErrNoRows {
+			return storage.ErrTOTPNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if enabledAt != nil {
+			return storage.ErrTOTPEnabled
+		}
+		_, err = tx.Exec(ctx, "UPDATE user_totp SET enabled_at = $1, last_step = $2 WHERE user_id = $3",
+			time.Now(), step, userID)
+		if err != nil {
+			return err
+		}
+		return replaceRecoveryCodes(ctx, tx, userID, codeHashes)
+	})
+}
+
+func (s *Store) UseTOTPStep(ctx context.Context, userID int, step int64) (bool, error) {
+	result, err := s.db.Exec(ctx,
+		"UPDATE user_totp SET last_step = $1 WHERE user_id = $2 AND last_step < $1 AND enabled_at IS NOT NULL",
+		step, userID)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+func (s *Store) UseRecoveryCode(ctx context.Context, userID int, codeHash string) (bool, error) {
+	result, err := s.db.Exec(ctx, `
+		UPDATE recovery_codes SET used_at = $1
+		WHERE code_id = (
+			SELECT code_id FROM recovery_codes
+			WHERE user_id = $2 AND code_hash = $3 AND used_at IS NULL
+			LIMIT 1
+		)`,
+		time.Now(), userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+func (s *Store) ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string) error {
+	return s.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		return replaceRecoveryCodes(ctx, tx, userID, codeHashes)
+	})
+}
+
+func (s *Store) DeleteTOTP(ctx context.Context, userID int) error {
+	return s.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, "DELETE FROM recovery_codes WHERE user_id = $1", userID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, "DELETE FROM user_totp WHERE user_id = $1", userID)
+		return err
+	})
+}
+
+func replaceRecoveryCodes(ctx context.Context, db queryer, userID int, codeHashes []string) error {
+	_, err := db.Exec(ctx, "DELETE FROM recovery_codes WHERE user_id = $1", userID)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(ctx, `
+		INSERT INTO recovery_codes (user_id, code_hash)
+		SELECT $1, UNNEST($2::text[])`,
+		userID, codeHashes)
+	return err
+}
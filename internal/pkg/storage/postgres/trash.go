@@ -0,0 +1,76 @@
+package postgres
+
+import (
+	"context"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/storage"
+	"time"
+)
+
+func (s *Store) ListTrash(ctx context.Context, user *models.User) ([]models.Note, error) {
+	rows, err := s.db.Query(
+		ctx,
+		"SELECT "+noteColumns+" FROM Notes WHERE user_id = $1 AND deleted_at IS NOT NULL ORDER BY deleted_at DESC, note_id DESC",
+		user.ID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notes := []models.Note{}
+	for rows.Next() {
+		var note models.Note
+		if err := scanNote(rows, &note); err != nil {
+			return nil, err
+		}
+		notes = append(notes, note)
+	}
+	return notes, rows.Err()
+}
+
+func (s *Store) RestoreNote(ctx context.Context, user *models.User, noteID int) error {
+	result, err := s.db.Exec(
+		ctx,
+		"UPDATE Notes SET deleted_at = NULL WHERE note_id = $1 AND user_id = $2 AND deleted_at IS NOT NULL",
+		noteID, user.ID,
+	)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return storage.ErrNoteNotFound
+	}
+	return nil
+}
+
+func (s *Store) PurgeNote(ctx context.Context, user *models.User, noteID int) error {
+	result, err := s.db.Exec(
+		ctx,
+		"DELETE FROM Notes WHERE note_id = $1 AND user_id = $2 AND deleted_at IS NOT NULL",
+		noteID, user.ID,
+	)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return storage.ErrNoteNotFound
+	}
+	return nil
+}
+
+func (s *Store) EmptyTrash(ctx context.Context, user *models.User) (int64, error) {
+	result, err := s.db.Exec(ctx, "DELETE FROM Notes WHERE user_id = $1 AND deleted_at IS NOT NULL", user.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+func (s *Store) PurgeTrash(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	result, err := s.db.Exec(ctx, "DELETE FROM Notes WHERE deleted_at < $1", deletedBefore)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,98 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/storage"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+const userColumns = "user_id, username, password_hash, COALESCE(email, ''), role, disabled_at"
+
+func (s *Store) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	return s.getUser(ctx, "LOWER(username) = LOWER($1)", username)
+}
+
+func (s *Store) GetUserByID(ctx context.Context, userID int) (*models.User, error) {
+	return s.getUser(ctx, "user_id = $1", userID)
+}
+
+func (s *Store) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	return s.getUser(ctx, "LOWER(email) = LOWER($1)", email)
+}
+
+func (s *Store) getUser(ctx context.Context, condition string, values ...interface{}) (*models.User, error) {
+	row := s.db.QueryRow(ctx, "SELECT "+userColumns+" FROM users WHERE "+condition, values...)
+
+	var user models.User
+	err := row.Scan(userFields(&user)...)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// userFields are the scan destinations of userColumns.
+func userFields(user *models.User) []interface{} {
+	return []interface{}{&user.ID, &user.Username, &user.Password, &user.Email, &user.Role, &user.DisabledAt}
+}
+
+func (s *Store) SaveUser(ctx context.Context, user models.User) error {
+	return insertUser(ctx, s.db, &user)
+}
+
+func insertUser(ctx context.Context, db queryer, user *models.User) error {
+	if user.Role == "" {
+		user.Role = models.RoleUser
+	}
+	err := db.QueryRow(ctx, `
+		INSERT INTO users (username, password_hash, email, role) VALUES ($1, $2, NULLIF($3, ''), $4)
+		RETURNING user_id`,
+		user.Username, user.Password, user.Email, user.Role).Scan(&user.ID)
+	return userError(err)
+}
+
+func (s *Store) UpdatePassword(ctx context.Context, userID int, passwordHash string) error {
+	_, err := s.db.Exec(ctx, "UPDATE users SET password_hash = $1 WHERE user_id = $2", passwordHash, userID)
+	return err
+}
+
+func (s *Store) UpdateEmail(ctx context.Context, userID int, email string) error {
+	_, err := s.db.Exec(ctx, "UPDATE users SET email = NULLIF($1, '') WHERE user_id = $2", email, userID)
+	return userError(err)
+}
+
+func (s *Store) DeleteUser(ctx context.Context, user models.User) error {
+	return s.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if err := leaveWorkspaces(ctx, tx, user.ID); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, "DELETE FROM notes WHERE user_id = $1", user.ID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, "DELETE FROM users WHERE user_id = $1", user.ID)
+		return err
+	})
+}
+
+// userError maps violations of the unique username and email indexes.
+func userError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != uniqueViolation {
+		return err
+	}
+	switch pgErr.ConstraintName {
+	case "users_username_idx":
+		return storage.ErrUserExists
+	case "users_email_idx":
+		return storage.ErrEmailExists
+	}
+	return err
+}
@@ -0,0 +1,301 @@
+package postgres
+
+import (
+	"context"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/storage"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+func (s *Store) CreateWorkspace(ctx context.Context, user *models.User, workspace *models.Workspace) error {
+	return s.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx,
+			"INSERT INTO workspaces (name, created_at) VALUES ($1, $2) RETURNING workspace_id, created_at",
+			workspace.Name, time.Now(),
+		).Scan(&workspace.ID, &workspace.CreatedAt)
+		if err != nil {
+			return err
+		}
+		workspace.Role = models.WorkspaceRoleOwner
+		_, err = tx.Exec(ctx,
+			"INSERT INTO workspace_members (workspace_id, user_id, role, created_at) VALUES ($1, $2, $3, $4)",
+			workspace.ID, user.ID, workspace.Role, workspace.CreatedAt)
+		return err
+	})
+}
+
+func (s *Store) ListWorkspaces(ctx context.Context, user *models.User) ([]models.Workspace, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT w.workspace_id, w.name, m.role, w.created_at
+		FROM workspaces w JOIN workspace_members m ON m.workspace_id = w.workspace_id
+		WHERE m.user_id = $1 ORDER BY LOWER(w.name), w.workspace_id`, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	workspaces := []models.Workspace{}
+	for rows.Next() {
+		var workspace models.Workspace
+		if err := rows.Scan(&workspace.ID, &workspace.Name, &workspace.Role, &workspace.CreatedAt); err != nil {
+			return nil, err
+		}
+		workspaces = append(workspaces, workspace)
+	}
+	return workspaces, rows.Err()
+}
+
+func (s *Store) GetWorkspace(ctx context.Context, user *models.User, workspaceID int) (models.Workspace, error) {
+	var workspace models.Workspace
+	err := s.db.QueryRow(ctx, `
+		SELECT w.workspace_id, w.name, m.role, w.created_at
+		FROM workspaces w JOIN workspace_members m ON m.workspace_id = w.workspace_id
+		WHERE w.workspace_id = $1 AND m.user_id = $2`,
+		workspaceID, user.ID,
+	).Scan(&workspace.ID, &workspace.Name, &workspace.Role, &workspace.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return models.Workspace{}, storage.ErrWorkspaceNotFound
+	}
+	return workspace, err
+}
+
+func (s *Store) RenameWorkspace(ctx context.Context, user *models.User, workspaceID int, name string) error {
+	return s.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if err := lockWorkspaceAsOwner(ctx, tx, workspaceID, user.ID); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, "UPDATE workspaces SET name = $1 WHERE workspace_id = $2", name, workspaceID)
+		return err
+	})
+}
+
+func (s *Store) DeleteWorkspace(ctx context.Context, user *models.User, workspaceID int) error {
+	return s.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if err := lockWorkspaceAsOwner(ctx, tx, workspaceID, user.ID); err != nil {
+			return err
+		}
+		// The notes of the workspace stay with their authors, the foreign
+		// key clears their workspace_id.
+		_, err := tx.Exec(ctx, "DELETE FROM workspaces WHERE workspace_id = $1", workspaceID)
+		return err
+	})
+}
+
+func (s *Store) ListWorkspaceMembers(ctx context.Context, user *models.User, workspaceID int) ([]models.WorkspaceMember, error) {
+	if err := checkMember(ctx, s.db, workspaceID, user.ID); err != nil {
+		return nil, err
+	}
+	rows, err := s.db.Query(ctx, `
+		SELECT m.workspace_id, m.user_id, u.username, m.role, m.created_at
+		FROM workspace_members m JOIN Users u ON u.user_id = m.user_id
+		WHERE m.workspace_id = $1 ORDER BY LOWER(u.username)`, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []models.WorkspaceMember{}
+	for rows.Next() {
+		var member models.WorkspaceMember
+		if err := rows.Scan(&member.WorkspaceID, &member.UserID, &member.Username, &member.Role, &member.CreatedAt); err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+	return members, rows.Err()
+}
+
+func (s *Store) SetWorkspaceMember(ctx context.Context, user *models.User, member *models.WorkspaceMember) error {
+	return s.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if err := lockWorkspaceAsOwner(ctx, tx, member.WorkspaceID, user.ID); err != nil {
+			return err
+		}
+		if member.Role != models.WorkspaceRoleOwner {
+			if err := keepOwner(ctx, tx, member.WorkspaceID, member.UserID); err != nil {
+				return err
+			}
+		}
+		return tx.QueryRow(ctx, `
+			INSERT INTO workspace_members (workspace_id, user_id, role, created_at) VALUES ($1, $2, $3, $4)
+			ON CONFLICT (workspace_id, user_id) DO UPDATE SET role = EXCLUDED.role
+			RETURNING created_at`,
+			member.WorkspaceID, member.UserID, member.Role, time.Now(),
+		).Scan(&member.CreatedAt)
+	})
+}
+
+func (s *Store) RemoveWorkspaceMember(ctx context.Context, user *models.User, workspaceID int, userID int) error {
+	return s.db.BeginFunc(ctx, func(tx pgx.Tx) error {
+		role, err := lockWorkspace(ctx, tx, workspaceID, user.ID)
+		if err != nil {
+			return err
+		}
+		if userID != user.ID && role != models.WorkspaceRoleOwner {
+			return storage.ErrNotWorkspaceOwner
+		}
+		if err := keepOwner(ctx, tx, workspaceID, userID); err != nil {
+			return err
+		}
+		result, err := tx.Exec(ctx, "DELETE FROM workspace_members WHERE workspace_id = $1 AND user_id = $2", workspaceID, userID)
+		if err != nil {
+			return err
+		}
+		if result.RowsAffected() == 0 {
+			return storage.ErrMemberNotFound
+		}
+		return handOverNotes(ctx, tx, userID, []int{workspaceID})
+	})
+}
+
+// lockWorkspace locks a workspace against concurrent changes of its
+// members and returns the role of the user in it.
+func lockWorkspace(ctx context.Context, tx pgx.Tx, workspaceID int, userID int) (string, error) {
+	var role string
+	err := tx.QueryRow(ctx, `
+		SELECT m.role FROM workspaces w
+		JOIN workspace_members m ON m.workspace_id = w.workspace_id AND m.user_id = $2
+		WHERE w.workspace_id = $1 FOR UPDATE OF w`,
+		workspaceID, userID,
+	).Scan(&role)
+	if err == pgx.ErrNoRows {
+		return "", storage.ErrWorkspaceNotFound
+	}
+	return role, err
+}
+
+func lockWorkspaceAsOwner(ctx context.Context, tx pgx.Tx, workspaceID int, userID int) error {
+	role, err := lockWorkspace(ctx, tx, workspaceID, userID)
+	if err != nil {
+		return err
+	}
+	if role != models.WorkspaceRoleOwner {
+		return storage.ErrNotWorkspaceOwner
+	}
+	return nil
+}
+
+// keepOwner fails with ErrLastWorkspaceOwner when userID is the only owner
+// of the workspace, which must be locked.
+func keepOwner(ctx context.Context, tx pgx.Tx, workspaceID int, userID int) error {
+	var others bool
+	var role string
+	err := tx.QueryRow(ctx, `
+		SELECT COALESCE((SELECT role FROM workspace_members WHERE workspace_id = $1 AND user_id = $2), ''),
+			EXISTS (SELECT 1 FROM workspace_members WHERE workspace_id = $1 AND user_id <> $2 AND role = $3)`,
+		workspaceID, userID, models.WorkspaceRoleOwner,
+	).Scan(&role, &others)
+	if err != nil {
+		return err
+	}
+	if role == models.WorkspaceRoleOwner && !others {
+		return storage.ErrLastWorkspaceOwner
+	}
+	return nil
+}
+
+// workspaceRole returns the role of the user in a workspace, or an empty
+// string if they are no member.
+func workspaceRole(ctx context.Context, db queryer, workspaceID int, userID int) (string, error) {
+	var role string
+	err := db.QueryRow(ctx,
+		"SELECT role FROM workspace_members WHERE workspace_id = $1 AND user_id = $2",
+		workspaceID, userID,
+	).Scan(&role)
+	if err == pgx.ErrNoRows {
+		return "", nil
+	}
+	return role, err
+}
+
+// checkMember fails with ErrWorkspaceNotFound unless the user is a member
+// of the workspace.
+func checkMember(ctx context.Context, db queryer, workspaceID int, userID int) error {
+	role, err := workspaceRole(ctx, db, workspaceID, userID)
+	if err != nil {
+		return err
+	}
+	if role == "" {
+		return storage.ErrWorkspaceNotFound
+	}
+	return nil
+}
+
+// handOverNotes gives the notes a user wrote in the workspaces to the
+// earliest other owner of each workspace. Tags and notebooks are personal,
+// the notes leave those of the user.
+func handOverNotes(ctx context.Context, db queryer, userID int, workspaceIDs []int) error {
+	_, err := db.Exec(ctx, `
+		DELETE FROM note_tags WHERE note_id IN (
+			SELECT note_id FROM Notes WHERE user_id = $1 AND workspace_id = ANY($2)
+		)`,
+		userID, workspaceIDs)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(ctx, `
+		UPDATE Notes SET notebook_id = NULL, user_id = (
+			SELECT m.user_id FROM workspace_members m
+			WHERE m.workspace_id = Notes.workspace_id AND m.user_id <> $1 AND m.role = $3
+			ORDER BY m.created_at, m.user_id LIMIT 1
+		)
+		WHERE user_id = $1 AND workspace_id = ANY($2)`,
+		userID, workspaceIDs, models.WorkspaceRoleOwner)
+	return err
+}
+
+// leaveWorkspaces prepares the deletion of a user. Workspaces with other
+// members keep an owner and the notes the user wrote in them, workspaces
+// without other members are deleted and their notes left to the user.
+func leaveWorkspaces(ctx context.Context, tx pgx.Tx, userID int) error {
+	rows, err := tx.Query(ctx, `
+		SELECT workspace_id FROM workspaces
+		WHERE workspace_id IN (SELECT workspace_id FROM workspace_members WHERE user_id = $1)
+		ORDER BY workspace_id FOR UPDATE`, userID)
+	if err != nil {
+		return err
+	}
+	var workspaceIDs []int
+	for rows.Next() {
+		var workspaceID int
+		if err := rows.Scan(&workspaceID); err != nil {
+			rows.Close()
+			return err
+		}
+		workspaceIDs = append(workspaceIDs, workspaceID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(workspaceIDs) == 0 {
+		return nil
+	}
+
+	_, err = tx.Exec(ctx, `
+		DELETE FROM workspaces w WHERE workspace_id = ANY($2)
+		AND NOT EXISTS (SELECT 1 FROM workspace_members m WHERE m.workspace_id = w.workspace_id AND m.user_id <> $1)`,
+		userID, workspaceIDs)
+	if err != nil {
+		return err
+	}
+	// Where the user is the only owner, the earliest editor, or else the
+	// earliest viewer, takes over.
+	_, err = tx.Exec(ctx, `
+		UPDATE workspace_members m SET role = $3
+		FROM (
+			SELECT DISTINCT ON (o.workspace_id) o.workspace_id, o.user_id
+			FROM workspace_members o
+			WHERE o.workspace_id = ANY($2) AND o.user_id <> $1
+				AND EXISTS (SELECT 1 FROM workspace_members x WHERE x.workspace_id = o.workspace_id AND x.user_id = $1 AND x.role = $3)
+				AND NOT EXISTS (SELECT 1 FROM workspace_members x WHERE x.workspace_id = o.workspace_id AND x.user_id <> $1 AND x.role = $3)
+			ORDER BY o.workspace_id, o.role = $4 DESC, o.created_at, o.user_id
+		) successor
+		WHERE m.workspace_id = successor.workspace_id AND m.user_id = successor.user_id`,
+		userID, workspaceIDs, models.WorkspaceRoleOwner, models.WorkspaceRoleEditor)
+	if err != nil {
+		return err
+	}
+	return handOverNotes(ctx, tx, userID, workspaceIDs)
+}
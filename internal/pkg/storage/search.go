@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+)
+
+// Search snippets are HTML-escaped text with matches wrapped in these tags.
+const (
+	HighlightStart = "<mark>"
+	HighlightStop  = "</mark>"
+)
+
+var ErrEmptySearchQuery = errors.New("Search query must contain at least one word")
+
+// SearchTerm is a single word or, when it has several words, a phrase whose
+// words must appear next to each other. Prefix makes the last word match
+// any word starting with it.
+type SearchTerm struct {
+	Words  []string
+	Prefix bool
+}
+
+// SearchQuery matches notes containing all of its terms, among the notes
+// of WorkspaceID or, when it is nil, the personal notes of the user.
+type SearchQuery struct {
+	Terms       []SearchTerm
+	WorkspaceID *int
+}
+
+// ParseSearchQuery parses queries like `"release plan" deploy* backend`:
+// quoted text is a phrase, a trailing * turns a word into a prefix and
+// everything else is a plain word. Words are lowercased and stripped of
+// punctuation.
+func ParseSearchQuery(input string) (SearchQuery, error) {
+	var query SearchQuery
+	for len(input) > 0 {
+		input = strings.TrimLeftFunc(input, unicode.IsSpace)
+		if input == "" {
+			break
+		}
+
+		var chunk string
+		if input[0] == '"' {
+			end := strings.IndexByte(input[1:], '"')
+			if end < 0 {
+				chunk, input = input[1:], ""
+			} else {
+				chunk, input = input[1:end+1], input[end+2:]
+			}
+		} else {
+			end := strings.IndexFunc(input, unicode.IsSpace)
+			if end < 0 {
+				chunk, input = input, ""
+			} else {
+				chunk, input = input[:end], input[end:]
+			}
+		}
+
+		// An unquoted word like "front-end" becomes a phrase of its parts,
+		// the same way the full-text parser splits it in indexed text.
+		term := SearchTerm{
+			Words:  SearchWords(chunk),
+			Prefix: strings.HasSuffix(chunk, "*"),
+		}
+		if len(term.Words) > 0 {
+			query.Terms = append(query.Terms, term)
+		}
+	}
+
+	if len(query.Terms) == 0 {
+		return query, ErrEmptySearchQuery
+	}
+	return query, nil
+}
+
+// SearchWords splits text into lowercase words made of letters and digits.
+func SearchWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -0,0 +1,281 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"noteserver/internal/pkg/models"
+	"time"
+)
+
+var (
+	ErrNoteNotFound          = errors.New("No matching notes found")
+	ErrNoteReadOnly          = errors.New("Note is shared with you read-only")
+	ErrNotNoteOwner          = errors.New("Only the owner of the note can do this")
+	ErrShareNotFound         = errors.New("No matching shares found")
+	ErrShareLinkNotFound     = errors.New("No matching share links found")
+	ErrWorkspaceNotFound     = errors.New("No matching workspaces found")
+	ErrWorkspaceReadOnly     = errors.New("Viewers cannot change the notes of a workspace")
+	ErrNotWorkspaceOwner     = errors.New("Only owners of the workspace can do this")
+	ErrLastWorkspaceOwner    = errors.New("A workspace must keep at least one owner")
+	ErrMemberNotFound        = errors.New("No matching workspace members found")
+	ErrVersionMismatch       = errors.New("Note has been modified since it was read")
+	ErrRevisionNotFound      = errors.New("No matching revisions found")
+	ErrTagNotFound           = errors.New("No matching tags found")
+	ErrTagExists             = errors.New("Tag already exists")
+	ErrUserExists            = errors.New("Username already exists")
+	ErrUserNotFound          = errors.New("No matching users found")
+	ErrEmailExists           = errors.New("Email address is already in use")
+	ErrResetTokenNotFound    = errors.New("Invalid or expired password reset token")
+	ErrInviteNotFound        = errors.New("Invalid, used or expired invite code")
+	ErrTOTPEnabled           = errors.New("Two-factor authentication is already enabled")
+	ErrTOTPNotFound          = errors.New("Two-factor authentication is not set up")
+	ErrIdentityNotFound      = errors.New("No matching linked identity found")
+	ErrIdentityExists        = errors.New("Identity is already linked to an account")
+	ErrOIDCLoginNotFound     = errors.New("Invalid or expired login state")
+	ErrTokenNotFound         = errors.New("Invalid or expired refresh token")
+	ErrTokenReused           = errors.New("Refresh token has already been used")
+	ErrPersonalTokenNotFound = errors.New("No matching access tokens found")
+	ErrPersonalTokenExists   = errors.New("Access token name already exists")
+)
+
+// NoteStore manages notes. UpdateNote and DeleteNote take the version the
+// client last saw and fail with ErrVersionMismatch when the note has changed
+// since; a zero version skips the check. CreateNote and UpdateNote set
+// note.Version and the timestamps to those stored. DeleteAllNotes removes all notes of the
+// user for good, including the trash, together with their tags and
+// notebooks, and returns the number of notes. Notes the user wrote in
+// workspaces are kept.
+//
+// ReadNote and UpdateNote also accept notes shared with the user; updates
+// of notes shared read-only fail with ErrNoteReadOnly. DeleteNote fails
+// with ErrNotNoteOwner for shared notes.
+//
+// Notes created with a WorkspaceID belong to that workspace: every member
+// can read them, owners and editors can also update and delete them,
+// viewers get ErrNoteReadOnly and ErrNotNoteOwner. CreateNote fails with
+// ErrWorkspaceNotFound for workspaces the user is no member of and with
+// ErrWorkspaceReadOnly for viewers. Listings and searches cover the
+// user's personal notes, or the notes of the workspace they are given;
+// they fail with ErrWorkspaceNotFound for workspaces of others.
+type NoteStore interface {
+	CreateNote(ctx context.Context, user *models.User, note *models.Note) (int, error)
+	ReadNote(ctx context.Context, user *models.User, noteID int) (models.Note, error)
+	UpdateNote(ctx context.Context, user *models.User, note *models.Note) error
+	DeleteNote(ctx context.Context, user *models.User, noteID int, version int) error
+	ListNotes(ctx context.Context, user *models.User, opts ListOptions) (NotePage, error)
+	SearchNotes(ctx context.Context, user *models.User, query SearchQuery, limit, offset int) ([]models.NoteSearchResult, error)
+	DeleteAllNotes(ctx context.Context, user *models.User) (int64, error)
+}
+
+// TrashStore manages notes removed with NoteStore.DeleteNote. Trashed notes
+// are hidden from every other read until restored or purged for good.
+type TrashStore interface {
+	ListTrash(ctx context.Context, user *models.User) ([]models.Note, error)
+	RestoreNote(ctx context.Context, user *models.User, noteID int) error
+	PurgeNote(ctx context.Context, user *models.User, noteID int) error
+	EmptyTrash(ctx context.Context, user *models.User) (int64, error)
+	PurgeTrash(ctx context.Context, deletedBefore time.Time) (int64, error)
+}
+
+// TagStore manages the user's tags. Tag names are unique per user and
+// compared case-insensitively.
+type TagStore interface {
+	ListTags(ctx context.Context, user *models.User) ([]models.Tag, error)
+	AddNoteTags(ctx context.Context, user *models.User, noteID int, names []string) error
+	RemoveNoteTag(ctx context.Context, user *models.User, noteID int, name string) error
+	RenameTag(ctx context.Context, user *models.User, name string, newName string) error
+	DeleteTag(ctx context.Context, user *models.User, name string) error
+}
+
+// NotebookStore manages nested notebooks. Notebook names are unique among
+// siblings; CreateNote, MoveNote and CopyNote return ErrNotebookNotFound
+// for notebooks of other users.
+type NotebookStore interface {
+	CreateNotebook(ctx context.Context, user *models.User, notebook *models.Notebook) (int, error)
+	ListNotebooks(ctx context.Context, user *models.User) ([]models.Notebook, error)
+	UpdateNotebook(ctx context.Context, user *models.User, notebook *models.Notebook) error
+	DeleteNotebook(ctx context.Context, user *models.User, notebookID int, mode NotebookDeleteMode) error
+	MoveNote(ctx context.Context, user *models.User, noteID int, notebookID *int) error
+	CopyNote(ctx context.Context, user *models.User, noteID int, notebookID *int) (int, error)
+}
+
+// WorkspaceStore manages workspaces and their members. The user creating a
+// workspace becomes its owner. Users who are no members of a workspace get
+// ErrWorkspaceNotFound, members who are no owners get ErrNotWorkspaceOwner
+// for renaming and deleting it and for managing members, except for
+// removing themselves. SetWorkspaceMember adds a member or changes their
+// role. Changes leaving a workspace without an owner fail with
+// ErrLastWorkspaceOwner.
+//
+// Notes written by a member who leaves or is removed go to the earliest
+// owner; notes of a deleted workspace become personal notes of their
+// authors. UserStore.DeleteUser hands the workspace notes of the user over
+// the same way, after making the earliest editor, or else viewer, owner
+// of workspaces the user was the only owner of.
+type WorkspaceStore interface {
+	CreateWorkspace(ctx context.Context, user *models.User, workspace *models.Workspace) error
+	ListWorkspaces(ctx context.Context, user *models.User) ([]models.Workspace, error)
+	GetWorkspace(ctx context.Context, user *models.User, workspaceID int) (models.Workspace, error)
+	RenameWorkspace(ctx context.Context, user *models.User, workspaceID int, name string) error
+	DeleteWorkspace(ctx context.Context, user *models.User, workspaceID int) error
+	ListWorkspaceMembers(ctx context.Context, user *models.User, workspaceID int) ([]models.WorkspaceMember, error)
+	SetWorkspaceMember(ctx context.Context, user *models.User, member *models.WorkspaceMember) error
+	RemoveWorkspaceMember(ctx context.Context, user *models.User, workspaceID int, userID int) error
+}
+
+// ShareStore shares notes with other users. Only the owner can share a
+// note, list and revoke its shares; the other users get ErrNoteNotFound.
+// ShareNote replaces the permission of an existing share and sets its
+// CreatedAt. ListSharedNotes returns the notes shared with the user, most
+// recently updated first.
+type ShareStore interface {
+	ShareNote(ctx context.Context, owner *models.User, share *models.NoteShare) error
+	ListNoteShares(ctx context.Context, owner *models.User, noteID int) ([]models.NoteShare, error)
+	RevokeShare(ctx context.Context, owner *models.User, noteID int, userID int) error
+	ListSharedNotes(ctx context.Context, user *models.User, limit, offset int) ([]models.SharedNote, error)
+}
+
+// ShareLinkStore manages public links to notes. Only the owner of a note
+// can create, list and delete its links; the other users get
+// ErrNoteNotFound. GetShareLink returns nil for unknown and expired tokens
+// and for links to notes in the trash. ViewShareLink counts a view of a
+// link and returns its note. TokenStore.PurgeExpiredTokens removes expired
+// links.
+type ShareLinkStore interface {
+	CreateShareLink(ctx context.Context, owner *models.User, link *models.ShareLink) error
+	ListShareLinks(ctx context.Context, owner *models.User, noteID int) ([]models.ShareLink, error)
+	DeleteShareLink(ctx context.Context, owner *models.User, noteID int, linkID int) error
+	GetShareLink(ctx context.Context, tokenHash string, now time.Time) (*models.ShareLink, error)
+	ViewShareLink(ctx context.Context, linkID int, now time.Time) (models.Note, error)
+}
+
+// RevisionStore reads the revisions recorded by NoteStore.UpdateNote,
+// including those of notes shared with the user.
+type RevisionStore interface {
+	ListRevisions(ctx context.Context, user *models.User, noteID int) ([]models.NoteRevision, error)
+	ReadRevision(ctx context.Context, user *models.User, noteID int, revisionID int) (models.NoteRevision, error)
+}
+
+// UserStore manages user accounts. Usernames are unique and compared
+// case-insensitively, so are email addresses, which are optional; an empty
+// email removes the address. SaveUser returns ErrUserExists and
+// ErrEmailExists for taken names and addresses.
+type UserStore interface {
+	GetUserByUsername(ctx context.Context, username string) (*models.User, error)
+	GetUserByID(ctx context.Context, userID int) (*models.User, error)
+	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+	SaveUser(ctx context.Context, user models.User) error
+	UpdatePassword(ctx context.Context, userID int, passwordHash string) error
+	UpdateEmail(ctx context.Context, userID int, email string) error
+	DeleteUser(ctx context.Context, user models.User) error
+}
+
+// AdminStore serves the administration of users. ListUsers returns users
+// ordered by id, GetUserStats returns nil for unknown users. SetUserRole
+// and SetUserDisabled fail with ErrUserNotFound for unknown users.
+type AdminStore interface {
+	ListUsers(ctx context.Context, query UserQuery) ([]models.UserStats, error)
+	GetUserStats(ctx context.Context, userID int) (*models.UserStats, error)
+	SetUserRole(ctx context.Context, userID int, role string) error
+	SetUserDisabled(ctx context.Context, userID int, disabled bool) error
+}
+
+// PasswordResetStore keeps password reset tokens. ResetPassword consumes a
+// valid token, sets the password of its user and discards the other reset
+// tokens of the user. It returns the user id, or ErrResetTokenNotFound for
+// unknown, used and expired tokens.
+type PasswordResetStore interface {
+	CreatePasswordReset(ctx context.Context, reset *models.PasswordReset) error
+	ResetPassword(ctx context.Context, tokenHash string, passwordHash string, now time.Time) (int, error)
+}
+
+// TokenStore keeps refresh tokens and the ids (jti) of revoked access
+// tokens. RotateRefreshToken marks a token as used and stores its successor
+// in the same family; presenting a used or revoked token again returns
+// ErrTokenReused together with the stored token so that the caller can
+// revoke its family. GetTokenUser returns the user of an access token and
+// whether the token is revoked, the user is nil for unknown users.
+type TokenStore interface {
+	CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error
+	GetRefreshToken(ctx context.Context, tokenHash string) (*models.RefreshToken, error)
+	RotateRefreshToken(ctx context.Context, tokenHash string, next *models.RefreshToken) (models.RefreshToken, error)
+	RevokeTokenFamily(ctx context.Context, familyID string) error
+	RevokeUserTokens(ctx context.Context, userID int) error
+	RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error
+	IsTokenRevoked(ctx context.Context, jti string) (bool, error)
+	GetTokenUser(ctx context.Context, jti string, userID int) (*models.User, bool, error)
+	PurgeExpiredTokens(ctx context.Context, now time.Time) (int64, error)
+}
+
+// PersonalTokenStore manages personal access tokens. Token names are unique
+// per user and compared case-insensitively. UsePersonalToken records the
+// time of use and returns the token with its user, or nil for unknown and
+// expired tokens. DeleteUserPersonalTokens deletes every token of the user.
+type PersonalTokenStore interface {
+	CreatePersonalToken(ctx context.Context, token *models.PersonalToken) error
+	ListPersonalTokens(ctx context.Context, userID int) ([]models.PersonalToken, error)
+	UsePersonalToken(ctx context.Context, tokenHash string, now time.Time) (*models.PersonalToken, *models.User, error)
+	DeletePersonalToken(ctx context.Context, userID int, tokenID int) error
+	DeleteUserPersonalTokens(ctx context.Context, userID int) error
+}
+
+// InviteStore manages invite codes. RegisterWithInvite consumes an unused
+// and unexpired invite and saves the user like UserStore.SaveUser, in one
+// step; it returns ErrInviteNotFound for any other code.
+type InviteStore interface {
+	CreateInvite(ctx context.Context, invite *models.Invite) error
+	ListInvites(ctx context.Context, userID int) ([]models.Invite, error)
+	DeleteInvite(ctx context.Context, userID int, inviteID int) error
+	RegisterWithInvite(ctx context.Context, user models.User, codeHash string, now time.Time) error
+}
+
+// TwoFactorStore keeps TOTP secrets and recovery codes, of which only the
+// hashes are stored. SaveTOTPSecret starts an enrollment, replacing a
+// pending one, and EnableTOTP completes it; both fail with ErrTOTPEnabled
+// when two-factor authentication is already enabled. UseTOTPStep and
+// UseRecoveryCode report whether the step or code was accepted, each can
+// be used once.
+type TwoFactorStore interface {
+	GetTOTP(ctx context.Context, userID int) (*models.TOTP, error)
+	SaveTOTPSecret(ctx context.Context, userID int, secret string) error
+	EnableTOTP(ctx context.Context, userID int, step int64, codeHashes []string) error
+	UseTOTPStep(ctx context.Context, userID int, step int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, userID int, codeHash string) (bool, error)
+	ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string) error
+	DeleteTOTP(ctx context.Context, userID int) error
+}
+
+// IdentityStore links accounts of identity providers to users. A subject is
+// linked to at most one user and a user to at most one subject per
+// provider, LinkIdentity returns ErrIdentityExists otherwise.
+// RegisterWithIdentity saves the user like UserStore.SaveUser and links the
+// identity in one step. ConsumeOIDCLogin removes and returns an unexpired
+// login, or fails with ErrOIDCLoginNotFound.
+type IdentityStore interface {
+	GetUserByIdentity(ctx context.Context, provider string, subject string) (*models.User, error)
+	ListIdentities(ctx context.Context, userID int) ([]models.Identity, error)
+	LinkIdentity(ctx context.Context, identity *models.Identity) error
+	RegisterWithIdentity(ctx context.Context, user *models.User, identity *models.Identity) error
+	UnlinkIdentity(ctx context.Context, userID int, provider string) error
+	CreateOIDCLogin(ctx context.Context, login *models.OIDCLogin) error
+	ConsumeOIDCLogin(ctx context.Context, stateHash string, now time.Time) (*models.OIDCLogin, error)
+}
+
+type Store interface {
+	NoteStore
+	ShareStore
+	ShareLinkStore
+	WorkspaceStore
+	TrashStore
+	RevisionStore
+	TagStore
+	NotebookStore
+	UserStore
+	AdminStore
+	TokenStore
+	PersonalTokenStore
+	PasswordResetStore
+	InviteStore
+	TwoFactorStore
+	IdentityStore
+}
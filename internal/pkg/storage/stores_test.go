@@ -0,0 +1,102 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"noteserver/internal/pkg/database"
+	"noteserver/internal/pkg/models"
+	"noteserver/internal/pkg/storage"
+	"noteserver/internal/pkg/storage/memory"
+	"noteserver/internal/pkg/storage/postgres"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// forEachStore runs the test against the memory store and, when
+// NOTESERVER_TEST_DATABASE holds the URL of a database, against PostgreSQL.
+func forEachStore(t *testing.T, test func(t *testing.T, store storage.Store)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, memory.New())
+	})
+	t.Run("postgres", func(t *testing.T) {
+		url := os.Getenv("NOTESERVER_TEST_DATABASE")
+		if url == "" {
+			t.Skip("NOTESERVER_TEST_DATABASE is not set")
+		}
+		ctx := context.Background()
+		db, err := database.Connect(ctx, database.Config{URL: url})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(db.Close)
+		if _, err := postgres.MigrateUp(ctx, db); err != nil {
+			t.Fatal(err)
+		}
+		test(t, postgres.New(db))
+	})
+}
+
+// addUser creates a user whose name is unique, so that tests can share a
+// database.
+func addUser(t *testing.T, store storage.Store, name string) *models.User {
+	t.Helper()
+	ctx := context.Background()
+	username := name + strconv.FormatInt(time.Now().UnixNano(), 36)
+	if err := store.SaveUser(ctx, models.User{Username: username, Password: "-"}); err != nil {
+		t.Fatal(err)
+	}
+	user, err := store.GetUserByUsername(ctx, username)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func TestCopyNote(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store storage.Store) {
+		ctx := context.Background()
+		alice := addUser(t, store, "alice")
+		bob := addUser(t, store, "bob")
+
+		workspace := models.Workspace{Name: "Team"}
+		if err := store.CreateWorkspace(ctx, alice, &workspace); err != nil {
+			t.Fatal(err)
+		}
+		member := models.WorkspaceMember{WorkspaceID: workspace.ID, UserID: bob.ID, Role: models.WorkspaceRoleEditor}
+		if err := store.SetWorkspaceMember(ctx, alice, &member); err != nil {
+			t.Fatal(err)
+		}
+		shared := models.Note{Title: "Plan", WorkspaceID: &workspace.ID}
+		sharedID, err := store.CreateNote(ctx, bob, &shared)
+		if err != nil {
+			t.Fatal(err)
+		}
+		personal := models.Note{Title: "Draft"}
+		personalID, err := store.CreateNote(ctx, bob, &personal)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := store.CopyNote(ctx, bob, sharedID, nil); err != nil {
+			t.Errorf("copy of a workspace note by its author: %v", err)
+		}
+		if _, err := store.CopyNote(ctx, alice, personalID, nil); !errors.Is(err, storage.ErrNoteNotFound) {
+			t.Errorf("copy of a note of another user: got %v, want %v", err, storage.ErrNoteNotFound)
+		}
+
+		// Authors who become viewers can no longer copy their workspace
+		// notes, the same as they can no longer move them.
+		member.Role = models.WorkspaceRoleViewer
+		if err := store.SetWorkspaceMember(ctx, alice, &member); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.CopyNote(ctx, bob, sharedID, nil); !errors.Is(err, storage.ErrNoteNotFound) {
+			t.Errorf("copy by a viewer: got %v, want %v", err, storage.ErrNoteNotFound)
+		}
+		if _, err := store.CopyNote(ctx, bob, personalID, nil); err != nil {
+			t.Errorf("copy of a personal note: %v", err)
+		}
+	})
+}
@@ -0,0 +1,10 @@
+package storage
+
+// UserQuery selects users for administrators. Search keeps users whose
+// username or email address contains it, ignoring case.
+type UserQuery struct {
+	Search string
+	// Limit caps the number of returned users, zero means no limit.
+	Limit  int
+	Offset int
+}
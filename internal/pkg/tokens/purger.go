@@ -0,0 +1,31 @@
+package tokens
+
+import (
+	"context"
+	l "noteserver/internal/pkg/logger"
+	"noteserver/internal/pkg/storage"
+	"time"
+)
+
+// RunPurger deletes expired refresh and personal access tokens and the
+// revocation entries of expired access tokens. It checks every
+// interval until ctx is cancelled.
+func RunPurger(ctx context.Context, store storage.TokenStore, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := store.PurgeExpiredTokens(ctx, time.Now())
+		if err != nil {
+			l.Logger.Error("Failed to purge expired tokens:", err)
+		} else if purged > 0 {
+			l.Logger.Info("Purged expired tokens:", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 seed of the test vectors of RFC 6238, base32
+// encoded.
+var rfcSecret = encoding.EncodeToString([]byte("12345678901234567890"))
+
+// TestCode checks the SHA-1 vectors of RFC 6238, appendix B, which have
+// eight digits; the codes are their last six.
+func TestCode(t *testing.T) {
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, test := range tests {
+		step := Step(time.Unix(test.unix, 0))
+		code, err := Code(rfcSecret, step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != test.code {
+			t.Errorf("Code at %d = %s, want %s", test.unix, code, test.code)
+		}
+	}
+}
+
+func TestCodeInvalidSecret(t *testing.T) {
+	if _, err := Code("not base32!", 1); err == nil {
+		t.Error("Code accepted a secret that is not base32")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	tests := []struct {
+		name   string
+		secret string
+		code   string
+		step   int64
+		ok     bool
+	}{
+		{"current", rfcSecret, "050471", Step(now), true},
+		{"lowercase secret", "gezdgnbvgy3tqojqgezdgnbvgy3tqojq", "050471", Step(now), true},
+		{"surrounding space", rfcSecret, " 050471\n", Step(now), true},
+		{"previous period", rfcSecret, "081804", Step(now) - 1, true},
+		{"wrong code", rfcSecret, "050472", 0, false},
+		{"eight digits", rfcSecret, "14050471", 0, false},
+		{"too old", rfcSecret, "287082", 0, false},
+	}
+	for _, test := range tests {
+		step, ok := Verify(test.secret, test.code, now)
+		if ok != test.ok || step != test.step {
+			t.Errorf("%s: Verify = %d, %v, want %d, %v", test.name, step, ok, test.step, test.ok)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package trash
+
+import (
+	"context"
+	l "noteserver/internal/pkg/logger"
+	"noteserver/internal/pkg/storage"
+	"time"
+)
+
+// RunPurger permanently deletes notes that have been in the trash longer
+// than retention. It checks every interval until ctx is cancelled.
+func RunPurger(ctx context.Context, store storage.TrashStore, retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := store.PurgeTrash(ctx, time.Now().Add(-retention))
+		if err != nil {
+			l.Logger.Error("Failed to purge trash:", err)
+		} else if purged > 0 {
+			l.Logger.Info("Purged notes from trash:", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}